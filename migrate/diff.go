@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// dbDiff is the difference between two Pebble databases
+type dbDiff struct {
+	OnlyInA   []string `json:"onlyInA"`
+	OnlyInB   []string `json:"onlyInB"`
+	Differing []string `json:"differing"`
+	Same      int      `json:"same"`
+	CountA    int      `json:"countA"`
+	CountB    int      `json:"countB"`
+}
+
+// Compare the databases at paths a and b, opened read-only. Both iterators
+// walk the keys in order, so the databases are compared in a single pass.
+func diffDBs(a, b string) (dbDiff, error) {
+	var diff dbDiff
+	dbA, err := pebble.Open(a, &pebble.Options{ReadOnly: true})
+	if err != nil {
+		return diff, fmt.Errorf("failed to open %s: %w", a, err)
+	}
+	defer dbA.Close()
+	dbB, err := pebble.Open(b, &pebble.Options{ReadOnly: true})
+	if err != nil {
+		return diff, fmt.Errorf("failed to open %s: %w", b, err)
+	}
+	defer dbB.Close()
+
+	iterA, err := dbA.NewIter(nil)
+	if err != nil {
+		return diff, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iterA.Close()
+	iterB, err := dbB.NewIter(nil)
+	if err != nil {
+		return diff, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iterB.Close()
+
+	validA, validB := iterA.First(), iterB.First()
+	for validA || validB {
+		cmp := 0
+		switch {
+		case !validB:
+			cmp = -1
+		case !validA:
+			cmp = 1
+		default:
+			cmp = bytes.Compare(iterA.Key(), iterB.Key())
+		}
+
+		switch {
+		case cmp < 0:
+			diff.OnlyInA = append(diff.OnlyInA, string(iterA.Key()))
+		case cmp > 0:
+			diff.OnlyInB = append(diff.OnlyInB, string(iterB.Key()))
+		case bytes.Equal(iterA.Value(), iterB.Value()):
+			diff.Same++
+		default:
+			diff.Differing = append(diff.Differing, string(iterA.Key()))
+		}
+
+		if cmp <= 0 {
+			diff.CountA++
+			validA = iterA.Next()
+		}
+		if cmp >= 0 {
+			diff.CountB++
+			validB = iterB.Next()
+		}
+	}
+
+	if err := iterA.Error(); err != nil {
+		return diff, fmt.Errorf("iterator error in %s: %w", a, err)
+	}
+	if err := iterB.Error(); err != nil {
+		return diff, fmt.Errorf("iterator error in %s: %w", b, err)
+	}
+	return diff, nil
+}
+
+// Print the diff as one JSON line prefixed with "diff summary: "
+func writeDiffSummary(w io.Writer, diff dbDiff) error {
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "diff summary: %s\n", data)
+	return err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+)
+
+func TestDiffDBs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, entries map[string]string) string {
+		path := filepath.Join(dir, name)
+		db, err := pebble.Open(path, &pebble.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		for key, value := range entries {
+			if err := db.Set([]byte(key), []byte(value), pebble.Sync); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return path
+	}
+
+	a := write("quake-db", map[string]string{"us1": "5.2", "us2": "6.0", "us4": "5.5", "us6": "7.1"})
+	b := write("quake-db-new", map[string]string{"us1": "5.3", "us2": "6.0", "us3": "5.8", "us6": "7.1", "us7": "5.6"})
+
+	diff, err := diffDBs(a, b)
+	if err != nil {
+		t.Fatalf("diffDBs returned error: %v", err)
+	}
+	want := dbDiff{
+		OnlyInA:   []string{"us4"},
+		OnlyInB:   []string{"us3", "us7"},
+		Differing: []string{"us1"},
+		Same:      2,
+		CountA:    4,
+		CountB:    5,
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("unexpected diff:\nwant %+v\ngot  %+v", want, diff)
+	}
+
+	var out bytes.Buffer
+	if err := writeDiffSummary(&out, diff); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), `diff summary: {"onlyInA":["us4"],`) {
+		t.Fatalf("unexpected summary %q", out.String())
+	}
+}
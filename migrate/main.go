@@ -2,12 +2,15 @@ package main
 
 import (
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/cockroachdb/pebble"
 )
@@ -18,7 +21,28 @@ type Earthquake struct {
 }
 
 func main() {
-	earthquakeData, err := downloadAndParseCSV()
+	fresh := flag.Bool("fresh", false, "delete an existing new database before migrating")
+	appendMode := flag.Bool("append", false, "add to and update an existing new database instead of deleting it")
+	diff := flag.Bool("diff", false, "compare two databases, by default quake-db and quake-db-new, and exit")
+	verify := flag.Bool("verify", false, "check that the migrated magnitudes read back as the CSV values and flag implausible ones")
+	flag.Parse()
+
+	if *diff {
+		a, b := "quake-db", "quake-db-new"
+		if flag.NArg() == 2 {
+			a, b = flag.Arg(0), flag.Arg(1)
+		}
+		result, err := diffDBs(a, b)
+		if err != nil {
+			log.Fatal("Failed to compare databases:", err)
+		}
+		if err := writeDiffSummary(os.Stdout, result); err != nil {
+			log.Fatal("Failed to write diff summary:", err)
+		}
+		return
+	}
+
+	earthquakeData, err := downloadAndParseCSV(feedURL)
 	if err != nil {
 		log.Fatal("Failed to download and parse CSV:", err)
 	}
@@ -33,26 +57,72 @@ func main() {
 	}
 	defer oldDB.Close()
 
-	if err := os.RemoveAll("quake-db-new"); err != nil {
-		log.Printf("Warning: Failed to remove existing new database: %v", err)
-	}
-
-	newDB, err := pebble.Open("quake-db-new", &pebble.Options{})
+	newDB, err := openNewDB("quake-db-new", *fresh, *appendMode)
 	if err != nil {
 		log.Fatal("Failed to create new database:", err)
 	}
 	defer newDB.Close()
 
+	migratedCount, skippedCount, err := migrateEntries(oldDB, newDB, earthquakeData)
+	if err != nil {
+		log.Fatal("Migration failed:", err)
+	}
+
+	if err := newDB.Flush(); err != nil {
+		log.Fatal("Failed to flush new database:", err)
+	}
+
+	log.Printf("Migration completed successfully!")
+	log.Printf("Migrated: %d entries", migratedCount)
+	log.Printf("Skipped: %d entries (not in CSV)", skippedCount)
+	log.Printf("New database created at: quake-db-new")
+
+	if *verify {
+		result, err := verifyMigration(newDB, earthquakeData)
+		if err != nil {
+			log.Fatal("Verification failed:", err)
+		}
+		log.Printf("Verified: %d entries, %d mismatches, %d implausible magnitudes", result.Checked, len(result.Mismatches), len(result.Implausible))
+		if len(result.Mismatches) > 0 || len(result.Implausible) > 0 {
+			newDB.Close()
+			oldDB.Close()
+			os.Exit(1)
+		}
+	}
+}
+
+// Open the database to migrate to. An existing database is deleted first
+// with fresh and kept with appendMode, so repeated runs only add and update
+// entries. Without either, an existing database is an error rather than
+// silently deleted.
+func openNewDB(path string, fresh, appendMode bool) (*pebble.DB, error) {
+	if fresh && appendMode {
+		return nil, fmt.Errorf("-fresh and -append exclude each other")
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		switch {
+		case fresh:
+			if err := os.RemoveAll(path); err != nil {
+				return nil, fmt.Errorf("failed to remove existing new database: %w", err)
+			}
+		case !appendMode:
+			return nil, fmt.Errorf("%s exists, rerun with -append to update it or -fresh to replace it", path)
+		}
+	}
+	return pebble.Open(path, &pebble.Options{})
+}
+
+// Copy the entries of the old database that exist in the CSV data to the new
+// database, storing the CSV magnitude in the current format
+func migrateEntries(oldDB, newDB *pebble.DB, earthquakeData map[string]Earthquake) (migratedCount, skippedCount int, err error) {
 	// Only copy entries that exist in the CSV file
 	iter, err := oldDB.NewIter(nil)
 	if err != nil {
-		log.Fatal("Failed to create iterator:", err)
+		return 0, 0, fmt.Errorf("failed to create iterator: %w", err)
 	}
 	defer iter.Close()
 
-	migratedCount := 0
-	skippedCount := 0
-
 	for iter.First(); iter.Valid(); iter.Next() {
 		earthquakeID := string(iter.Key())
 
@@ -63,33 +133,28 @@ func main() {
 			continue
 		}
 
-		magnitudeBytes := fmt.Appendf(nil, "%.1f", earthquake.Mag)
+		magnitudeBytes := []byte(formatMag(earthquake.Mag))
 
 		if err := newDB.Set(iter.Key(), magnitudeBytes, &pebble.WriteOptions{}); err != nil {
 			log.Printf("Failed to store earthquake ID %s: %v", earthquakeID, err)
 			continue
 		}
 
-		log.Printf("Migrated earthquake ID %s with magnitude %.1f", earthquakeID, earthquake.Mag)
+		log.Printf("Migrated earthquake ID %s with magnitude %s", earthquakeID, formatMag(earthquake.Mag))
 		migratedCount++
 	}
 
 	if err := iter.Error(); err != nil {
-		log.Fatal("Iterator error:", err)
-	}
-
-	if err := newDB.Flush(); err != nil {
-		log.Fatal("Failed to flush new database:", err)
+		return migratedCount, skippedCount, fmt.Errorf("iterator error: %w", err)
 	}
-
-	log.Printf("Migration completed successfully!")
-	log.Printf("Migrated: %d entries", migratedCount)
-	log.Printf("Skipped: %d entries (not in CSV)", skippedCount)
-	log.Printf("New database created at: quake-db-new")
+	return migratedCount, skippedCount, nil
 }
 
-func downloadAndParseCSV() (map[string]Earthquake, error) {
-	resp, err := http.Get("https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/4.5_week.csv")
+// USGS feed with the magnitudes to migrate
+const feedURL = "https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/4.5_week.csv"
+
+func downloadAndParseCSV(url string) (map[string]Earthquake, error) {
+	resp, err := newHTTPClient(30 * time.Second).Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download CSV: %w", err)
 	}
@@ -99,7 +164,12 @@ func downloadAndParseCSV() (map[string]Earthquake, error) {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	reader := csv.NewReader(resp.Body)
+	return parseCSV(resp.Body)
+}
+
+// Parse the magnitudes of a USGS CSV feed by event ID
+func parseCSV(r io.Reader) (map[string]Earthquake, error) {
+	reader := csv.NewReader(r)
 	headers, err := reader.Read()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CSV header: %w", err)
@@ -137,3 +207,9 @@ func downloadAndParseCSV() (map[string]Earthquake, error) {
 
 	return earthquakeData, nil
 }
+
+// Format a magnitude with one decimal place, rounding halves away from zero
+// so that 5.25 becomes "5.3" instead of the binary-rounded "5.2"
+func formatMag(m float64) string {
+	return strconv.FormatFloat(math.Round(m*10)/10, 'f', 1, 64)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+)
+
+func TestMigrateTwiceInAppendMode(t *testing.T) {
+	oldDB := openTestDB(t, "quake-db")
+	for _, id := range []string{"us1", "us2"} {
+		if err := oldDB.Set([]byte(id), []byte("5.2"), pebble.Sync); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newPath := filepath.Join(t.TempDir(), "quake-db-new")
+
+	migrate := func(data map[string]Earthquake, fresh, appendMode bool) error {
+		newDB, err := openNewDB(newPath, fresh, appendMode)
+		if err != nil {
+			return err
+		}
+		defer newDB.Close()
+		_, _, err = migrateEntries(oldDB, newDB, data)
+		return err
+	}
+
+	// The first run only finds us1 in the CSV, the second one us2 and a revised us1
+	if err := migrate(map[string]Earthquake{"us1": {ID: "us1", Mag: 5.25}}, false, true); err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+	if err := migrate(map[string]Earthquake{"us1": {ID: "us1", Mag: 5.4}, "us2": {ID: "us2", Mag: 6.0}}, false, true); err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+
+	newDB, err := pebble.Open(newPath, &pebble.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"us1": "5.4", "us2": "6.0"} {
+		value, closer, err := newDB.Get([]byte(id))
+		if err != nil {
+			t.Fatalf("expected %s to be stored: %v", id, err)
+		}
+		if string(value) != want {
+			t.Errorf("expected %s to be %s, got %s", id, want, value)
+		}
+		closer.Close()
+	}
+	newDB.Close()
+
+	// Without a mode an existing database is kept and reported
+	if err := migrate(nil, false, false); err == nil || !strings.Contains(err.Error(), "-append") {
+		t.Fatalf("expected an error for an existing database, got %v", err)
+	}
+	// -fresh starts over
+	if err := migrate(map[string]Earthquake{"us2": {ID: "us2", Mag: 6.0}}, true, false); err != nil {
+		t.Fatalf("fresh run failed: %v", err)
+	}
+	newDB, err = pebble.Open(newPath, &pebble.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer newDB.Close()
+	if _, _, err := newDB.Get([]byte("us1")); err != pebble.ErrNotFound {
+		t.Fatalf("expected -fresh to drop us1, got %v", err)
+	}
+}
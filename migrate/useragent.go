@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// USGS asks automated clients to identify themselves with a descriptive
+// User-Agent and may throttle generic ones such as Go's default
+const defaultUserAgent = "bluesky-earthquake-bot-migrate (+https://github.com/ralscha/bluesky-earthquake-bot)"
+
+// The User-Agent of outbound requests from USER_AGENT, defaultUserAgent when unset
+func userAgent() string {
+	if agent := strings.TrimSpace(os.Getenv("USER_AGENT")); agent != "" {
+		return agent
+	}
+	return defaultUserAgent
+}
+
+// userAgentTransport sets the User-Agent on every request it sends
+type userAgentTransport struct {
+	base  http.RoundTripper
+	agent string
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.agent)
+	return t.base.RoundTrip(req)
+}
+
+// HTTP client for all outbound requests, sending the configured User-Agent
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: userAgentTransport{base: http.DefaultTransport, agent: userAgent()},
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloadSendsUserAgent(t *testing.T) {
+	var agent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.Header.Get("User-Agent")
+		w.Write([]byte("time,mag,id\n2026-06-01T10:00:00.000Z,4.5,us1\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	data, err := downloadAndParseCSV(server.URL)
+	if err != nil {
+		t.Fatalf("downloadAndParseCSV returned error: %v", err)
+	}
+	if len(data) != 1 || agent != defaultUserAgent {
+		t.Fatalf("got %d earthquakes with User-Agent %q", len(data), agent)
+	}
+
+	t.Setenv("USER_AGENT", "my-quake-bot (mailto:ops@example.com)")
+	if _, err := downloadAndParseCSV(server.URL); err != nil {
+		t.Fatalf("downloadAndParseCSV returned error: %v", err)
+	}
+	if agent != "my-quake-bot (mailto:ops@example.com)" {
+		t.Fatalf("expected the configured User-Agent, got %q", agent)
+	}
+}
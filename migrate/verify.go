@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Largest difference between a stored magnitude and the CSV magnitude; the
+// stored value has one decimal, so rounding accounts for up to 0.05
+const magnitudeTolerance = 0.05 + 1e-9
+
+// Plausible range of magnitudes, values outside hint at a corrupt CSV row
+const (
+	minPlausibleMag = -1.0
+	maxPlausibleMag = 10.0
+)
+
+// verifyResult lists the IDs of entries that failed a verification check
+type verifyResult struct {
+	Checked     int
+	Mismatches  []string
+	Implausible []string
+}
+
+// Check that every migrated entry reads back as its CSV magnitude and that the
+// CSV magnitude is plausible
+func verifyMigration(newDB *pebble.DB, earthquakeData map[string]Earthquake) (verifyResult, error) {
+	var result verifyResult
+	for _, id := range slices.Sorted(maps.Keys(earthquakeData)) {
+		earthquake := earthquakeData[id]
+		value, closer, err := newDB.Get([]byte(id))
+		if errors.Is(err, pebble.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read earthquake ID %s: %w", id, err)
+		}
+		stored := string(value)
+		closer.Close()
+		result.Checked++
+
+		mag, err := strconv.ParseFloat(stored, 64)
+		if err != nil || math.Abs(mag-earthquake.Mag) > magnitudeTolerance {
+			log.Printf("Mismatch for earthquake ID %s: stored %q, CSV magnitude %v", id, stored, earthquake.Mag)
+			result.Mismatches = append(result.Mismatches, id)
+		}
+		if earthquake.Mag < minPlausibleMag || earthquake.Mag > maxPlausibleMag {
+			log.Printf("Implausible magnitude %v for earthquake ID %s", earthquake.Mag, id)
+			result.Implausible = append(result.Implausible, id)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+)
+
+func openTestDB(t *testing.T, name string) *pebble.DB {
+	t.Helper()
+	db, err := pebble.Open(filepath.Join(t.TempDir(), name), &pebble.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestVerifyMigrationFlagsImplausibleMagnitude(t *testing.T) {
+	csv := `time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status
+2026-06-07T23:10:05.120Z,38.297,142.373,29.0,5.25,mww,,,,,us,us1,,"76 km E of Ishinomaki, Japan",earthquake,reviewed
+2026-06-07T22:00:00.000Z,-18.0,178.0,550.0,61.0,mww,,,,,us,us2,,"Fiji region",earthquake,reviewed
+2026-06-07T21:00:00.000Z,-33.5,-71.8,35.7,4.6,mb,,,,,us,us3,,"Central Chile",earthquake,reviewed
+`
+	earthquakeData, err := parseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("parseCSV returned error: %v", err)
+	}
+
+	oldDB := openTestDB(t, "quake-db")
+	for _, id := range []string{"us1", "us2", "us3"} {
+		if err := oldDB.Set([]byte(id), []byte("5.2"), pebble.Sync); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newDB := openTestDB(t, "quake-db-new")
+	if migrated, skipped, err := migrateEntries(oldDB, newDB, earthquakeData); migrated != 3 || skipped != 0 || err != nil {
+		t.Fatalf("expected 3 migrated entries, got %d migrated, %d skipped (err %v)", migrated, skipped, err)
+	}
+
+	// A corrupt write of one entry
+	if err := newDB.Set([]byte("us3"), []byte("4.9"), pebble.Sync); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := verifyMigration(newDB, earthquakeData)
+	if err != nil {
+		t.Fatalf("verifyMigration returned error: %v", err)
+	}
+	if result.Checked != 3 {
+		t.Fatalf("expected 3 checked entries, got %d", result.Checked)
+	}
+	if !slices.Equal(result.Implausible, []string{"us2"}) {
+		t.Fatalf("expected us2 to be implausible, got %v", result.Implausible)
+	}
+	if !slices.Equal(result.Mismatches, []string{"us3"}) {
+		t.Fatalf("expected us3 to mismatch, got %v", result.Mismatches)
+	}
+}
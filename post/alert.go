@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+)
+
+// Alert rule defaults: M5.5 worldwide and 250 km around home
+const (
+	defaultAlertThreshold = 5.5
+	defaultHomeRadiusKm   = 250
+)
+
+// alertRules decides which reviewed earthquakes are alerted: those at or
+// above the global threshold, and with a home rule also the smaller ones
+// near home.
+type alertRules struct {
+	global float64
+	home   *homeRule
+}
+
+// homeRule alerts earthquakes of at least minMag within radiusKm of a location
+type homeRule struct {
+	lat, lon float64
+	radiusKm float64
+	minMag   float64
+}
+
+// Read the alert rules. ALERT_THRESHOLD sets the global threshold. The home
+// rule needs HOME_ALERT_THRESHOLD and a valid HOME_LAT and HOME_LON, with
+// HOME_RADIUS_KM as radius. Invalid values are logged and ignored.
+func loadAlertRules() alertRules {
+	rules := alertRules{global: envMagnitude("ALERT_THRESHOLD", defaultAlertThreshold)}
+
+	value := os.Getenv("HOME_ALERT_THRESHOLD")
+	if value == "" {
+		return rules
+	}
+	minMag, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid HOME_ALERT_THRESHOLD %q", value)
+		return rules
+	}
+	lat, latErr := strconv.ParseFloat(os.Getenv("HOME_LAT"), 64)
+	lon, lonErr := strconv.ParseFloat(os.Getenv("HOME_LON"), 64)
+	if latErr != nil || lonErr != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		log.Printf("Ignoring HOME_ALERT_THRESHOLD without a valid HOME_LAT and HOME_LON")
+		return rules
+	}
+	radius := float64(defaultHomeRadiusKm)
+	if value := os.Getenv("HOME_RADIUS_KM"); value != "" {
+		if r, err := strconv.ParseFloat(value, 64); err == nil && r > 0 {
+			radius = r
+		} else {
+			log.Printf("Ignoring invalid HOME_RADIUS_KM %q", value)
+		}
+	}
+	rules.home = &homeRule{lat: lat, lon: lon, radiusKm: radius, minMag: minMag}
+	return rules
+}
+
+func envMagnitude(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	mag, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid %s %q", name, value)
+		return fallback
+	}
+	return mag
+}
+
+// Report whether a reviewed earthquake matches any rule
+func (r alertRules) matches(q Earthquake) bool {
+	if q.Status != "reviewed" {
+		return false
+	}
+	if q.Mag >= r.global {
+		return true
+	}
+	return r.home != nil && q.Mag >= r.home.minMag &&
+		distanceKm(r.home.lat, r.home.lon, q.Latitude, q.Longitude) <= r.home.radiusKm
+}
+
+// The earthquakes that match a rule, each ID once. The feeds overlap, the
+// first occurrence is kept so the significant feed wins.
+func (r alertRules) selectAlerts(quakes []Earthquake) []Earthquake {
+	var selected []Earthquake
+	seen := make(map[string]bool)
+	for _, q := range quakes {
+		if seen[q.ID] || !r.matches(q) {
+			continue
+		}
+		seen[q.ID] = true
+		selected = append(selected, q)
+	}
+	return selected
+}
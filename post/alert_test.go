@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAlertRulesNearHome(t *testing.T) {
+	// Zurich, with Basel about 75 km away and Rome about 690 km away
+	t.Setenv("HOME_LAT", "47.37")
+	t.Setenv("HOME_LON", "8.54")
+	t.Setenv("HOME_ALERT_THRESHOLD", "4")
+	t.Setenv("HOME_RADIUS_KM", "200")
+	rules := loadAlertRules()
+	if rules.global != defaultAlertThreshold || rules.home == nil {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+
+	quakes := []Earthquake{
+		{ID: "near", Mag: 4.1, Status: "reviewed", Latitude: 47.56, Longitude: 7.59},
+		{ID: "far", Mag: 4.1, Status: "reviewed", Latitude: 41.9, Longitude: 12.5},
+		{ID: "global", Mag: 6.2, Status: "reviewed", Latitude: -33.4, Longitude: -70.6, IsSignificant: true},
+		{ID: "small", Mag: 3.2, Status: "reviewed", Latitude: 47.56, Longitude: 7.59},
+		{ID: "automatic", Mag: 4.5, Status: "automatic", Latitude: 47.56, Longitude: 7.59},
+		// The same event again from the all-day feed
+		{ID: "global", Mag: 6.2, Status: "reviewed", Latitude: -33.4, Longitude: -70.6},
+	}
+	selected := rules.selectAlerts(quakes)
+	var ids []string
+	for _, q := range selected {
+		ids = append(ids, q.ID)
+	}
+	if !slices.Equal(ids, []string{"near", "global"}) {
+		t.Fatalf("selected %v, want [near global]", ids)
+	}
+	if !selected[1].IsSignificant {
+		t.Fatal("expected the first occurrence of a duplicate to be kept")
+	}
+}
+
+func TestAlertRulesDefaults(t *testing.T) {
+	t.Setenv("HOME_LAT", "47.37")
+	t.Setenv("HOME_LON", "8.54")
+	rules := loadAlertRules()
+	if rules.home != nil {
+		t.Fatal("expected no home rule without HOME_ALERT_THRESHOLD")
+	}
+	if rules.matches(Earthquake{Mag: 5.4, Status: "reviewed", Latitude: 47.37, Longitude: 8.54}) ||
+		!rules.matches(Earthquake{Mag: 5.5, Status: "reviewed"}) {
+		t.Fatal("expected the default global threshold of M5.5")
+	}
+
+	t.Setenv("ALERT_THRESHOLD", "6.5")
+	t.Setenv("HOME_ALERT_THRESHOLD", "4")
+	t.Setenv("HOME_LON", "")
+	rules = loadAlertRules()
+	if rules.global != 6.5 || rules.home != nil {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+}
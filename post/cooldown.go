@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Keys of the per-region cooldown state, "cooldown:<region>"
+const cooldownPrefix = "cooldown:"
+
+// regionCooldown is the alert state of a region. Smaller earthquakes in the
+// region during the cooldown are not alerted but counted as aftershocks.
+type regionCooldown struct {
+	LastAlert time.Time `json:"lastAlert"`
+	// ID and magnitude of the alert that started the cooldown
+	MainshockID string  `json:"mainshockId"`
+	Mainshock   float64 `json:"mainshock"`
+	Aftershocks int     `json:"aftershocks"`
+	Largest     float64 `json:"largest"`
+}
+
+// Stored value of an earthquake suppressed by a cooldown, followed by its
+// magnitude. Alerted earthquakes are stored with the bare magnitude.
+const suppressedPrefix = "suppressed:"
+
+// Report whether a stored value marks a suppressed earthquake
+func isSuppressed(value []byte) bool {
+	return strings.HasPrefix(string(value), suppressedPrefix)
+}
+
+// Earthquakes of this magnitude are always alerted, whatever the cooldown
+const cooldownBypassMag = 7.0
+
+// cooldowns suppresses alerts in a region for period after an alert. A zero
+// period disables the cooldown.
+type cooldowns struct {
+	db     *pebble.DB
+	period time.Duration
+}
+
+// The cooldown from ALERT_COOLDOWN, e.g. "6h". Unset means no cooldown.
+func alertCooldown() time.Duration {
+	value := os.Getenv("ALERT_COOLDOWN")
+	if value == "" {
+		return 0
+	}
+	period, err := time.ParseDuration(value)
+	if err != nil || period < 0 {
+		log.Printf("Ignoring invalid ALERT_COOLDOWN %q", value)
+		return 0
+	}
+	return period
+}
+
+// The region of a USGS place, the part after the last comma:
+// "10 km S of Ofunato, Japan" becomes "Japan"
+func regionOf(place string) string {
+	if i := strings.LastIndex(place, ","); i >= 0 {
+		place = place[i+1:]
+	}
+	return strings.TrimSpace(place)
+}
+
+// Report whether the region of q is in cooldown. A suppressed earthquake is
+// counted for the aftershock summary of its region. An earthquake larger
+// than the one that started the cooldown, or of M7 and above, is not
+// suppressed; its alert starts a new cooldown.
+func (c *cooldowns) suppress(q Earthquake) (bool, error) {
+	if c.period <= 0 {
+		return false, nil
+	}
+	key := []byte(cooldownPrefix + regionOf(q.Place))
+	state, active, err := c.active(key)
+	if err != nil || !active || q.Mag > state.Mainshock || q.Mag >= cooldownBypassMag {
+		return false, err
+	}
+
+	state.Aftershocks++
+	state.Largest = max(state.Largest, q.Mag)
+	return true, c.store(key, state)
+}
+
+// Report whether the upgrade alert of q is held by the cooldown of its
+// region. The upgrade of the earthquake that started the cooldown is not
+// held, nor is an upgrade that would not be suppressed as a new alert.
+func (c *cooldowns) holdsUpgrade(q Earthquake) (bool, error) {
+	if c.period <= 0 {
+		return false, nil
+	}
+	state, active, err := c.active([]byte(cooldownPrefix + regionOf(q.Place)))
+	if err != nil || !active || q.ID == state.MainshockID {
+		return false, err
+	}
+	return q.Mag <= state.Mainshock && q.Mag < cooldownBypassMag, nil
+}
+
+// Start the cooldown of the region of q after posting an alert for it. An
+// alert for the earthquake that started the running cooldown, such as an
+// upgrade, keeps the aftershocks counted so far.
+func (c *cooldowns) start(q Earthquake) error {
+	if c.period <= 0 {
+		return nil
+	}
+	key := []byte(cooldownPrefix + regionOf(q.Place))
+	state, active, err := c.active(key)
+	if err != nil {
+		return err
+	}
+	if !active || state.MainshockID != q.ID {
+		state = regionCooldown{MainshockID: q.ID}
+	}
+	state.LastAlert, state.Mainshock = now(), q.Mag
+	return c.store(key, state)
+}
+
+// Load the cooldown state at key, reporting whether the cooldown is running
+func (c *cooldowns) active(key []byte) (regionCooldown, bool, error) {
+	state, found, err := c.load(key)
+	if err != nil || !found {
+		return state, false, err
+	}
+	return state, now().Sub(state.LastAlert) < c.period, nil
+}
+
+// Post the aftershock summary of every region whose cooldown is over and
+// forget the region. A region without aftershocks is forgotten silently.
+func (c *cooldowns) postSummaries() error {
+	iter, err := c.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(cooldownPrefix),
+		UpperBound: []byte("cooldown;"),
+	})
+	if err != nil {
+		return err
+	}
+
+	expired := make(map[string]regionCooldown)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var state regionCooldown
+		if err := json.Unmarshal(iter.Value(), &state); err != nil {
+			iter.Close()
+			return fmt.Errorf("failed to decode cooldown %s: %w", iter.Key(), err)
+		}
+		if now().Sub(state.LastAlert) >= c.period {
+			expired[string(iter.Key())] = state
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	for key, state := range expired {
+		if state.Aftershocks > 0 {
+			if err := publishPost(aftershockSummary(strings.TrimPrefix(key, cooldownPrefix), state), "", "", ""); err != nil {
+				return fmt.Errorf("failed to post aftershock summary: %w", err)
+			}
+		}
+		if err := c.db.Delete([]byte(key), &pebble.WriteOptions{}); err != nil {
+			return fmt.Errorf("failed to delete cooldown: %w", err)
+		}
+	}
+	return nil
+}
+
+func aftershockSummary(region string, state regionCooldown) string {
+	noun := "earthquakes"
+	if state.Aftershocks == 1 {
+		noun = "earthquake"
+	}
+	return fmt.Sprintf("Aftershock summary for %s\n%d more %s since the alert on %s, the largest M%s",
+		region, state.Aftershocks, noun, state.LastAlert.UTC().Format("2006-01-02 15:04 UTC"), formatMag(state.Largest))
+}
+
+func (c *cooldowns) load(key []byte) (regionCooldown, bool, error) {
+	var state regionCooldown
+	value, closer, err := c.db.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return state, false, nil
+	}
+	if err != nil {
+		return state, false, err
+	}
+	defer closer.Close()
+
+	if err := json.Unmarshal(value, &state); err != nil {
+		return state, false, fmt.Errorf("failed to decode cooldown %s: %w", key, err)
+	}
+	return state, true, nil
+}
+
+func (c *cooldowns) store(key []byte, state regionCooldown) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return c.db.Set(key, value, &pebble.WriteOptions{})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCooldownRollsAftershocksIntoSummary(t *testing.T) {
+	db := openTestDB(t)
+
+	clock := time.Date(2026, 6, 8, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	posts := stubSendPost(t)
+	t.Cleanup(func() { now = time.Now })
+
+	details := newDetailFetcher()
+	quake := func(id, offset, place string, mag float64) Earthquake {
+		details.cache[id] = eventDetail{}
+		return Earthquake{ID: id, Time: "2026-06-08T" + offset + ":00.000Z", Mag: mag, Place: place, Status: "reviewed", Type: "earthquake"}
+	}
+	cooldown := &cooldowns{db: db, period: 6 * time.Hour}
+
+	// The feed lists the newest events first
+	processEarthquakes(db, []Earthquake{
+		quake("us5", "11:40", "20 km E of Ofunato, Japan", 5.6),
+		quake("us4", "11:30", "30 km E of Ofunato, Japan", 6.1),
+		quake("us3", "11:20", "15 km NE of Ofunato, Japan", 5.8),
+		quake("us9", "11:15", "40 km W of Iquique, Chile", 5.7),
+		quake("us2", "11:10", "25 km E of Ofunato, Japan", 5.9),
+		quake("us1", "11:00", "10 km E of Ofunato, Japan", 7.2),
+	}, nil, details, cooldown)
+
+	if len(*posts) != 2 || !strings.HasPrefix((*posts)[0], "7.2 magnitude") || !strings.Contains((*posts)[1], "Iquique, Chile") {
+		t.Fatalf("expected one alert per region, the mainshock first, got %q", *posts)
+	}
+
+	// A later run within the cooldown neither alerts nor summarizes
+	clock = clock.Add(time.Hour)
+	processEarthquakes(db, []Earthquake{quake("us6", "12:30", "5 km S of Kamaishi, Japan", 5.5)}, nil, details, cooldown)
+	if len(*posts) != 2 {
+		t.Fatalf("expected no posts during the cooldown, got %q", (*posts)[2:])
+	}
+
+	clock = clock.Add(6 * time.Hour)
+	processEarthquakes(db, nil, nil, details, cooldown)
+	if len(*posts) != 3 {
+		t.Fatalf("expected one summary after the cooldown, got %q", (*posts)[2:])
+	}
+	want := "Aftershock summary for Japan\n5 more earthquakes since the alert on 2026-06-08 12:00 UTC, the largest M6.1"
+	if (*posts)[2] != want {
+		t.Fatalf("unexpected summary %q", (*posts)[2])
+	}
+
+	processEarthquakes(db, nil, nil, details, cooldown)
+	if len(*posts) != 3 {
+		t.Fatalf("expected the summary to be posted once, got %q", (*posts)[3:])
+	}
+}
+
+func TestCooldownLetsLargerEarthquakesThrough(t *testing.T) {
+	db := openTestDB(t)
+
+	clock := time.Date(2026, 6, 8, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	posts := stubSendPost(t)
+	t.Cleanup(func() { now = time.Now })
+
+	details := newDetailFetcher()
+	quake := func(id, offset string, mag float64) Earthquake {
+		details.cache[id] = eventDetail{}
+		return Earthquake{ID: id, Time: "2026-06-08T" + offset + ":00.000Z", Mag: mag, Place: "10 km E of Ofunato, Japan", Status: "reviewed", Type: "earthquake"}
+	}
+	cooldown := &cooldowns{db: db, period: 6 * time.Hour}
+
+	processEarthquakes(db, []Earthquake{
+		quake("us1", "11:00", 6.0),
+		quake("us2", "11:10", 5.8),
+		quake("us3", "11:20", 6.6),
+		quake("us4", "11:30", 6.2),
+	}, nil, details, cooldown)
+	if len(*posts) != 2 || !strings.HasPrefix((*posts)[0], "6.0 magnitude") || !strings.HasPrefix((*posts)[1], "6.6 magnitude") {
+		t.Fatalf("expected the M6.0 and the larger M6.6 to be alerted, got %q", *posts)
+	}
+
+	// The M6.6 started a new cooldown, which an M7 bypasses as well
+	processEarthquakes(db, []Earthquake{quake("us5", "11:40", 6.4), quake("us6", "11:50", 7.0)}, nil, details, cooldown)
+	if len(*posts) != 3 || !strings.HasPrefix((*posts)[2], "7.0 magnitude") {
+		t.Fatalf("expected only the M7.0 to be alerted, got %q", (*posts)[2:])
+	}
+}
+
+func TestSuppressedEarthquakeGetsNoUpdate(t *testing.T) {
+	db := openTestDB(t)
+
+	posts := stubSendPost(t)
+
+	details := newDetailFetcher()
+	details.cache["us1"], details.cache["us2"] = eventDetail{}, eventDetail{}
+	cooldown := &cooldowns{db: db, period: 6 * time.Hour}
+	quakes := func(mag float64) []Earthquake {
+		return []Earthquake{
+			{ID: "us1", Time: "2026-06-08T11:00:00.000Z", Mag: 6.8, Place: "Near Ofunato, Japan", Status: "reviewed", Type: "earthquake"},
+			{ID: "us2", Time: "2026-06-08T11:10:00.000Z", Mag: mag, Place: "Near Ofunato, Japan", Status: "reviewed", Type: "earthquake"},
+		}
+	}
+
+	processEarthquakes(db, quakes(6.1), nil, details, cooldown)
+	processEarthquakes(db, quakes(6.3), nil, details, cooldown)
+	t.Setenv("MAG_UPGRADE_DELTA", "0.1")
+	processEarthquakes(db, quakes(6.5), nil, details, cooldown)
+	if len(*posts) != 1 {
+		t.Fatalf("expected only the mainshock to be alerted, got %q", *posts)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const detailBaseURL = "https://earthquake.usgs.gov/fdsnws/event/1/query"
+
+// Attempts per event detail, so a slow API delays an alert by at most
+// detailAttempts times the client timeout plus the backoff
+const detailAttempts = 3
+
+// errEventNotFound means the FDSN API does not know the event, usually
+// because it was deleted after the feed was generated
+var errEventNotFound = errors.New("event not found")
+
+// eventDetail holds the fields of the FDSN event detail that the bulk CSV lacks
+type eventDetail struct {
+	DepthError float64
+	Stations   int
+}
+
+// detailFetcher downloads event details from the FDSN event API. Responses are
+// cached because the same event usually shows up in more than one feed, and
+// requests are spaced at least interval apart. Timeouts, rate limits and
+// server errors are retried up to detailAttempts times, waiting backoff and
+// then twice as long before each retry.
+type detailFetcher struct {
+	client   *http.Client
+	baseURL  string
+	interval time.Duration
+	backoff  time.Duration
+	last     time.Time
+	cache    map[string]eventDetail
+	// Events the API reported as not found, not requested again
+	missing map[string]bool
+}
+
+func newDetailFetcher() *detailFetcher {
+	return &detailFetcher{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		baseURL:  detailBaseURL,
+		interval: time.Second,
+		backoff:  2 * time.Second,
+		cache:    make(map[string]eventDetail),
+		missing:  make(map[string]bool),
+	}
+}
+
+// Merge the event detail into the earthquake. On error the earthquake keeps
+// its bulk feed fields, so the alert can still be posted.
+func (f *detailFetcher) enrich(q *Earthquake) error {
+	detail, err := f.fetch(q.ID)
+	if err != nil {
+		return err
+	}
+	q.DepthError = detail.DepthError
+	q.Stations = detail.Stations
+	return nil
+}
+
+func (f *detailFetcher) fetch(id string) (eventDetail, error) {
+	if detail, ok := f.cache[id]; ok {
+		return detail, nil
+	}
+	if f.missing[id] {
+		return eventDetail{}, errEventNotFound
+	}
+
+	var err error
+	for attempt := range detailAttempts {
+		if attempt > 0 {
+			time.Sleep(f.backoff << (attempt - 1))
+		}
+		detail, retry, requestErr := f.request(id)
+		if err = requestErr; err == nil {
+			f.cache[id] = detail
+			return detail, nil
+		}
+		if errors.Is(err, errEventNotFound) {
+			f.missing[id] = true
+		}
+		if !retry {
+			break
+		}
+	}
+	return eventDetail{}, err
+}
+
+// Request the detail of an event once, reporting whether a failure is
+// worth retrying
+func (f *detailFetcher) request(id string) (eventDetail, bool, error) {
+	if wait := f.interval - time.Since(f.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	f.last = time.Now()
+
+	query := url.Values{"eventid": {id}, "format": {"geojson"}}
+	resp, err := f.client.Get(f.baseURL + "?" + query.Encode())
+	if err != nil {
+		return eventDetail{}, true, fmt.Errorf("failed to download event detail: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNoContent:
+		return eventDetail{}, false, errEventNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return eventDetail{}, true, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return eventDetail{}, false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var feature struct {
+		Properties struct {
+			Nst      *int `json:"nst"`
+			Products struct {
+				Origin []struct {
+					Properties map[string]string `json:"properties"`
+				} `json:"origin"`
+			} `json:"products"`
+		} `json:"properties"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&feature); err != nil {
+		return eventDetail{}, false, fmt.Errorf("failed to decode event detail: %w", err)
+	}
+
+	var detail eventDetail
+	if feature.Properties.Nst != nil {
+		detail.Stations = *feature.Properties.Nst
+	}
+	if origins := feature.Properties.Products.Origin; len(origins) > 0 {
+		if depthError, err := strconv.ParseFloat(origins[0].Properties["depth-uncertainty"], 64); err == nil {
+			detail.DepthError = depthError
+		}
+	}
+
+	return detail, false, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDetailFetcherCachesEventDetail(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.URL.Query().Get("eventid"); got != "us123" {
+			t.Errorf("expected eventid us123, got %q", got)
+		}
+		w.Write([]byte(`{"type":"Feature","properties":{"nst":120,"products":{"origin":[{"properties":{"depth-uncertainty":"1.8"}}]}}}`))
+	}))
+	defer server.Close()
+
+	fetcher := newDetailFetcher()
+	fetcher.baseURL = server.URL
+	fetcher.interval = 0
+
+	first := Earthquake{ID: "us123", Depth: 10}
+	if err := fetcher.enrich(&first); err != nil {
+		t.Fatalf("enrich returned error: %v", err)
+	}
+	if first.Stations != 120 || first.DepthError != 1.8 {
+		t.Fatalf("expected detail fields to be merged, got %+v", first)
+	}
+
+	second := Earthquake{ID: "us123"}
+	if err := fetcher.enrich(&second); err != nil {
+		t.Fatalf("enrich returned error on cache hit: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request with a cache hit, got %d", requests)
+	}
+	if second.Stations != 120 {
+		t.Fatalf("expected cached detail to be merged, got %+v", second)
+	}
+}
+
+func TestDetailFetcherRetriesServerErrors(t *testing.T) {
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("eventid")
+		requests[id]++
+		if id == "us456" || requests[id] < detailAttempts {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"type":"Feature","properties":{"nst":42}}`))
+	}))
+	defer server.Close()
+
+	fetcher := newDetailFetcher()
+	fetcher.baseURL, fetcher.interval, fetcher.backoff = server.URL, 0, 0
+
+	q := Earthquake{ID: "us123"}
+	if err := fetcher.enrich(&q); err != nil || q.Stations != 42 {
+		t.Fatalf("expected the last attempt to succeed, got %+v (err %v)", q, err)
+	}
+
+	// Give up after detailAttempts failures
+	if err := fetcher.enrich(&Earthquake{ID: "us456"}); err == nil || requests["us456"] != detailAttempts {
+		t.Fatalf("expected %d attempts and an error, got %d attempts (err %v)", detailAttempts, requests["us456"], err)
+	}
+}
+
+func TestPostEarthquakeWithoutDetailOfDeletedEvent(t *testing.T) {
+	db := openTestDB(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	posts := stubSendPost(t)
+
+	details := newDetailFetcher()
+	details.baseURL, details.interval, details.backoff = server.URL, 0, 0
+
+	q := Earthquake{ID: "us1", Time: "2026-06-08T10:00:00.000Z", Mag: 5.4, Depth: 12, Place: "10 km S of Somewhere", Type: "earthquake"}
+	if err := postEarthquake(q, "", db, []byte("us1"), details); err != nil {
+		t.Fatalf("postEarthquake returned error: %v", err)
+	}
+	if len(*posts) != 1 || !strings.Contains((*posts)[0], "5.4 magnitude") || !strings.Contains((*posts)[0], "10 km S of Somewhere") {
+		t.Fatalf("expected the alert with the feed fields, got %q", *posts)
+	}
+	if strings.Contains((*posts)[0], "stations") {
+		t.Fatalf("expected no detail line, got %q", (*posts)[0])
+	}
+	if requests != 1 {
+		t.Fatalf("expected a 404 not to be retried, got %d requests", requests)
+	}
+
+	// The miss is remembered
+	if err := details.enrich(&q); !errors.Is(err, errEventNotFound) || requests != 1 {
+		t.Fatalf("expected a cached miss, got %v after %d requests", err, requests)
+	}
+}
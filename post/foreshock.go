@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+)
+
+// Feed with the earthquakes of all magnitudes of the past day
+const recentFeedURL = "https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_day.csv"
+
+// Earthquakes within this distance of an alerted one count as nearby
+const foreshockRadiusKm = 100
+
+const defaultForeshockWindow = 24 * time.Hour
+
+// How far back to look for foreshocks, from FORESHOCK_WINDOW (e.g. "12h").
+// The recent feed covers one day, so longer windows find no more events.
+func foreshockWindow() time.Duration {
+	value := os.Getenv("FORESHOCK_WINDOW")
+	if value == "" {
+		return defaultForeshockWindow
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		log.Printf("Ignoring invalid FORESHOCK_WINDOW %q", value)
+		return defaultForeshockWindow
+	}
+	return window
+}
+
+// Count the smaller earthquakes within foreshockRadiusKm of q in the window
+// before it. Events without a valid time are ignored.
+func countForeshocks(q Earthquake, recent []Earthquake, window time.Duration) int {
+	t, err := time.Parse(time.RFC3339Nano, q.Time)
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, e := range recent {
+		if e.ID == q.ID || e.Mag >= q.Mag {
+			continue
+		}
+		et, err := time.Parse(time.RFC3339Nano, e.Time)
+		if err != nil || !et.Before(t) || t.Sub(et) > window {
+			continue
+		}
+		if distanceKm(q.Latitude, q.Longitude, e.Latitude, e.Longitude) <= foreshockRadiusKm {
+			count++
+		}
+	}
+	return count
+}
+
+// Note on the foreshocks of the earthquake, empty without any
+func foreshockLine(q Earthquake) string {
+	if q.Foreshocks == 0 {
+		return ""
+	}
+	noun := "quakes"
+	if q.Foreshocks == 1 {
+		noun = "quake"
+	}
+	return fmt.Sprintf("\nFollows %d nearby %s in the last %s", q.Foreshocks, noun, formatWindow(foreshockWindow()))
+}
+
+// Format a window as "24h" or "90m", falling back to the Go duration format
+func formatWindow(window time.Duration) string {
+	switch {
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	case window%time.Minute == 0:
+		return fmt.Sprintf("%dm", window/time.Minute)
+	default:
+		return window.String()
+	}
+}
+
+// Great-circle distance between two points using the haversine formula
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
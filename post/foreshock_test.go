@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountForeshocksFindsSmallerNearbyEarlierQuakes(t *testing.T) {
+	mainshock := Earthquake{ID: "us0", Time: "2026-06-08T12:00:00.000Z", Mag: 6.4, Latitude: 38.3, Longitude: 142.4}
+	recent := []Earthquake{
+		mainshock,
+		{ID: "us1", Time: "2026-06-07T14:00:00.000Z", Mag: 3.1, Latitude: 38.4, Longitude: 142.5},
+		{ID: "us2", Time: "2026-06-08T09:30:00.000Z", Mag: 4.2, Latitude: 38.1, Longitude: 142.1},
+		{ID: "us3", Time: "2026-06-08T11:55:00.000Z", Mag: 5.0, Latitude: 38.9, Longitude: 142.9},
+		// Too far away, too early, too large and after the mainshock
+		{ID: "us4", Time: "2026-06-08T10:00:00.000Z", Mag: 3.0, Latitude: 35.7, Longitude: 139.7},
+		{ID: "us5", Time: "2026-06-07T11:00:00.000Z", Mag: 3.5, Latitude: 38.3, Longitude: 142.4},
+		{ID: "us6", Time: "2026-06-08T08:00:00.000Z", Mag: 6.6, Latitude: 38.3, Longitude: 142.4},
+		{ID: "us7", Time: "2026-06-08T12:05:00.000Z", Mag: 4.8, Latitude: 38.3, Longitude: 142.4},
+	}
+
+	mainshock.Foreshocks = countForeshocks(mainshock, recent, 24*time.Hour)
+	if mainshock.Foreshocks != 3 {
+		t.Fatalf("expected 3 foreshocks, got %d", mainshock.Foreshocks)
+	}
+	if got := foreshockLine(mainshock); got != "\nFollows 3 nearby quakes in the last 24h" {
+		t.Fatalf("unexpected foreshock line %q", got)
+	}
+
+	if got := countForeshocks(mainshock, recent, 3*time.Hour); got != 2 {
+		t.Fatalf("expected 2 foreshocks in a 3h window, got %d", got)
+	}
+
+	t.Setenv("FORESHOCK_WINDOW", "90m")
+	mainshock.Foreshocks = countForeshocks(mainshock, recent, foreshockWindow())
+	if got := foreshockLine(mainshock); got != "\nFollows 1 nearby quake in the last 90m" {
+		t.Fatalf("unexpected foreshock line %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "fmt"
+
+// prePostHook checks the text of a post before it is sent. Returning an
+// error aborts the post, e.g. for banned words or a missing tag.
+type prePostHook func(text string) error
+
+// Hooks run in registration order before every post
+var prePostHooks []prePostHook
+
+// Register a hook for every following post. Deployments with their own
+// policies add a file to this package that registers a hook in an init
+// function.
+func registerPrePostHook(hook prePostHook) {
+	prePostHooks = append(prePostHooks, hook)
+}
+
+// Run the pre-post hooks and send the post when all of them accept it.
+// Nothing is sent in safe mode.
+func publishPost(text string, earthquakeType string, fullURL string, shortURL string) error {
+	if safeMode() {
+		return errSafeMode
+	}
+	for _, hook := range prePostHooks {
+		if err := hook(text); err != nil {
+			return fmt.Errorf("post rejected: %w", err)
+		}
+	}
+	return sendPost(text, earthquakeType, fullURL, shortURL)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPrePostHookRejectsPost(t *testing.T) {
+	db := openTestDB(t)
+
+	posts := stubSendPost(t)
+	registerPrePostHook(func(text string) error {
+		if strings.Contains(text, "Forbidden") {
+			return errors.New("banned word")
+		}
+		return nil
+	})
+	t.Cleanup(func() { prePostHooks = nil })
+
+	details := newDetailFetcher()
+	quake := func(id, place string) Earthquake {
+		details.cache[id] = eventDetail{}
+		return Earthquake{ID: id, Time: "2026-06-08T10:00:00.000Z", Mag: 6.1, Place: place, Status: "reviewed", Type: "earthquake"}
+	}
+
+	err := postEarthquake(quake("us1", "Forbidden Valley"), "", db, []byte("us1"), details)
+	if err == nil || !strings.Contains(err.Error(), "post rejected: banned word") {
+		t.Fatalf("expected the hook to reject the post, got %v", err)
+	}
+	if len(*posts) != 0 {
+		t.Fatalf("expected nothing to be posted, got %q", *posts)
+	}
+	if _, closer, err := db.Get([]byte("us1")); err == nil {
+		closer.Close()
+		t.Fatal("expected a rejected earthquake not to be stored")
+	}
+
+	if err := postEarthquake(quake("us2", "Fiji"), "", db, []byte("us2"), details); err != nil || len(*posts) != 1 {
+		t.Fatalf("expected an accepted post, got %d posts (err %v)", len(*posts), err)
+	}
+}
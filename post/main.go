@@ -2,13 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -29,13 +36,35 @@ type Earthquake struct {
 	Place         string
 	Type          string
 	IsSignificant bool
+	Depth         float64
+	DepthError    float64
+	Stations      int
+	Latitude      float64
+	Longitude     float64
+	// Number of smaller nearby earthquakes shortly before this one
+	Foreshocks int
 }
 
+// openPebble opens a Pebble database. Tests replace it to simulate open errors.
+var openPebble = pebble.Open
+
+// sendPost posts to Bluesky. Tests replace it to capture the posts.
+var sendPost = postToBluesky
+
+// now returns the current time. Tests replace it to move the clock.
+var now = time.Now
+
 func main() {
+	repairDB := flag.Bool("repair-db", false, "move a corrupt database aside and start with an empty one")
+	flag.Parse()
+
 	err := godotenv.Load()
 	if err != nil && !os.IsNotExist(err) {
 		log.Fatal("Error loading .env file")
 	}
+	if safeMode() {
+		logSafeModeBanner()
+	}
 
 	feedURLs := []struct {
 		url           string
@@ -55,23 +84,59 @@ func main() {
 		earthquakes = append(earthquakes, quakes...)
 	}
 
-	var filtered []Earthquake
-	for _, q := range earthquakes {
-		if q.Mag >= 5.5 && q.Status == "reviewed" {
-			filtered = append(filtered, q)
+	rules := loadAlertRules()
+	filtered := rules.selectAlerts(earthquakes)
+
+	// All earthquakes of the past day, to find foreshocks of the alerted ones.
+	// The M4.5 feed misses smaller earthquakes near home, so the home rule is
+	// also checked against this feed.
+	var recent []Earthquake
+	if len(filtered) > 0 || rules.home != nil {
+		recent, err = fetchEarthquakes(recentFeedURL, false)
+		if err != nil {
+			log.Printf("Failed to fetch recent earthquakes: %v", err)
+		} else if rules.home != nil {
+			filtered = rules.selectAlerts(append(earthquakes, recent...))
 		}
 	}
 
-	db, err := pebble.Open("quake-db", &pebble.Options{})
+	db, lost, err := openDB("quake-db", *repairDB)
 	if err != nil {
 		log.Fatal("Failed to open database:", err)
 	}
 	defer db.Close()
+	if lost {
+		markAlerted(db, filtered)
+	}
+	pdsCache = db
 
-	for _, q := range filtered {
+	processEarthquakes(db, filtered, recent, newDetailFetcher(), &cooldowns{db: db, period: alertCooldown()})
+	_ = db.Flush()
+}
+
+// Post the earthquakes that were not posted yet, oldest first so that a
+// mainshock is alerted before its aftershocks. Recent holds the earthquakes
+// of all magnitudes in which foreshocks are looked up.
+func processEarthquakes(db *pebble.DB, quakes []Earthquake, recent []Earthquake, details *detailFetcher, cooldown *cooldowns) {
+	if err := cooldown.postSummaries(); err != nil {
+		log.Printf("Failed to post aftershock summaries: %v", err)
+	}
+
+	slices.SortStableFunc(quakes, func(a, b Earthquake) int {
+		return strings.Compare(a.Time, b.Time)
+	})
+
+	window := foreshockWindow()
+	upgradeDelta := magnitudeUpgradeDelta()
+	for _, q := range quakes {
 		key := []byte(q.ID)
+		q.Foreshocks = countForeshocks(q, recent, window)
+
+		if upgradeDelta > 0 && checkUpgrade(q, db, key, details, cooldown, upgradeDelta) {
+			continue
+		}
 
-		if q.Mag >= 5.5 && q.Mag < 6 {
+		if q.Mag < 6 {
 			_, closer, err := db.Get(key)
 			if err == nil {
 				closer.Close()
@@ -82,24 +147,21 @@ func main() {
 				continue
 			}
 
-			if err := postEarthquake(q, "", db, key); err != nil {
-				log.Printf("Failed to post earthquake ID %s: %v", q.ID, err)
-			}
+			postNewEarthquake(q, db, key, details, cooldown)
 			continue
 		} else {
 			value, closer, err := db.Get(key)
 
 			if errors.Is(err, pebble.ErrNotFound) {
-				if err := postEarthquake(q, "", db, key); err != nil {
-					log.Printf("Failed to post earthquake ID %s: %v", q.ID, err)
-				}
+				postNewEarthquake(q, db, key, details, cooldown)
 			} else if err == nil {
-				storedMagStr := string(value)
-				currentMagStr := fmt.Sprintf("%.1f", q.Mag)
+				unchanged := sameMag(string(value), q.Mag)
+				suppressed := isSuppressed(value)
 				closer.Close()
 
-				if storedMagStr != currentMagStr {
-					if err := postEarthquake(q, "Updated:\n", db, key); err != nil {
+				// A suppressed earthquake was never alerted, so it gets no update
+				if !unchanged && !suppressed {
+					if err := postEarthquake(q, "Updated:\n", db, key, details); err != nil {
 						log.Printf("Failed to post updated earthquake ID %s: %v", q.ID, err)
 					}
 				}
@@ -108,7 +170,110 @@ func main() {
 			}
 		}
 	}
-	_ = db.Flush()
+}
+
+// Post a new earthquake unless its region is in cooldown. A suppressed
+// earthquake is stored with suppressedPrefix so it is not alerted later, not
+// even as an update.
+func postNewEarthquake(q Earthquake, db *pebble.DB, key []byte, details *detailFetcher, cooldown *cooldowns) {
+	suppressed, err := cooldown.suppress(q)
+	if err != nil {
+		log.Printf("Failed to check the cooldown for earthquake ID %s: %v", q.ID, err)
+	}
+	if suppressed {
+		if err := db.Set(key, []byte(suppressedPrefix+formatMag(q.Mag)), &pebble.WriteOptions{}); err != nil {
+			log.Printf("Failed to store suppressed earthquake ID %s: %v", q.ID, err)
+		}
+		return
+	}
+
+	if err := postEarthquake(q, "", db, key, details); err != nil {
+		log.Printf("Failed to post earthquake ID %s: %v", q.ID, err)
+		return
+	}
+	if err := cooldown.start(q); err != nil {
+		log.Printf("Failed to start the cooldown for earthquake ID %s: %v", q.ID, err)
+	}
+}
+
+// openDB opens the database at path. If Pebble reports corruption and repair is
+// set, the corrupt directory is moved aside, a new database is created and
+// the entries that can still be read from the corrupt one are copied into it.
+// Reports whether entries may have been lost, in which case recent
+// earthquakes may be posted again.
+func openDB(path string, repair bool) (*pebble.DB, bool, error) {
+	db, err := openPebble(path, &pebble.Options{})
+	if err == nil || !repair || !pebble.IsCorruptionError(err) {
+		return db, false, err
+	}
+
+	corruptPath := fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+	log.Printf("WARNING: database %s is corrupt (%v), moving it to %s and starting with a new database", path, err, corruptPath)
+	if err := os.Rename(path, corruptPath); err != nil {
+		return nil, false, fmt.Errorf("failed to move corrupt database aside: %w", err)
+	}
+
+	db, err = openPebble(path, &pebble.Options{})
+	if err != nil {
+		return nil, false, err
+	}
+	copied, err := recoverEntries(corruptPath, db)
+	if err != nil {
+		log.Printf("WARNING: recovered %d entries of the corrupt database, the others are lost: %v", copied, err)
+		return db, true, nil
+	}
+	log.Printf("Recovered %d entries of the corrupt database", copied)
+	return db, false, nil
+}
+
+// Copy the entries of the database at path into db, returning the number of
+// entries copied. The database is opened read-only, so a corrupt one is not
+// changed.
+func recoverEntries(path string, db *pebble.DB) (int, error) {
+	old, err := openPebble(path, &pebble.Options{ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer old.Close()
+
+	iter, err := old.NewIter(nil)
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := db.Set(iter.Key(), iter.Value(), &pebble.WriteOptions{}); err != nil {
+			iter.Close()
+			return copied, err
+		}
+		copied++
+	}
+	return copied, errors.Join(iter.Error(), iter.Close())
+}
+
+// Store the earthquakes that are not in the database as alerted, without
+// posting them. After a repair that lost entries, this keeps the alerts of
+// the earthquakes still in the feeds from being posted again.
+func markAlerted(db *pebble.DB, quakes []Earthquake) {
+	marked := 0
+	for _, q := range quakes {
+		key := []byte(q.ID)
+		_, closer, err := db.Get(key)
+		if err == nil {
+			closer.Close()
+			continue
+		}
+		if !errors.Is(err, pebble.ErrNotFound) {
+			log.Printf("Database error for ID %s: %v", q.ID, err)
+			continue
+		}
+		if err := db.Set(key, []byte(formatMag(q.Mag)), &pebble.WriteOptions{}); err != nil {
+			log.Printf("Failed to mark earthquake ID %s as alerted: %v", q.ID, err)
+			continue
+		}
+		marked++
+	}
+	log.Printf("Marked %d earthquakes of the feeds as already alerted", marked)
 }
 
 func fetchEarthquakes(url string, isSignificant bool) ([]Earthquake, error) {
@@ -157,6 +322,10 @@ func parseEarthquakesCSV(r io.Reader, isSignificant bool) ([]Earthquake, error)
 			continue
 		}
 
+		depth, _ := strconv.ParseFloat(quakeMap["depth"], 64)
+		latitude, _ := strconv.ParseFloat(quakeMap["latitude"], 64)
+		longitude, _ := strconv.ParseFloat(quakeMap["longitude"], 64)
+
 		earthquakes = append(earthquakes, Earthquake{
 			Time:          quakeMap["time"],
 			Mag:           mag,
@@ -165,35 +334,41 @@ func parseEarthquakesCSV(r io.Reader, isSignificant bool) ([]Earthquake, error)
 			Place:         quakeMap["place"],
 			Type:          quakeMap["type"],
 			IsSignificant: isSignificant,
+			Depth:         depth,
+			Latitude:      latitude,
+			Longitude:     longitude,
 		})
 	}
 
 	return earthquakes, nil
 }
 
-func postEarthquake(q Earthquake, prefix string, db *pebble.DB, key []byte) error {
+func postEarthquake(q Earthquake, prefix string, db *pebble.DB, key []byte, details *detailFetcher) error {
 	t, err := time.Parse(time.RFC3339Nano, q.Time)
 	if err != nil {
 		return fmt.Errorf("failed to parse time: %w", err)
 	}
 
+	if err := details.enrich(&q); err != nil {
+		log.Printf("Posting earthquake ID %s without details: %v", q.ID, err)
+	}
+
 	isoTimestamp := t.Format("2006-01-02 15:04:05 UTC")
 
-	fullURL := fmt.Sprintf("https://earthquake.usgs.gov/earthquakes/eventpage/%s/executive", q.ID)
-	shortURL := fmt.Sprintf("earthquake.usgs.gov/%s", q.ID)
+	fullURL, shortURL := eventPageURL(q.ID)
 
 	if q.IsSignificant && prefix == "" {
 		prefix = "Significant earthquake\n"
 	}
 
-	msg := fmt.Sprintf("%s%.1f magnitude %s #%s\n%s\n%s\n\n%s",
-		prefix, q.Mag, earthquakeTypeByMagnitude(q.Mag), q.Type, isoTimestamp, q.Place, shortURL)
+	msg := fmt.Sprintf("%s%s magnitude %s #%s\n%s\n%s%s%s\n\n%s",
+		prefix, formatMag(q.Mag), earthquakeTypeByMagnitude(q.Mag), q.Type, isoTimestamp, q.Place, detailLine(q), foreshockLine(q), shortURL)
 
-	if err := postToBluesky(msg, q.Type, fullURL, shortURL); err != nil {
+	if err := publishPost(msg, q.Type, fullURL, shortURL); err != nil {
 		return fmt.Errorf("failed to post to Bluesky: %w", err)
 	}
 
-	magnitudeBytes := fmt.Appendf(nil, "%.1f", q.Mag)
+	magnitudeBytes := []byte(formatMag(q.Mag))
 	if err := db.Set(key, magnitudeBytes, &pebble.WriteOptions{}); err != nil {
 		return fmt.Errorf("failed to store magnitude: %w", err)
 	}
@@ -201,9 +376,20 @@ func postEarthquake(q Earthquake, prefix string, db *pebble.DB, key []byte) erro
 	return nil
 }
 
+// Format the depth and station count from the event detail, empty without detail
+func detailLine(q Earthquake) string {
+	if q.Stations == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nDepth %.1f km (±%.1f km), %d stations", q.Depth, q.DepthError, q.Stations)
+}
+
 func postToBluesky(text string, earthquakeType string, fullURL string, shortURL string) error {
 	identifier := os.Getenv("BLUESKY_IDENTIFIER")
-	password := os.Getenv("BLUESKY_PASSWORD")
+	password, err := blueskyPassword()
+	if err != nil {
+		return err
+	}
 	if identifier == "" || password == "" {
 		return fmt.Errorf("missing Bluesky credentials in environment variables")
 	}
@@ -213,7 +399,7 @@ func postToBluesky(text string, earthquakeType string, fullURL string, shortURL
 		host = "https://me.rasc.ch"
 	}
 
-	client := &xrpc.Client{Host: host}
+	client := &xrpc.Client{Host: pdsHost(context.Background(), host, identifier)}
 
 	auth, err := atproto.ServerCreateSession(
 		context.Background(),
@@ -226,33 +412,66 @@ func postToBluesky(text string, earthquakeType string, fullURL string, shortURL
 	if err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
+	warnFullAccessSession(auth.AccessJwt)
 
 	authClient := xrpc.Client{
 		Host: client.Host,
 		Auth: &xrpc.AuthInfo{AccessJwt: auth.AccessJwt},
 	}
 
+	post := &bsky.FeedPost{
+		Text:      text,
+		Langs:     []string{"en"},
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Facets:    buildFacets(text, earthquakeType, fullURL, shortURL),
+	}
+
+	_, err = atproto.RepoCreateRecord(
+		context.Background(),
+		&authClient,
+		&atproto.RepoCreateRecord_Input{
+			Repo:       auth.Did,
+			Collection: "app.bsky.feed.post",
+			Record:     &util.LexiconTypeDecoder{Val: post},
+		},
+	)
+	return err
+}
+
+// URL of the USGS event page and the shorter form shown in the post text. The
+// ID is escaped so unusual characters cannot change the path.
+func eventPageURL(id string) (fullURL string, shortURL string) {
+	escaped := url.PathEscape(id)
+	return "https://earthquake.usgs.gov/earthquakes/eventpage/" + escaped, "earthquake.usgs.gov/" + escaped
+}
+
+// Link facet over shortURL, pointing to fullURL, and tag facet over the
+// "#type" hashtag. Facet ranges are byte offsets into the UTF-8 text.
+func buildFacets(text string, earthquakeType string, fullURL string, shortURL string) []*bsky.RichtextFacet {
+	var facets []*bsky.RichtextFacet
+
 	linkStartPos := strings.Index(text, shortURL)
-	linkEndPos := linkStartPos + len(shortURL)
+	if shortURL != "" && linkStartPos != -1 {
+		linkEndPos := linkStartPos + len(shortURL)
 
-	linkFacet := &bsky.RichtextFacet{
-		Features: []*bsky.RichtextFacet_Features_Elem{
-			{
-				RichtextFacet_Link: &bsky.RichtextFacet_Link{
-					Uri: fullURL,
+		linkFacet := &bsky.RichtextFacet{
+			Features: []*bsky.RichtextFacet_Features_Elem{
+				{
+					RichtextFacet_Link: &bsky.RichtextFacet_Link{
+						Uri: fullURL,
+					},
 				},
 			},
-		},
-		Index: &bsky.RichtextFacet_ByteSlice{
-			ByteEnd:   int64(linkEndPos),
-			ByteStart: int64(linkStartPos),
-		},
+			Index: &bsky.RichtextFacet_ByteSlice{
+				ByteEnd:   int64(linkEndPos),
+				ByteStart: int64(linkStartPos),
+			},
+		}
+		facets = append(facets, linkFacet)
 	}
 
-	facets := []*bsky.RichtextFacet{linkFacet}
-
 	tagStartPos := strings.Index(text, "#"+earthquakeType)
-	if tagStartPos != -1 {
+	if earthquakeType != "" && tagStartPos != -1 {
 		tagEndPos := tagStartPos + len(earthquakeType) + 1
 
 		tagFacet := &bsky.RichtextFacet{
@@ -271,23 +490,76 @@ func postToBluesky(text string, earthquakeType string, fullURL string, shortURL
 		facets = append(facets, tagFacet)
 	}
 
-	post := &bsky.FeedPost{
-		Text:      text,
-		Langs:     []string{"en"},
-		CreatedAt: time.Now().Format(time.RFC3339),
-		Facets:    facets,
+	return facets
+}
+
+// Scope of app password sessions, "com.atproto.appPassPrivileged" when the
+// app password may also read direct messages
+const appPassScopePrefix = "com.atproto.appPass"
+
+// Report whether an access token belongs to a session with the full access
+// of the account password. The scope claim of the token is read without
+// verifying it, tokens that cannot be read are not reported.
+func isFullAccessSession(accessJwt string) bool {
+	parts := strings.Split(accessJwt, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
 	}
+	var claims struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Scope == "" {
+		return false
+	}
+	return !strings.HasPrefix(claims.Scope, appPassScopePrefix)
+}
 
-	_, err = atproto.RepoCreateRecord(
-		context.Background(),
-		&authClient,
-		&atproto.RepoCreateRecord_Input{
-			Repo:       auth.Did,
-			Collection: "app.bsky.feed.post",
-			Record:     &util.LexiconTypeDecoder{Val: post},
-		},
-	)
-	return err
+// Warn when a session has the full access of the account password
+func warnFullAccessSession(accessJwt string) {
+	if isFullAccessSession(accessJwt) {
+		log.Printf("WARNING: logged in with a full-access session, use an app password so a leaked credential cannot change the account")
+	}
+}
+
+// App passwords look like "abcd-efgh-ijkl-mnop"
+var appPasswordPattern = regexp.MustCompile(`^[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}$`)
+
+// Read the password from the file named by BLUESKY_PASSWORD_FILE, falling back
+// to BLUESKY_PASSWORD. Warns when the password is probably the account password.
+func blueskyPassword() (string, error) {
+	password := os.Getenv("BLUESKY_PASSWORD")
+	if path := os.Getenv("BLUESKY_PASSWORD_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		password = strings.TrimSpace(string(data))
+		if password == "" {
+			return "", fmt.Errorf("password file %s is empty", path)
+		}
+	}
+
+	if password != "" && !appPasswordPattern.MatchString(password) {
+		log.Printf("WARNING: the Bluesky password does not look like an app password, create one under Settings > Privacy and security > App passwords")
+	}
+	return password, nil
+}
+
+// Format a magnitude with one decimal place, rounding halves away from zero
+// so that 5.25 becomes "5.3" instead of the binary-rounded "5.2"
+func formatMag(m float64) string {
+	return strconv.FormatFloat(math.Round(m*10)/10, 'f', 1, 64)
+}
+
+// Check if a stored magnitude is the magnitude of an earthquake. Values stored
+// before formatMag rounded halves away from zero were formatted with "%.1f",
+// so both spellings of the magnitude count as unchanged.
+func sameMag(stored string, mag float64) bool {
+	return stored == formatMag(mag) || stored == fmt.Sprintf("%.1f", mag)
 }
 
 func earthquakeTypeByMagnitude(mag float64) string {
@@ -1,10 +1,39 @@
 package main
 
 import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/cockroachdb/pebble"
 )
 
+// Open an empty database that is closed when the test ends
+func openTestDB(t *testing.T) *pebble.DB {
+	t.Helper()
+	db, err := pebble.Open(filepath.Join(t.TempDir(), "quake-db"), &pebble.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Capture the posts instead of sending them for the rest of the test
+func stubSendPost(t *testing.T) *[]string {
+	t.Helper()
+	var posts []string
+	sendPost = func(text, earthquakeType, fullURL, shortURL string) error {
+		posts = append(posts, text)
+		return nil
+	}
+	t.Cleanup(func() { sendPost = postToBluesky })
+	return &posts
+}
+
 func TestParseEarthquakesCSVHandlesFractionalSecondsAndShortRows(t *testing.T) {
 	csv := `time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status
 2026-06-08T10:11:12.345Z,1,2,3,5.6,mww,,,,,us,us123,,10 km S of Test,earthquake,reviewed
@@ -26,3 +55,181 @@ func TestParseEarthquakesCSVHandlesFractionalSecondsAndShortRows(t *testing.T) {
 		t.Fatal("expected significant flag to be preserved")
 	}
 }
+
+func TestOpenDBMovesCorruptDatabaseAside(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "quake-db")
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	openPebble = func(dirname string, opts *pebble.Options) (*pebble.DB, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("open %s: %w", dirname, pebble.ErrCorruption)
+		}
+		return pebble.Open(dirname, opts)
+	}
+	t.Cleanup(func() { openPebble = pebble.Open })
+
+	if _, _, err := openDB(path, false); !pebble.IsCorruptionError(err) {
+		t.Fatalf("expected corruption error without repair, got %v", err)
+	}
+
+	calls = 0
+	db, lost, err := openDB(path, true)
+	if err != nil {
+		t.Fatalf("openDB with repair returned error: %v", err)
+	}
+	defer db.Close()
+
+	moved, err := filepath.Glob(path + ".corrupt-*")
+	if err != nil || len(moved) != 1 {
+		t.Fatalf("expected corrupt database to be moved aside, got %v (err %v)", moved, err)
+	}
+	// The directory holds no readable database, so nothing is recovered
+	if !lost {
+		t.Fatal("expected the entries to be reported as lost")
+	}
+
+	markAlerted(db, []Earthquake{{ID: "us1", Mag: 6.1}})
+	value, closer, err := db.Get([]byte("us1"))
+	if err != nil || string(value) != "6.1" {
+		t.Fatalf("expected the feed's earthquake to be marked as alerted, got %q (err %v)", value, err)
+	}
+	closer.Close()
+}
+
+func TestOpenDBRecoversEntriesOfCorruptDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quake-db")
+	old, err := pebble.Open(path, &pebble.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"us1", "us2"} {
+		if err := old.Set([]byte(id), []byte("6.0"), pebble.Sync); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := old.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	openPebble = func(dirname string, opts *pebble.Options) (*pebble.DB, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("open %s: %w", dirname, pebble.ErrCorruption)
+		}
+		return pebble.Open(dirname, opts)
+	}
+	t.Cleanup(func() { openPebble = pebble.Open })
+
+	db, lost, err := openDB(path, true)
+	if err != nil || lost {
+		t.Fatalf("expected a complete recovery, got lost %v (err %v)", lost, err)
+	}
+	defer db.Close()
+	for _, id := range []string{"us1", "us2"} {
+		value, closer, err := db.Get([]byte(id))
+		if err != nil || string(value) != "6.0" {
+			t.Fatalf("expected %s to be recovered, got %q (err %v)", id, value, err)
+		}
+		closer.Close()
+	}
+}
+
+func TestFormatMagRoundsHalvesUp(t *testing.T) {
+	tests := map[float64]string{
+		5.25:      "5.3",
+		6.75:      "6.8",
+		4.95:      "5.0",
+		5.2999999: "5.3",
+		5.24:      "5.2",
+		7:         "7.0",
+	}
+	for mag, want := range tests {
+		if got := formatMag(mag); got != want {
+			t.Errorf("formatMag(%v) = %q, want %q", mag, got, want)
+		}
+	}
+}
+
+func TestSameMagAcceptsLegacyRounding(t *testing.T) {
+	// "5.2" is how "%.1f" stored 5.25 before formatMag rounded it to "5.3"
+	if !sameMag("5.2", 5.25) || !sameMag("5.3", 5.25) {
+		t.Fatal("expected both roundings of 5.25 to match")
+	}
+	if sameMag("5.2", 5.3) || sameMag("5.3", 5.2) {
+		t.Fatal("expected a revised magnitude not to match")
+	}
+}
+
+func TestBlueskyPasswordPrefersFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("  wxyz-1234-abcd-5678\n"), 0o600); err != nil {
+		t.Fatalf("failed to write password file: %v", err)
+	}
+	t.Setenv("BLUESKY_PASSWORD", "from-env")
+	t.Setenv("BLUESKY_PASSWORD_FILE", path)
+
+	password, err := blueskyPassword()
+	if err != nil || password != "wxyz-1234-abcd-5678" {
+		t.Fatalf("expected the password from the file, got %q (err %v)", password, err)
+	}
+
+	t.Setenv("BLUESKY_PASSWORD_FILE", "")
+	if password, _ := blueskyPassword(); password != "from-env" {
+		t.Fatalf("expected the environment fallback, got %q", password)
+	}
+}
+
+func TestEventPageURLAndFacets(t *testing.T) {
+	fullURL, shortURL := eventPageURL("us7000abcd")
+	if fullURL != "https://earthquake.usgs.gov/earthquakes/eventpage/us7000abcd" || shortURL != "earthquake.usgs.gov/us7000abcd" {
+		t.Fatalf("unexpected URLs %q, %q", fullURL, shortURL)
+	}
+	if fullURL, _ := eventPageURL("ak/2026 #1"); fullURL != "https://earthquake.usgs.gov/earthquakes/eventpage/ak%2F2026%20%231" {
+		t.Fatalf("expected the ID to be escaped, got %q", fullURL)
+	}
+
+	// "±" takes two bytes, so byte and character offsets differ
+	text := "6.1 magnitude strong #earthquake\nDepth 10.0 km (±1.8 km)\n\nearthquake.usgs.gov/us7000abcd"
+	facets := buildFacets(text, "earthquake", fullURL, shortURL)
+	if len(facets) != 2 {
+		t.Fatalf("expected a link and a tag facet, got %d", len(facets))
+	}
+	link := facets[0]
+	if link.Features[0].RichtextFacet_Link.Uri != fullURL {
+		t.Fatalf("unexpected link target %q", link.Features[0].RichtextFacet_Link.Uri)
+	}
+	if got := text[link.Index.ByteStart:link.Index.ByteEnd]; got != shortURL {
+		t.Fatalf("expected the link to cover %q, got %q", shortURL, got)
+	}
+	if link.Index.ByteEnd != int64(len(text)) {
+		t.Fatalf("expected the link to end at byte %d, got %d", len(text), link.Index.ByteEnd)
+	}
+	if tag := facets[1]; text[tag.Index.ByteStart:tag.Index.ByteEnd] != "#earthquake" {
+		t.Fatalf("unexpected tag range %d-%d", tag.Index.ByteStart, tag.Index.ByteEnd)
+	}
+}
+
+func TestIsFullAccessSession(t *testing.T) {
+	token := func(claims string) string {
+		return "eyJhbGciOiJFUzI1NksifQ." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2ln"
+	}
+	tests := map[string]bool{
+		token(`{"scope":"com.atproto.access","sub":"did:plc:bot"}`):            true,
+		token(`{"scope":"com.atproto.appPass","sub":"did:plc:bot"}`):           false,
+		token(`{"scope":"com.atproto.appPassPrivileged","sub":"did:plc:bot"}`): false,
+		token(`{"sub":"did:plc:bot"}`):                                         false,
+		"access-1":                                                             false,
+		"a.!!!.c":                                                              false,
+	}
+	for jwt, want := range tests {
+		if got := isFullAccessSession(jwt); got != want {
+			t.Errorf("isFullAccessSession(%q) = %v, want %v", jwt, got, want)
+		}
+	}
+}
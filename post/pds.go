@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/cockroachdb/pebble"
+)
+
+// Directory serving the DID documents of did:plc identities
+var plcDirectory = "https://plc.directory"
+
+// Keys of the discovered PDS endpoint of each account, "pds:<identifier>"
+const pdsPrefix = "pds:"
+
+// How long a discovered PDS endpoint is used before it is resolved again, so a
+// migrated account is picked up within a day
+const pdsCacheTTL = 24 * time.Hour
+
+// pdsCache is the database that caches the discovered PDS endpoints, set in
+// main. Without it the PDS is resolved for every post.
+var pdsCache *pebble.DB
+
+// pdsEndpoint is a PDS service endpoint and when it was discovered
+type pdsEndpoint struct {
+	URL        string    `json:"url"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+}
+
+// Find the PDS that hosts the account. The handle is resolved to a DID
+// through host and the PDS is read from the DID document. When discovery
+// fails, or the identifier is an email address, host itself is used.
+func pdsHost(ctx context.Context, host, identifier string) string {
+	if strings.Contains(identifier, "@") {
+		return host
+	}
+
+	key := []byte(pdsPrefix + identifier)
+	if pdsCache != nil {
+		value, closer, err := pdsCache.Get(key)
+		if err == nil {
+			var cached pdsEndpoint
+			err = json.Unmarshal(value, &cached)
+			closer.Close()
+			if err == nil && now().Sub(cached.ResolvedAt) < pdsCacheTTL {
+				return cached.URL
+			}
+		}
+		if err != nil && !errors.Is(err, pebble.ErrNotFound) {
+			log.Printf("Failed to load the cached PDS endpoint: %v", err)
+		}
+	}
+
+	endpoint, err := resolvePDS(ctx, host, identifier)
+	if err != nil {
+		log.Printf("Failed to discover the PDS of %s, using %s: %v", identifier, host, err)
+		return host
+	}
+
+	if pdsCache != nil {
+		data, _ := json.Marshal(pdsEndpoint{URL: endpoint, ResolvedAt: now()})
+		if err := pdsCache.Set(key, data, &pebble.WriteOptions{}); err != nil {
+			log.Printf("Failed to cache the PDS endpoint: %v", err)
+		}
+	}
+	return endpoint
+}
+
+// Resolve a handle or DID to the service endpoint of its PDS
+func resolvePDS(ctx context.Context, host, identifier string) (string, error) {
+	did := identifier
+	if !strings.HasPrefix(identifier, "did:") {
+		out, err := atproto.IdentityResolveHandle(ctx, &xrpc.Client{Host: host}, identifier)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve handle: %w", err)
+		}
+		did = out.Did
+	}
+
+	docURL, err := didDocumentURL(did)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download DID document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code for DID document: %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Service []struct {
+			ID              string `json:"id"`
+			Type            string `json:"type"`
+			ServiceEndpoint string `json:"serviceEndpoint"`
+		} `json:"service"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to decode DID document: %w", err)
+	}
+
+	for _, service := range doc.Service {
+		if strings.HasSuffix(service.ID, "#atproto_pds") && service.Type == "AtprotoPersonalDataServer" && service.ServiceEndpoint != "" {
+			return strings.TrimSuffix(service.ServiceEndpoint, "/"), nil
+		}
+	}
+	return "", fmt.Errorf("DID document of %s names no PDS", did)
+}
+
+// URL of the DID document of a did:plc or did:web identity
+func didDocumentURL(did string) (string, error) {
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		return plcDirectory + "/" + did, nil
+	case strings.HasPrefix(did, "did:web:"):
+		return "https://" + strings.TrimPrefix(did, "did:web:") + "/.well-known/did.json", nil
+	default:
+		return "", fmt.Errorf("unsupported DID %q", did)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestPostToBlueskyUsesTheAccountPDS(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string][]string{}
+	record := func(server string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests[server] = append(requests[server], r.URL.Path)
+			mu.Unlock()
+			switch r.URL.Path {
+			case "/xrpc/com.atproto.identity.resolveHandle":
+				json.NewEncoder(w).Encode(map[string]string{"did": "did:plc:bot"})
+			case "/xrpc/com.atproto.server.createSession":
+				json.NewEncoder(w).Encode(map[string]string{
+					"accessJwt": "access", "refreshJwt": "refresh", "did": "did:plc:bot", "handle": "bot.example.com",
+				})
+			case "/xrpc/com.atproto.repo.createRecord":
+				json.NewEncoder(w).Encode(map[string]string{"uri": "at://did:plc:bot/app.bsky.feed.post/1", "cid": "cid"})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+	}
+	pds := httptest.NewServer(record("pds"))
+	t.Cleanup(pds.Close)
+	entryway := httptest.NewServer(record("entryway"))
+	t.Cleanup(entryway.Close)
+	plc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests["plc"] = append(requests["plc"], r.URL.Path)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"service": []map[string]string{
+			{"id": "#atproto_pds", "type": "AtprotoPersonalDataServer", "serviceEndpoint": pds.URL + "/"},
+		}})
+	}))
+	t.Cleanup(plc.Close)
+
+	plcDirectory = plc.URL
+	t.Cleanup(func() { plcDirectory = "https://plc.directory" })
+	db := openTestDB(t)
+	pdsCache = db
+	t.Cleanup(func() { pdsCache = nil })
+	t.Setenv("BLUESKY_HOST", entryway.URL)
+	t.Setenv("BLUESKY_IDENTIFIER", "bot.example.com")
+	t.Setenv("BLUESKY_PASSWORD", "abcd-efgh-ijkl-mnop")
+
+	for range 2 {
+		if err := postToBluesky("M6.1 strong #earthquake", "earthquake", "", ""); err != nil {
+			t.Fatalf("postToBluesky returned error: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests["entryway"]) != 1 || requests["entryway"][0] != "/xrpc/com.atproto.identity.resolveHandle" {
+		t.Fatalf("expected only the handle to be resolved at BLUESKY_HOST, got %v", requests["entryway"])
+	}
+	if len(requests["plc"]) != 1 || requests["plc"][0] != "/did:plc:bot" {
+		t.Fatalf("expected one DID document download, the second post to use the cache, got %v", requests["plc"])
+	}
+	if len(requests["pds"]) != 4 {
+		t.Fatalf("expected the logins and posts to go to the PDS, got %v", requests["pds"])
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+)
+
+// errSafeMode is returned instead of posting while SAFE_MODE is set
+var errSafeMode = errors.New("SAFE_MODE is set, nothing is written to Bluesky")
+
+// SAFE_MODE blocks every post, whatever else is configured. It is checked in
+// publishPost before the hooks and before logging in.
+func safeMode() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("SAFE_MODE"))
+	return enabled
+}
+
+// Log a banner that is hard to miss in the logs of a run in safe mode
+func logSafeModeBanner() {
+	log.Println("************************************************************")
+	log.Println("* SAFE_MODE is set: nothing is posted to Bluesky this run  *")
+	log.Println("************************************************************")
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSafeModeNeverCallsThePoster(t *testing.T) {
+	db := openTestDB(t)
+
+	posts := stubSendPost(t)
+	hooked := 0
+	registerPrePostHook(func(text string) error {
+		hooked++
+		return nil
+	})
+	t.Cleanup(func() { prePostHooks = nil })
+	t.Setenv("SAFE_MODE", "true")
+
+	details := newDetailFetcher()
+	details.cache["us1"] = eventDetail{}
+	q := Earthquake{ID: "us1", Time: "2026-06-08T10:00:00.000Z", Mag: 6.1, Place: "Fiji", Status: "reviewed", Type: "earthquake"}
+	if err := postEarthquake(q, "", db, []byte("us1"), details); !errors.Is(err, errSafeMode) {
+		t.Fatalf("expected the alert to be blocked, got %v", err)
+	}
+	if err := publishPost("summary", "earthquake", "", ""); !errors.Is(err, errSafeMode) {
+		t.Fatalf("expected the post to be blocked, got %v", err)
+	}
+	if len(*posts) != 0 || hooked != 0 {
+		t.Fatalf("expected no poster or hook calls in safe mode, got %d and %d", len(*posts), hooked)
+	}
+	if _, closer, err := db.Get([]byte("us1")); err == nil {
+		closer.Close()
+		t.Fatal("expected a blocked alert not to be stored")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// The magnitude increase from MAG_UPGRADE_DELTA, e.g. "0.5", from which an
+// alerted earthquake gets an upgrade alert. Unset or invalid disables it.
+func magnitudeUpgradeDelta() float64 {
+	delta, err := strconv.ParseFloat(os.Getenv("MAG_UPGRADE_DELTA"), 64)
+	if err != nil || delta <= 0 {
+		return 0
+	}
+	return delta
+}
+
+// Handle an earthquake that is already stored when upgrade alerts are on,
+// reporting whether it was stored. The stored value is the magnitude of the
+// last alert, so a series of small revisions still adds up to an upgrade. An
+// upgrade by delta or more is alerted and stored, smaller revisions and
+// downgrades are ignored. An upgrade held by the cooldown of its region is
+// not stored either, so it is alerted once the cooldown is over.
+func checkUpgrade(q Earthquake, db *pebble.DB, key []byte, details *detailFetcher, cooldown *cooldowns, delta float64) bool {
+	value, closer, err := db.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Database error for ID %s: %v", q.ID, err)
+		return true
+	}
+	// A suppressed earthquake was never alerted, so it cannot be upgraded
+	if isSuppressed(value) || sameMag(string(value), q.Mag) {
+		closer.Close()
+		return true
+	}
+	stored, parseErr := strconv.ParseFloat(string(value), 64)
+	closer.Close()
+	if parseErr != nil {
+		log.Printf("Invalid stored magnitude %q for ID %s", value, q.ID)
+		return true
+	}
+
+	// Compare the shown tenths so the alert matches the magnitudes in it
+	shown, _ := strconv.ParseFloat(formatMag(q.Mag), 64)
+	if shown-stored < delta-1e-9 {
+		return true
+	}
+	held, err := cooldown.holdsUpgrade(q)
+	if err != nil {
+		log.Printf("Failed to check the cooldown for earthquake ID %s: %v", q.ID, err)
+	}
+	if held {
+		return true
+	}
+	if err := postEarthquake(q, upgradePrefix(stored, q.Mag), db, key, details); err != nil {
+		log.Printf("Failed to post upgraded earthquake ID %s: %v", q.ID, err)
+		return true
+	}
+	if err := cooldown.start(q); err != nil {
+		log.Printf("Failed to start the cooldown for earthquake ID %s: %v", q.ID, err)
+	}
+	return true
+}
+
+// First line of an upgrade alert, e.g. "Upgraded to M7.1 (was M6.0)"
+func upgradePrefix(stored, current float64) string {
+	return fmt.Sprintf("Upgraded to M%s (was M%s)\n", formatMag(current), formatMag(stored))
+}
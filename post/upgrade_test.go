@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMagnitudeUpgradeAlert(t *testing.T) {
+	db := openTestDB(t)
+	posts := stubSendPost(t)
+	t.Setenv("MAG_UPGRADE_DELTA", "0.5")
+
+	details := newDetailFetcher()
+	details.cache["us1"] = eventDetail{}
+	cooldown := &cooldowns{db: db}
+	quake := func(mag float64) []Earthquake {
+		return []Earthquake{{ID: "us1", Time: "2026-06-08T10:00:00.000Z", Mag: mag, Place: "Near the coast of Peru", Status: "reviewed", Type: "earthquake"}}
+	}
+
+	processEarthquakes(db, quake(6.0), nil, details, cooldown)
+	if len(*posts) != 1 || strings.HasPrefix((*posts)[0], "Upgraded") {
+		t.Fatalf("expected the first alert, got %q", *posts)
+	}
+
+	// Minor revisions are not alerted, and do not move the baseline
+	processEarthquakes(db, quake(6.2), nil, details, cooldown)
+	processEarthquakes(db, quake(6.4), nil, details, cooldown)
+	processEarthquakes(db, quake(5.8), nil, details, cooldown)
+	if len(*posts) != 1 {
+		t.Fatalf("expected no alert for minor revisions, got %q", (*posts)[1:])
+	}
+
+	processEarthquakes(db, quake(7.1), nil, details, cooldown)
+	if len(*posts) != 2 || !strings.HasPrefix((*posts)[1], "Upgraded to M7.1 (was M6.0)\n7.1 magnitude") {
+		t.Fatalf("expected an upgrade alert, got %q", *posts)
+	}
+	value, closer, err := db.Get([]byte("us1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	if string(value) != "7.1" {
+		t.Fatalf("expected the upgraded magnitude to be stored, got %q", value)
+	}
+
+	processEarthquakes(db, quake(7.1), nil, details, cooldown)
+	if len(*posts) != 2 {
+		t.Fatalf("expected the upgrade to be alerted once, got %q", *posts)
+	}
+}
+
+func TestUpgradeRespectsCooldown(t *testing.T) {
+	db := openTestDB(t)
+	posts := stubSendPost(t)
+	clock := time.Date(2026, 6, 8, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+	t.Setenv("MAG_UPGRADE_DELTA", "0.5")
+
+	details := newDetailFetcher()
+	details.cache["us1"], details.cache["us2"] = eventDetail{}, eventDetail{}
+	cooldown := &cooldowns{db: db, period: 6 * time.Hour}
+	quakes := func(mag1, mag2 float64) []Earthquake {
+		return []Earthquake{
+			{ID: "us1", Time: "2026-06-08T11:00:00.000Z", Mag: mag1, Place: "Near Ofunato, Japan", Status: "reviewed", Type: "earthquake"},
+			{ID: "us2", Time: "2026-06-08T11:10:00.000Z", Mag: mag2, Place: "Near Ofunato, Japan", Status: "reviewed", Type: "earthquake"},
+		}
+	}
+
+	// The larger us2 is alerted and starts a new cooldown
+	processEarthquakes(db, quakes(6.0, 6.8), nil, details, cooldown)
+	if len(*posts) != 2 {
+		t.Fatalf("expected both alerts, got %q", *posts)
+	}
+
+	// The upgrade of us1 is held, the upgrade of the mainshock us2 is not
+	processEarthquakes(db, quakes(6.6, 7.3), nil, details, cooldown)
+	if len(*posts) != 3 || !strings.HasPrefix((*posts)[2], "Upgraded to M7.3 (was M6.8)") {
+		t.Fatalf("expected only the upgrade of the mainshock, got %q", (*posts)[2:])
+	}
+
+	clock = clock.Add(6 * time.Hour)
+	processEarthquakes(db, quakes(6.6, 7.3), nil, details, cooldown)
+	if len(*posts) != 4 || !strings.HasPrefix((*posts)[3], "Upgraded to M6.6 (was M6.0)") {
+		t.Fatalf("expected the held upgrade after the cooldown, got %q", (*posts)[3:])
+	}
+}
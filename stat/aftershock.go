@@ -0,0 +1,111 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Smallest magnitude whose aftershocks are looked for by default
+const defaultMainshockMag = 5.0
+
+// Declustering is the share of a week's located events that are likely
+// aftershocks of a larger event of the same week
+type Declustering struct {
+	Mainshocks  int     `json:"mainshocks"`
+	Aftershocks int     `json:"aftershocks"`
+	Events      int     `json:"events"`
+	Percent     float64 `json:"percent"`
+	// Magnitude from which events count as mainshocks
+	MinMagnitude float64 `json:"minMagnitude"`
+}
+
+// Space and time window of Gardner and Knopoff (1974) around a mainshock of
+// magnitude mag: events inside it are counted as its aftershocks
+func aftershockWindow(mag float64) (distanceKm float64, duration time.Duration) {
+	distanceKm = math.Pow(10, 0.1238*mag+0.983)
+	days := math.Pow(10, 0.5409*mag-0.547)
+	if mag >= 6.5 {
+		days = math.Pow(10, 0.032*mag+2.7389)
+	}
+	return distanceKm, time.Duration(days * float64(24*time.Hour))
+}
+
+// Label likely aftershocks with a simple window declustering. Located events
+// are visited from the largest down; an event of at least minMag that is not
+// an aftershock itself is a mainshock, and the smaller events after it within
+// its Gardner-Knopoff window are its aftershocks. Only the week's events are
+// seen, so aftershocks of a mainshock in an earlier week count as independent,
+// and the long windows of large mainshocks make the share an upper estimate
+// for busy regions. Nil without a located event of at least minMag.
+func decluster(events []Earthquake, minMag float64) *Declustering {
+	located := make([]Earthquake, 0, len(events))
+	for _, eq := range events {
+		if eq.HasLocation() && !eq.Time.IsZero() {
+			located = append(located, eq)
+		}
+	}
+	slices.SortStableFunc(located, func(a, b Earthquake) int {
+		return cmp.Or(cmp.Compare(b.Magnitude, a.Magnitude), a.Time.Compare(b.Time))
+	})
+
+	result := Declustering{Events: len(located), MinMagnitude: minMag}
+	aftershock := make([]bool, len(located))
+	for i, main := range located {
+		if main.Magnitude < minMag {
+			break
+		}
+		if aftershock[i] {
+			continue
+		}
+		result.Mainshocks++
+		distance, duration := aftershockWindow(main.Magnitude)
+		for j := i + 1; j < len(located); j++ {
+			eq := located[j]
+			if aftershock[j] || eq.Magnitude >= main.Magnitude || !eq.Time.After(main.Time) || eq.Time.Sub(main.Time) > duration {
+				continue
+			}
+			if haversineKm(main.Latitude, main.Longitude, eq.Latitude, eq.Longitude) <= distance {
+				aftershock[j] = true
+				result.Aftershocks++
+			}
+		}
+	}
+
+	if result.Mainshocks == 0 {
+		return nil
+	}
+	result.Percent = math.Round(float64(result.Aftershocks)*1000/float64(result.Events)) / 10
+	return &result
+}
+
+// Declustering of the week's events with AFTERSHOCK_RATIO=true, nil otherwise.
+// AFTERSHOCK_MIN_MAG sets the smallest mainshock magnitude.
+func weeklyDeclustering(events []Earthquake) *Declustering {
+	if !envBool("AFTERSHOCK_RATIO") {
+		return nil
+	}
+	minMag := defaultMainshockMag
+	if value, err := strconv.ParseFloat(os.Getenv("AFTERSHOCK_MIN_MAG"), 64); err == nil {
+		minMag = value
+	}
+	return decluster(events, minMag)
+}
+
+// Detailed report line such as "Likely aftershocks: 23.5% (412 of 1753 events,
+// 2 mainshocks M5.0+)", empty without a declustering
+func aftershockLine(d *Declustering) string {
+	if d == nil {
+		return ""
+	}
+	noun := "mainshocks"
+	if d.Mainshocks == 1 {
+		noun = "mainshock"
+	}
+	return fmt.Sprintf("Likely aftershocks: %.1f%% (%s of %s events, %d %s M%s+)",
+		d.Percent, formatCount(d.Aftershocks), formatCount(d.Events), d.Mainshocks, noun, formatMag(d.MinMagnitude))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeclusterSeparatesAftershocksFromBackground(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := []Earthquake{{ID: "main", Magnitude: 6.8, Time: start.Add(time.Hour), Latitude: 38.3, Longitude: 142.4}}
+
+	// 20 smaller events within 30 km in the two days after the mainshock
+	for i := range 20 {
+		events = append(events, Earthquake{Magnitude: 4.0 + float64(i%10)/10, Time: start.Add(time.Duration(2+i*2) * time.Hour),
+			Latitude: 38.3 + float64(i%5)*0.05, Longitude: 142.4 - float64(i%4)*0.05})
+	}
+	// A foreshock in the same place is not an aftershock
+	events = append(events, Earthquake{Magnitude: 5.1, Time: start, Latitude: 38.3, Longitude: 142.4})
+	// 29 background events far away
+	for i := range 29 {
+		events = append(events, Earthquake{Magnitude: 2.0 + float64(i%20)/10, Time: start.Add(time.Duration(i*5) * time.Hour),
+			Latitude: -30 + float64(i), Longitude: -70 - float64(i)})
+	}
+	// Events without a location are left out
+	events = append(events, Earthquake{Magnitude: 3.0, Time: start.Add(3 * time.Hour), Latitude: math.NaN(), Longitude: math.NaN()})
+
+	d := decluster(events, 5.0)
+	if d == nil || d.Aftershocks != 20 || d.Events != 51 || d.Mainshocks != 2 || d.Percent != 39.2 {
+		t.Fatalf("unexpected declustering %+v", d)
+	}
+	line := aftershockLine(d)
+	if line != "Likely aftershocks: 39.2% (20 of 51 events, 2 mainshocks M5.0+)" {
+		t.Fatalf("unexpected line %q", line)
+	}
+
+	// Without a large event there is nothing to decluster
+	if d := decluster(events[22:], 5.0); d != nil {
+		t.Fatalf("expected no declustering for background only, got %+v", d)
+	}
+}
+
+func TestDetailedReportShowsAftershockShare(t *testing.T) {
+	t.Setenv("AFTERSHOCK_RATIO", "true")
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := []Earthquake{
+		{Magnitude: 6.0, Time: start, Latitude: 10, Longitude: 10},
+		{Magnitude: 4.0, Time: start.Add(time.Hour), Latitude: 10.1, Longitude: 10},
+	}
+	report := newReport("2026-W23", WeekStats{Counts: [7]int{0, 0, 1, 0, 1, 0, 0}, StartDate: start})
+	addEventSections(&report, WeekStats{Events: events})
+	if text := renderDetailed(report); !strings.Contains(text, "Likely aftershocks: 50.0% (1 of 2 events, 1 mainshock M5.0+)") {
+		t.Fatalf("expected the aftershock share in the detailed report:\n%s", text)
+	}
+}
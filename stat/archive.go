@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header of the CSV archive: week, start, end, one column per magnitude
+// category ("micro", "minor", ...), total and largest magnitude
+func archiveHeader() []string {
+	header := []string{"week", "start", "end"}
+	for _, category := range categories {
+		header = append(header, strings.ToLower(strings.Fields(category)[0]))
+	}
+	return append(header, "total", "max")
+}
+
+// Row of a report in the CSV archive. A category left out of the report,
+// micro with EXCLUDE_MICRO, is an empty cell rather than a zero.
+func archiveRow(report Report) []string {
+	row := []string{report.WeekKey, report.StartDate.UTC().Format(time.RFC3339), report.EndDate.UTC().Format(time.RFC3339)}
+	for _, category := range categories {
+		cell := ""
+		for _, count := range report.Categories {
+			if count.Label == category {
+				cell = strconv.Itoa(count.Count)
+			}
+		}
+		row = append(row, cell)
+	}
+	largest := ""
+	if report.Largest != nil {
+		largest = formatMag(report.Largest.Magnitude)
+	}
+	return append(row, strconv.Itoa(report.Total), largest)
+}
+
+// Append the report as a row to the CSV archive at path. A new or empty file
+// gets the header first; a week that is already archived is not appended
+// again, so a retried run does not duplicate rows.
+func appendArchiveRow(path string, report Report) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	empty := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		empty = false
+		if record[0] == report.WeekKey {
+			return nil
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to seek archive: %w", err)
+	}
+	writer := csv.NewWriter(file)
+	if empty {
+		writer.Write(archiveHeader())
+	}
+	writer.Write(archiveRow(report))
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// archiveOutput appends the weekly totals to a CSV archive
+type archiveOutput struct {
+	path string
+}
+
+func (o archiveOutput) name() string { return "archive" }
+
+func (o archiveOutput) publish(ctx context.Context, reportData ReportData) error {
+	return appendArchiveRow(o.path, reportData.Report)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendArchiveRow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.csv")
+	week := func(weekKey string, start time.Time, counts [7]int, largest float64) Report {
+		return newReport(weekKey, WeekStats{Counts: counts, MagnitudeSum: 10, StartDate: start,
+			EndDate: start.AddDate(0, 0, 7).Add(-time.Second), Largest: Earthquake{Magnitude: largest}})
+	}
+	w22 := week("2026-W22", time.Date(2026, 5, 25, 0, 0, 0, 0, time.UTC), [7]int{10, 20, 3, 1, 0, 0, 0}, 5.4)
+	w23 := week("2026-W23", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), [7]int{12, 18, 4, 0, 1, 0, 0}, 6.1)
+
+	for _, report := range []Report{w22, w23, w22} {
+		if err := appendArchiveRow(path, report); err != nil {
+			t.Fatalf("appendArchiveRow(%s) failed: %v", report.WeekKey, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "week,start,end,micro,minor,light,moderate,strong,major,great,total,max\n" +
+		"2026-W22,2026-05-25T00:00:00Z,2026-05-31T23:59:59Z,10,20,3,1,0,0,0,34,5.4\n" +
+		"2026-W23,2026-06-01T00:00:00Z,2026-06-07T23:59:59Z,12,18,4,0,1,0,0,35,6.1\n"
+	if string(data) != want {
+		t.Fatalf("archive is\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	fdsnQueryURL = "https://earthquake.usgs.gov/fdsnws/event/1/query"
+	// Largest page the FDSN event service returns
+	fdsnPageLimit = 20000
+)
+
+// backfiller downloads past events from the FDSN event API. Results are
+// fetched in pages of pageLimit events and requests are spaced at least
+// interval apart. Events are filtered like the live feed, including the
+// per-network thresholds.
+type backfiller struct {
+	client     *http.Client
+	baseURL    string
+	pageLimit  int
+	interval   time.Duration
+	last       time.Time
+	thresholds map[string]float64
+}
+
+func newBackfiller(thresholds map[string]float64) *backfiller {
+	return &backfiller{
+		client:     newHTTPClient(2 * time.Minute),
+		baseURL:    fdsnQueryURL,
+		pageLimit:  fdsnPageLimit,
+		interval:   time.Second,
+		thresholds: thresholds,
+	}
+}
+
+// Store the stats of every complete week between start and end. Weeks that
+// already have stats are kept as they are. Backfilled weeks are marked as
+// historical and are never posted.
+func (b *backfiller) backfill(store *Store, start, end time.Time) (int, error) {
+	weekStart, _, _, _ := getWeekBoundaries(start)
+	if weekStart.Before(start) {
+		weekStart = nextWeekStart(start)
+	}
+
+	stored := 0
+	for ; !nextWeekStart(weekStart).After(end); weekStart = nextWeekStart(weekStart) {
+		_, weekEnd, year, weekNum := getWeekBoundaries(weekStart)
+		weekKey := formatWeekKey(year, weekNum)
+		if _, ok := store.LoadWeekStats(weekKey); ok {
+			continue
+		}
+
+		earthquakes, err := b.fetchRange(weekStart, nextWeekStart(weekStart))
+		if err != nil {
+			return stored, fmt.Errorf("failed to fetch week %s: %w", weekKey, err)
+		}
+		if envBool("REVIEWED_ONLY") {
+			earthquakes = filterReviewed(earthquakes)
+		}
+		earthquakes = filterByNetwork(earthquakes, b.thresholds)
+
+		stats, ok := groupByWeek(earthquakes)[weekKey]
+		if !ok {
+			stats = WeekStats{StartDate: weekStart, EndDate: weekEnd, Year: year, WeekNum: weekNum}
+		}
+		stats.Historical = true
+		store.StoreWeekStats(weekKey, stats)
+		stored++
+	}
+	return stored, nil
+}
+
+// Fetch all events in [start, end), page by page
+func (b *backfiller) fetchRange(start, end time.Time) ([]Earthquake, error) {
+	var earthquakes []Earthquake
+	for offset := 1; ; offset += b.pageLimit {
+		page, err := b.fetchPage(start, end, offset)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			return earthquakes, nil
+		}
+		earthquakes = append(earthquakes, page...)
+	}
+}
+
+func (b *backfiller) fetchPage(start, end time.Time, offset int) ([]Earthquake, error) {
+	if wait := b.interval - time.Since(b.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	b.last = time.Now()
+
+	query := url.Values{
+		"format":    {"csv"},
+		"starttime": {start.UTC().Format("2006-01-02T15:04:05")},
+		"endtime":   {end.UTC().Format("2006-01-02T15:04:05")},
+		"orderby":   {"time-asc"},
+		"limit":     {strconv.Itoa(b.pageLimit)},
+		"offset":    {strconv.Itoa(offset)},
+	}
+	resp, err := b.client.Get(b.baseURL + "?" + query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to download CSV: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// FDSN answers 204 when no events are left
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("unexpected content type %q, the service may be down", contentType)
+	}
+
+	return parseCSV(resp.Body)
+}
+
+// Parse the -backfill range. The end date is optional and defaults to today.
+func parseBackfillRange(startStr, endStr string) (time.Time, time.Time, error) {
+	start, err := time.Parse(time.DateOnly, startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date %q: %w", startStr, err)
+	}
+	end := now().UTC()
+	if endStr != "" {
+		if end, err = time.Parse(time.DateOnly, endStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %q: %w", endStr, err)
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start date %s is not before end date %s", startStr, end.Format(time.DateOnly))
+	}
+	return start, end, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBackfillPaginatesAndStoresHistoricalWeeks(t *testing.T) {
+	store := openTestStore(t)
+
+	header := "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n"
+	pages := map[string]string{
+		"1": header +
+			"2026-06-01T10:00:00.000Z,1,2,10,2.5,ml,,,,,us,a,,Place A,earthquake,reviewed\n" +
+			"2026-06-03T10:00:00.000Z,1,2,10,4.5,mb,,,,,us,b,,Place B,earthquake,reviewed\n",
+		"3": header +
+			"2026-06-06T10:00:00.000Z,1,2,400,6.1,mww,,,,,us,c,,Place C,earthquake,reviewed\n",
+	}
+	var offsets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("format") != "csv" || query.Get("limit") != "2" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		if query.Get("starttime") != "2026-06-01T00:00:00" || query.Get("endtime") != "2026-06-08T00:00:00" {
+			t.Errorf("unexpected time range %s", r.URL.RawQuery)
+		}
+		offsets = append(offsets, query.Get("offset"))
+		page, ok := pages[query.Get("offset")]
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(server.Close)
+
+	b := &backfiller{client: server.Client(), baseURL: server.URL, pageLimit: 2}
+	stored, err := b.backfill(store, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 6, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("backfill returned error: %v", err)
+	}
+	if stored != 1 {
+		t.Fatalf("expected only the complete week to be stored, got %d", stored)
+	}
+	if fmt.Sprint(offsets) != "[1 3 5]" {
+		t.Fatalf("expected pages at offsets 1, 3 and 5, got %v", offsets)
+	}
+
+	stats, ok := store.LoadWeekStats("2026-W23")
+	if !ok {
+		t.Fatal("expected stats for 2026-W23")
+	}
+	if !stats.Historical || stats.Total() != 3 || stats.Largest.ID != "c" {
+		t.Fatalf("unexpected stored stats %+v", stats)
+	}
+	if store.WasWeekPosted("2026-W23") {
+		t.Fatal("expected backfilled week not to be marked as posted")
+	}
+}
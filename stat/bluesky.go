@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+type BlueskyConfig struct {
+	Identifier string
+	Password   string
+}
+
+// Post the earthquake report to Bluesky. Additional texts are posted as
+// replies, each one answering the previous post.
+func postToBluesky(ctx context.Context, store *Store, texts ...string) error {
+	_, err := postThread(ctx, store, "", texts)
+	return err
+}
+
+// Post texts as a thread. With a thread key, every successful post is recorded
+// in the store, so after a failure the next call with the same key continues
+// the thread after the last post instead of posting it again from the start.
+// Returns the first post of the thread.
+func postThread(ctx context.Context, store *Store, threadKey string, texts []string) (*atproto.RepoStrongRef, error) {
+	var posted []*atproto.RepoStrongRef
+	if threadKey != "" {
+		var err error
+		if posted, err = store.LoadThreadProgress(threadKey); err != nil {
+			return nil, fmt.Errorf("failed to load thread progress: %w", err)
+		}
+		if len(posted) > 0 {
+			fmt.Printf("Resuming thread %s after post %d of %d\n", threadKey, len(posted), len(texts))
+		}
+	}
+
+	client, err := login(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(posted); i < len(texts); i++ {
+		post := buildPost(texts[i])
+		if len(posted) > 0 {
+			post.Reply = &bsky.FeedPost_ReplyRef{Root: posted[0], Parent: posted[len(posted)-1]}
+		}
+
+		ref, err := createPost(ctx, client, post)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create post %d of %d: %w", i+1, len(texts), err)
+		}
+		posted = append(posted, ref)
+		if i == 0 {
+			gate(ctx, client, ref)
+		}
+		if threadKey != "" {
+			if err := store.SaveThreadProgress(threadKey, posted); err != nil {
+				return nil, fmt.Errorf("failed to save thread progress: %w", err)
+			}
+		}
+	}
+
+	fmt.Println("Successfully posted earthquake report to Bluesky!")
+	if threadKey != "" {
+		if err := store.DeleteThreadProgress(threadKey); err != nil {
+			fmt.Printf("Error deleting thread progress: %v\n", err)
+		}
+	}
+
+	// A failed pin must not fail the run, the report itself was posted
+	if envBool("PIN_LATEST") && len(posted) > 0 {
+		if err := pinPost(ctx, client, posted[0]); err != nil {
+			fmt.Printf("Error pinning report: %v\n", err)
+		}
+	}
+	if len(posted) == 0 {
+		return nil, nil
+	}
+	return posted[0], nil
+}
+
+// Create the threadgate of REPLY_POLICY for the first post of a thread. A
+// failure must not fail the run, the post itself is out.
+func gate(ctx context.Context, client *xrpc.Client, post *atproto.RepoStrongRef) {
+	rules, err := replyPolicy()
+	if err == nil && rules != nil {
+		err = createThreadgate(ctx, client, post, rules)
+	}
+	if err != nil {
+		fmt.Printf("Error limiting replies: %v\n", err)
+	}
+}
+
+// Log in to Bluesky and return an authenticated client for the account's PDS.
+// The store caches the discovered PDS and may be nil.
+func login(ctx context.Context, store *Store) (*xrpc.Client, error) {
+	if safeMode() {
+		return nil, errSafeMode
+	}
+
+	// Get Bluesky credentials from environment variables
+	password, err := blueskyPassword()
+	if err != nil {
+		return nil, err
+	}
+	bskyConfig := BlueskyConfig{
+		Identifier: os.Getenv("BLUESKY_IDENTIFIER"),
+		Password:   password,
+	}
+
+	if bskyConfig.Identifier == "" || bskyConfig.Password == "" {
+		return nil, fmt.Errorf("missing Bluesky credentials in environment variables")
+	}
+
+	// Create a Bluesky client
+	host := os.Getenv("BLUESKY_HOST")
+	if host == "" {
+		host = "https://me.rasc.ch"
+	}
+
+	agent := userAgent()
+	client := &xrpc.Client{
+		Host:      pdsHost(ctx, store, host, bskyConfig.Identifier),
+		Auth:      &xrpc.AuthInfo{},
+		UserAgent: &agent,
+	}
+
+	// Continue the stored session to spare the rate-limited password login
+	var sessions sessionStore
+	keepSession := false
+	if store != nil {
+		sessions, keepSession = openSessionStore()
+	}
+	if keepSession && resumeSession(ctx, sessions, client, bskyConfig.Identifier) {
+		return client, nil
+	}
+
+	// Log in to Bluesky
+	auth, err := atproto.ServerCreateSession(ctx, client, &atproto.ServerCreateSession_Input{
+		Identifier: bskyConfig.Identifier,
+		Password:   bskyConfig.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate with Bluesky: %w", err)
+	}
+	warnFullAccessSession(auth.AccessJwt)
+
+	// Set auth info
+	client.Auth.AccessJwt = auth.AccessJwt
+	client.Auth.RefreshJwt = auth.RefreshJwt
+	client.Auth.Handle = auth.Handle
+	client.Auth.Did = auth.Did
+
+	if keepSession {
+		session := storedSession{Host: client.Host, Did: auth.Did, Handle: auth.Handle, AccessJwt: auth.AccessJwt, RefreshJwt: auth.RefreshJwt}
+		if err := sessions.Save(bskyConfig.Identifier, session); err != nil {
+			fmt.Printf("Error saving session: %v\n", err)
+		}
+	}
+	return client, nil
+}
+
+// Scope of app password sessions, "com.atproto.appPassPrivileged" when the
+// app password may also read direct messages
+const appPassScopePrefix = "com.atproto.appPass"
+
+// Report whether an access token belongs to a session with the full access
+// of the account password. The scope claim of the token is read without
+// verifying it, tokens that cannot be read are not reported.
+func isFullAccessSession(accessJwt string) bool {
+	parts := strings.Split(accessJwt, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Scope == "" {
+		return false
+	}
+	return !strings.HasPrefix(claims.Scope, appPassScopePrefix)
+}
+
+// Warn when a session has the full access of the account password
+func warnFullAccessSession(accessJwt string) {
+	if isFullAccessSession(accessJwt) {
+		fmt.Println("Warning: logged in with a full-access session, use an app password so a leaked credential cannot change the account")
+	}
+}
+
+// App passwords look like "abcd-efgh-ijkl-mnop"
+var appPasswordPattern = regexp.MustCompile(`^[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}$`)
+
+// Read the password from the file named by BLUESKY_PASSWORD_FILE, falling back
+// to BLUESKY_PASSWORD. A password that is not shaped like an app password is
+// probably the account password, which grants far more than posting.
+func blueskyPassword() (string, error) {
+	password := os.Getenv("BLUESKY_PASSWORD")
+	if path := os.Getenv("BLUESKY_PASSWORD_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		password = strings.TrimSpace(string(data))
+		if password == "" {
+			return "", fmt.Errorf("password file %s is empty", path)
+		}
+	}
+
+	if password != "" && !appPasswordPattern.MatchString(password) {
+		fmt.Println("Warning: the Bluesky password does not look like an app password, create one under Settings > Privacy and security > App passwords")
+	}
+	return password, nil
+}
+
+// Create a post record and return a strong reference to it
+func createPost(ctx context.Context, client *xrpc.Client, post *bsky.FeedPost) (*atproto.RepoStrongRef, error) {
+	out, err := atproto.RepoCreateRecord(ctx, client, &atproto.RepoCreateRecord_Input{
+		Repo:       client.Auth.Did,
+		Collection: "app.bsky.feed.post",
+		Record:     &util.LexiconTypeDecoder{Val: post},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &atproto.RepoStrongRef{Uri: out.Uri, Cid: out.Cid}, nil
+}
+
+// Pin the post on the bot's profile, keeping the other profile fields. On the
+// first run the account may have no profile record yet, then one is created.
+func pinPost(ctx context.Context, client *xrpc.Client, ref *atproto.RepoStrongRef) error {
+	profile := &bsky.ActorProfile{LexiconTypeID: "app.bsky.actor.profile"}
+	var swapRecord *string
+
+	out, err := atproto.RepoGetRecord(ctx, client, "", "app.bsky.actor.profile", client.Auth.Did, "self")
+	switch {
+	case err == nil:
+		existing, ok := out.Value.Val.(*bsky.ActorProfile)
+		if !ok {
+			return fmt.Errorf("unexpected profile record type %T", out.Value.Val)
+		}
+		profile = existing
+		// Fail instead of overwriting a profile that was edited in the meantime
+		swapRecord = out.Cid
+	case strings.Contains(err.Error(), "RecordNotFound"):
+	default:
+		return fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	profile.PinnedPost = ref
+	_, err = atproto.RepoPutRecord(ctx, client, &atproto.RepoPutRecord_Input{
+		Repo:       client.Auth.Did,
+		Collection: "app.bsky.actor.profile",
+		Rkey:       "self",
+		Record:     &util.LexiconTypeDecoder{Val: profile},
+		SwapRecord: swapRecord,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	return nil
+}
+
+// Build the post record for a report. All FeedPost fields are populated here
+// so lexicon changes only need to be handled in one place.
+func buildPost(reportText string) *bsky.FeedPost {
+	return &bsky.FeedPost{
+		LexiconTypeID: "app.bsky.feed.post",
+		Text:          reportText,
+		CreatedAt:     now().Format(time.RFC3339),
+		Langs:         []string{"en"},
+		Labels:        selfLabels(),
+	}
+}
+
+// Self-labels from POST_LABELS, a comma-separated list of label values such
+// as "graphic-media". Unset means an unlabeled post.
+func selfLabels() *bsky.FeedPost_Labels {
+	var values []*atproto.LabelDefs_SelfLabel
+	for value := range strings.SplitSeq(os.Getenv("POST_LABELS"), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, &atproto.LabelDefs_SelfLabel{Val: value})
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return &bsky.FeedPost_Labels{
+		LabelDefs_SelfLabels: &atproto.LabelDefs_SelfLabels{Values: values},
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockPDS is a minimal Bluesky PDS that records the requests it receives
+type mockPDS struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests map[string][]map[string]any
+	records  int
+	// Stored profile record, nil until one is put
+	profile map[string]any
+	// Number of blob uploads to fail with a server error
+	failUploads int
+	// Fail the creation of the record with this number once, 0 never fails
+	failRecordAt int
+	// Service endpoint in the DID document of did:plc:bot, the server itself by default
+	pdsEndpoint string
+	// Pages of notifications, the cursor is the index of the next page
+	notifications [][]map[string]any
+	// Number of sessions issued, every refresh rotates the tokens
+	sessions int
+	// Called once before the next refresh is checked, to rotate the tokens
+	// as another run would
+	beforeRefresh func()
+}
+
+func newMockPDS(t *testing.T) *mockPDS {
+	t.Helper()
+	pds := &mockPDS{requests: make(map[string][]map[string]any)}
+	pds.Server = httptest.NewServer(http.HandlerFunc(pds.handle))
+	pds.pdsEndpoint = pds.URL
+	t.Cleanup(pds.Close)
+
+	// The server also acts as the PLC directory
+	plcDirectory = pds.URL + "/plc"
+	t.Cleanup(func() { plcDirectory = "https://plc.directory" })
+
+	t.Setenv("BLUESKY_HOST", pds.URL)
+	t.Setenv("BLUESKY_IDENTIFIER", "bot.example.com")
+	t.Setenv("BLUESKY_PASSWORD", "abcd-efgh-ijkl-mnop")
+	t.Setenv("SESSION_FILE", filepath.Join(t.TempDir(), "session.json"))
+	return pds
+}
+
+func (p *mockPDS) handle(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/xrpc/")
+	body, _ := io.ReadAll(r.Body)
+	var input map[string]any
+	_ = json.Unmarshal(body, &input)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests[method] = append(p.requests[method], input)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch method {
+	case "com.atproto.identity.resolveHandle":
+		if r.URL.Query().Get("handle") != "bot.example.com" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "InvalidRequest", "message": "Unable to resolve handle"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"did": "did:plc:bot"})
+	case "/plc/did:plc:bot":
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "did:plc:bot",
+			"service": []map[string]string{
+				{"id": "#atproto_pds", "type": "AtprotoPersonalDataServer", "serviceEndpoint": p.pdsEndpoint},
+			},
+		})
+	case "com.atproto.server.createSession":
+		p.sessions++
+		json.NewEncoder(w).Encode(p.session())
+	case "com.atproto.server.refreshSession":
+		if p.beforeRefresh != nil {
+			p.beforeRefresh()
+			p.beforeRefresh = nil
+		}
+		if r.Header.Get("Authorization") != "Bearer refresh-"+strconv.Itoa(p.sessions) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken", "message": "Token has been revoked"})
+			return
+		}
+		p.sessions++
+		json.NewEncoder(w).Encode(p.session())
+	case "com.atproto.repo.createRecord":
+		if p.failRecordAt == p.records+1 {
+			p.failRecordAt = 0
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": "UpstreamFailure"})
+			return
+		}
+		p.records++
+		json.NewEncoder(w).Encode(map[string]string{
+			"uri": "at://did:plc:bot/app.bsky.feed.post/" + string(rune('a'+p.records-1)),
+			"cid": "cid" + string(rune('a'+p.records-1)),
+		})
+	case "com.atproto.repo.uploadBlob":
+		if p.failUploads > 0 {
+			p.failUploads--
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": "UpstreamFailure"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"blob": map[string]any{
+			"$type": "blob", "ref": map[string]string{"$link": "bafkblob"}, "mimeType": "*/*", "size": len(body),
+		}})
+	case "com.atproto.repo.getRecord":
+		if p.profile == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "RecordNotFound", "message": "Could not locate record"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"uri": "at://did:plc:bot/app.bsky.actor.profile/self", "cid": "profilecid", "value": p.profile,
+		})
+	case "com.atproto.repo.putRecord":
+		p.profile = input["record"].(map[string]any)
+		json.NewEncoder(w).Encode(map[string]string{
+			"uri": "at://did:plc:bot/app.bsky.actor.profile/self", "cid": "profilecid",
+		})
+	case "app.bsky.notification.listNotifications":
+		page, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		out := map[string]any{"notifications": []map[string]any{}}
+		if page < len(p.notifications) {
+			out["notifications"] = p.notifications[page]
+		}
+		if page+1 < len(p.notifications) {
+			out["cursor"] = strconv.Itoa(page + 1)
+		}
+		json.NewEncoder(w).Encode(out)
+	default:
+		json.NewEncoder(w).Encode(map[string]any{})
+	}
+}
+
+// Tokens of the latest session
+func (p *mockPDS) session() map[string]string {
+	n := strconv.Itoa(p.sessions)
+	return map[string]string{
+		"accessJwt": "access-" + n, "refreshJwt": "refresh-" + n, "did": "did:plc:bot", "handle": "bot.example.com",
+	}
+}
+
+// Records of the given XRPC method, in request order
+func (p *mockPDS) calls(method string) []map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests[method]
+}
+
+func TestBuildPostSetsRequiredFields(t *testing.T) {
+	instant := time.Date(2026, 6, 15, 8, 30, 0, 0, time.UTC)
+	setNow(t, instant)
+
+	post := buildPost("Weekly Earthquake Report")
+
+	if post.LexiconTypeID != "app.bsky.feed.post" {
+		t.Fatalf("expected post type, got %q", post.LexiconTypeID)
+	}
+	if post.Text != "Weekly Earthquake Report" {
+		t.Fatalf("unexpected text %q", post.Text)
+	}
+	createdAt, err := time.Parse(time.RFC3339, post.CreatedAt)
+	if err != nil || !createdAt.Equal(instant) {
+		t.Fatalf("expected createdAt %v, got %q (err %v)", instant, post.CreatedAt, err)
+	}
+	if len(post.Langs) != 1 || post.Langs[0] != "en" {
+		t.Fatalf("expected langs [en], got %v", post.Langs)
+	}
+}
+
+func TestPostToBlueskyThreadsReplies(t *testing.T) {
+	pds := newMockPDS(t)
+
+	if err := postToBluesky(context.Background(), nil, "first", "second", "third"); err != nil {
+		t.Fatalf("postToBluesky returned error: %v", err)
+	}
+
+	created := pds.calls("com.atproto.repo.createRecord")
+	if len(created) != 3 {
+		t.Fatalf("expected 3 posts, got %d", len(created))
+	}
+	if _, ok := created[0]["record"].(map[string]any)["reply"]; ok {
+		t.Fatal("expected the first post not to be a reply")
+	}
+
+	reply := created[2]["record"].(map[string]any)["reply"].(map[string]any)
+	if root := reply["root"].(map[string]any)["uri"]; root != "at://did:plc:bot/app.bsky.feed.post/a" {
+		t.Fatalf("expected root to be the first post, got %v", root)
+	}
+	if parent := reply["parent"].(map[string]any)["uri"]; parent != "at://did:plc:bot/app.bsky.feed.post/b" {
+		t.Fatalf("expected parent to be the second post, got %v", parent)
+	}
+}
+
+func TestPostToBlueskyAttachesConfiguredSelfLabels(t *testing.T) {
+	pds := newMockPDS(t)
+
+	if post := buildPost("unlabeled"); post.Labels != nil {
+		t.Fatalf("expected no labels by default, got %+v", post.Labels)
+	}
+
+	t.Setenv("POST_LABELS", "graphic-media, sexual")
+	if err := postToBluesky(context.Background(), nil, "labeled"); err != nil {
+		t.Fatalf("postToBluesky returned error: %v", err)
+	}
+
+	labels := pds.calls("com.atproto.repo.createRecord")[0]["record"].(map[string]any)["labels"].(map[string]any)
+	if labels["$type"] != "com.atproto.label.defs#selfLabels" {
+		t.Fatalf("expected self-labels type, got %v", labels["$type"])
+	}
+	values := labels["values"].([]any)
+	if len(values) != 2 || values[0].(map[string]any)["val"] != "graphic-media" || values[1].(map[string]any)["val"] != "sexual" {
+		t.Fatalf("unexpected label values %v", values)
+	}
+}
+
+func TestLoginReadsPasswordFromFile(t *testing.T) {
+	pds := newMockPDS(t)
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("wxyz-1234-abcd-5678\n"), 0o600); err != nil {
+		t.Fatalf("failed to write password file: %v", err)
+	}
+	t.Setenv("BLUESKY_PASSWORD_FILE", path)
+
+	if _, err := login(context.Background(), nil); err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+	session := pds.calls("com.atproto.server.createSession")
+	if len(session) != 1 || session[0]["password"] != "wxyz-1234-abcd-5678" {
+		t.Fatalf("expected the password from the file, got %v", session)
+	}
+
+	t.Setenv("BLUESKY_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := login(context.Background(), nil); err == nil {
+		t.Fatal("expected an error for a missing password file")
+	}
+}
+
+func TestPostToBlueskyPinsLatestReport(t *testing.T) {
+	pds := newMockPDS(t)
+
+	if err := postToBluesky(context.Background(), nil, "unpinned"); err != nil {
+		t.Fatalf("postToBluesky returned error: %v", err)
+	}
+	if puts := pds.calls("com.atproto.repo.putRecord"); len(puts) != 0 {
+		t.Fatalf("expected no pin without PIN_LATEST, got %d profile updates", len(puts))
+	}
+
+	t.Setenv("PIN_LATEST", "true")
+
+	// First run: no profile record exists yet
+	if err := postToBluesky(context.Background(), nil, "report", "reply"); err != nil {
+		t.Fatalf("postToBluesky returned error: %v", err)
+	}
+	puts := pds.calls("com.atproto.repo.putRecord")
+	if len(puts) != 1 {
+		t.Fatalf("expected one profile update, got %d", len(puts))
+	}
+	if puts[0]["rkey"] != "self" || puts[0]["collection"] != "app.bsky.actor.profile" || puts[0]["swapRecord"] != nil {
+		t.Fatalf("unexpected profile creation %v", puts[0])
+	}
+	pinned := puts[0]["record"].(map[string]any)["pinnedPost"].(map[string]any)
+	if pinned["uri"] != "at://did:plc:bot/app.bsky.feed.post/b" || pinned["cid"] != "cidb" {
+		t.Fatalf("expected the report, not its reply, to be pinned, got %v", pinned)
+	}
+
+	// Later runs keep the other profile fields
+	pds.profile["displayName"] = "Earthquake Bot"
+	if err := postToBluesky(context.Background(), nil, "next report"); err != nil {
+		t.Fatalf("postToBluesky returned error: %v", err)
+	}
+	puts = pds.calls("com.atproto.repo.putRecord")
+	record := puts[1]["record"].(map[string]any)
+	if record["displayName"] != "Earthquake Bot" || puts[1]["swapRecord"] != "profilecid" {
+		t.Fatalf("expected the existing profile to be updated, got %v", puts[1])
+	}
+	if uri := record["pinnedPost"].(map[string]any)["uri"]; uri != "at://did:plc:bot/app.bsky.feed.post/d" {
+		t.Fatalf("expected the newest report to be pinned, got %v", uri)
+	}
+}
+
+func TestPostThreadResumesAfterFailure(t *testing.T) {
+	pds := newMockPDS(t)
+	store := openTestStore(t)
+	pds.failRecordAt = 2
+
+	texts := []string{"first", "second", "third"}
+	if _, err := postThread(context.Background(), store, "2026-W23", texts); err == nil {
+		t.Fatal("expected the failed second post to fail the thread")
+	}
+	if posted, _ := store.LoadThreadProgress("2026-W23"); len(posted) != 1 {
+		t.Fatalf("expected the first post to be recorded, got %v", posted)
+	}
+
+	if _, err := postThread(context.Background(), store, "2026-W23", texts); err != nil {
+		t.Fatalf("resuming the thread returned error: %v", err)
+	}
+
+	created := pds.calls("com.atproto.repo.createRecord")
+	var succeeded []string
+	for _, call := range created {
+		succeeded = append(succeeded, call["record"].(map[string]any)["text"].(string))
+	}
+	// The failed attempt of "second" is recorded as well
+	if strings.Join(succeeded, ",") != "first,second,second,third" {
+		t.Fatalf("expected the thread to continue with the second post, got %v", succeeded)
+	}
+	reply := created[3]["record"].(map[string]any)["reply"].(map[string]any)
+	if root := reply["root"].(map[string]any)["uri"]; root != "at://did:plc:bot/app.bsky.feed.post/a" {
+		t.Fatalf("expected the resumed posts to reply to the original root, got %v", root)
+	}
+	if parent := reply["parent"].(map[string]any)["uri"]; parent != "at://did:plc:bot/app.bsky.feed.post/b" {
+		t.Fatalf("expected the third post to answer the resumed second post, got %v", parent)
+	}
+	if posted, _ := store.LoadThreadProgress("2026-W23"); posted != nil {
+		t.Fatalf("expected the progress to be deleted after the thread completed, got %v", posted)
+	}
+}
+
+func TestIsFullAccessSession(t *testing.T) {
+	token := func(claims string) string {
+		return "eyJhbGciOiJFUzI1NksifQ." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2ln"
+	}
+	tests := map[string]bool{
+		token(`{"scope":"com.atproto.access","sub":"did:plc:bot"}`):            true,
+		token(`{"scope":"com.atproto.appPass","sub":"did:plc:bot"}`):           false,
+		token(`{"scope":"com.atproto.appPassPrivileged","sub":"did:plc:bot"}`): false,
+		token(`{"sub":"did:plc:bot"}`):                                         false,
+		"access-1":                                                             false,
+		"a.!!!.c":                                                              false,
+	}
+	for jwt, want := range tests {
+		if got := isFullAccessSession(jwt); got != want {
+			t.Errorf("isFullAccessSession(%q) = %v, want %v", jwt, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"os"
+	"strconv"
+)
+
+// Default magnitude of completeness. The worldwide USGS catalog is complete
+// from about M4.5, below that mostly well-instrumented regions report.
+const defaultCompletenessMag = 4.5
+
+// Fewer events above the completeness magnitude give a b-value too uncertain to report
+const minBValueEvents = 50
+
+// BValue is the Gutenberg-Richter b-value of the week and the inputs it was computed from
+type BValue struct {
+	Value           float64 `json:"value"`
+	CompletenessMag float64 `json:"completenessMag"`
+	Events          int     `json:"events"`
+}
+
+// Maximum-likelihood b-value after Aki (1965), b = log10(e) / (mean(M) - Mc),
+// over the magnitudes at or above the completeness magnitude. Returns NaN
+// with fewer than minBValueEvents such magnitudes.
+func computeBValue(mags []float64, completenessMag float64) float64 {
+	var sum float64
+	var n int
+	for _, m := range mags {
+		if m >= completenessMag {
+			sum += m
+			n++
+		}
+	}
+	if n < minBValueEvents {
+		return math.NaN()
+	}
+
+	meanExcess := sum/float64(n) - completenessMag
+	if meanExcess <= 0 {
+		return math.NaN()
+	}
+	return math.Log10(math.E) / meanExcess
+}
+
+// b-value of the events, nil when there are too few complete events
+func weeklyBValue(events []Earthquake) *BValue {
+	completenessMag := completenessMagnitude()
+	mags := make([]float64, 0, len(events))
+	complete := 0
+	for _, eq := range events {
+		mags = append(mags, eq.Magnitude)
+		if eq.Magnitude >= completenessMag {
+			complete++
+		}
+	}
+
+	b := computeBValue(mags, completenessMag)
+	if math.IsNaN(b) {
+		return nil
+	}
+	return &BValue{Value: b, CompletenessMag: completenessMag, Events: complete}
+}
+
+// Read the magnitude of completeness from COMPLETENESS_MAG
+func completenessMagnitude() float64 {
+	if mc, err := strconv.ParseFloat(os.Getenv("COMPLETENESS_MAG"), 64); err == nil {
+		return mc
+	}
+	return defaultCompletenessMag
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// Magnitudes at the quantiles of a Gutenberg-Richter distribution with the given b-value
+func gutenbergRichterMags(n int, b, completenessMag float64) []float64 {
+	mags := make([]float64, n)
+	for i := range mags {
+		u := (float64(i) + 0.5) / float64(n)
+		mags[i] = completenessMag - math.Log10(u)/b
+	}
+	return mags
+}
+
+func TestComputeBValueRecoversKnownDistribution(t *testing.T) {
+	for _, want := range []float64{0.8, 1.0, 1.3} {
+		mags := gutenbergRichterMags(2000, want, 3.0)
+		// Events below the completeness magnitude are ignored
+		mags = append(mags, 1.2, 2.1, 2.9)
+
+		if got := computeBValue(mags, 3.0); math.Abs(got-want) > 0.02 {
+			t.Fatalf("expected b-value %.2f, got %.3f", want, got)
+		}
+	}
+}
+
+func TestComputeBValueOmitsSmallSamples(t *testing.T) {
+	if got := computeBValue(gutenbergRichterMags(minBValueEvents-1, 1, 4.5), 4.5); !math.IsNaN(got) {
+		t.Fatalf("expected NaN for too few events, got %v", got)
+	}
+
+	var events []Earthquake
+	for _, m := range gutenbergRichterMags(100, 1, 4.5) {
+		events = append(events, Earthquake{Magnitude: m})
+	}
+	b := weeklyBValue(events)
+	if b == nil || b.Events != 100 || b.CompletenessMag != 4.5 {
+		t.Fatalf("unexpected b-value %+v", b)
+	}
+	text := renderDetailed(Report{Categories: newReport("", WeekStats{}).Categories, BValue: b})
+	if !strings.Contains(text, "b-value: 1.0") || !strings.Contains(text, "(M4.5 and above, 100 events)") {
+		t.Fatalf("expected b-value line, got:\n%s", text)
+	}
+}
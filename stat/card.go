@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+)
+
+// Layout of the report card in pixels. Text is drawn with cardFont at an
+// integer scale, a glyph is 5x7 font pixels plus one pixel of spacing.
+const (
+	cardWidth      = 1200
+	cardMargin     = 60
+	cardRowHeight  = 60
+	cardTitleScale = 6
+	cardTextScale  = 4
+	cardSmallScale = 3
+	cardBarLeft    = 520
+	cardBarRight   = 980
+)
+
+var (
+	cardText  = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	cardMuted = color.RGBA{R: 0xa9, G: 0xbc, B: 0xcc, A: 0xff}
+)
+
+// Render the report as a PNG card: title, week, one row per category with a
+// bar in the category color, the total, the largest earthquake and a footer
+// from CARD_FOOTER, "Data: USGS" by default. Bars are on a log scale so the
+// rare strong earthquakes stay visible next to thousands of micro ones.
+func renderReportCard(report Report) ([]byte, error) {
+	height := 2*cardMargin + 7*cardTitleScale + 3*7*cardSmallScale + 2*7*cardTextScale + 140 + len(report.Categories)*cardRowHeight
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, height))
+	for y := range height {
+		for x := range cardWidth {
+			img.SetRGBA(x, y, mapBackground)
+		}
+	}
+
+	y := cardMargin
+	drawText(img, cardMargin, y, cardTitleScale, cardText, "Weekly Earthquake Report")
+	y += 7*cardTitleScale + 20
+	drawText(img, cardMargin, y, cardSmallScale, cardMuted, fmt.Sprintf("%s  %s - %s", report.WeekKey,
+		report.StartDate.Format("2006-01-02"), report.EndDate.Format("2006-01-02")))
+	y += 7*cardSmallScale + 40
+
+	largestCount := 0
+	for _, category := range report.Categories {
+		largestCount = max(largestCount, category.Count)
+	}
+	for _, category := range report.Categories {
+		rowY := y + (cardRowHeight-7*cardTextScale)/2
+		drawText(img, cardMargin, rowY, cardTextScale, cardText, category.Label)
+		if category.Count > 0 {
+			width := int(float64(cardBarRight-cardBarLeft) * math.Log1p(float64(category.Count)) / math.Log1p(float64(largestCount)))
+			fill(img, image.Rect(cardBarLeft, rowY, cardBarLeft+max(width, cardTextScale), rowY+7*cardTextScale), categoryColor(category.Label))
+		}
+		count := formatCount(category.Count)
+		drawText(img, cardWidth-cardMargin-textWidth(count, cardTextScale), rowY, cardTextScale, cardText, count)
+		y += cardRowHeight
+	}
+
+	y += 20
+	total := formatCount(report.Total)
+	drawText(img, cardMargin, y, cardTextScale, cardText, "Total")
+	drawText(img, cardWidth-cardMargin-textWidth(total, cardTextScale), y, cardTextScale, cardText, total)
+	y += 7*cardTextScale + 20
+	if report.Largest != nil {
+		drawText(img, cardMargin, y, cardTextScale, cardText, "Largest M"+formatMag(report.Largest.Magnitude))
+	}
+	y += 7*cardTextScale + 40
+
+	footer := os.Getenv("CARD_FOOTER")
+	if footer == "" {
+		footer = "Data: USGS"
+	}
+	drawText(img, cardMargin, y, cardSmallScale, cardMuted, footer)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode report card: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Palette color of a category label, the text color for unknown labels
+func categoryColor(label string) color.RGBA {
+	if i := slices.Index(categories, label); i >= 0 {
+		return magnitudePalette[i]
+	}
+	return cardText
+}
+
+func fill(img *image.RGBA, rect image.Rectangle, c color.RGBA) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// Width of text in pixels at the given scale
+func textWidth(text string, scale int) int {
+	return len([]rune(text)) * 6 * scale
+}
+
+// Draw text with its top left corner at x, y. Lowercase letters are drawn in
+// uppercase, characters without a glyph as blanks.
+func drawText(img *image.RGBA, x, y, scale int, c color.RGBA, text string) {
+	for _, r := range text {
+		glyph := cardFont[unicode.ToUpper(r)]
+		for row, bits := range glyph {
+			for col := range 5 {
+				if bits&(1<<(4-col)) != 0 {
+					fill(img, image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale), c)
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
+
+// 5x7 bitmap font for the characters of the report card, one byte per row
+// with the leftmost pixel in bit 4
+var cardFont = map[rune][7]uint8{
+	'A': {0b01110, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'B': {0b11110, 0b10001, 0b10001, 0b11110, 0b10001, 0b10001, 0b11110},
+	'C': {0b01110, 0b10001, 0b10000, 0b10000, 0b10000, 0b10001, 0b01110},
+	'D': {0b11110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b11110},
+	'E': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b11111},
+	'F': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b10000},
+	'G': {0b01110, 0b10001, 0b10000, 0b10111, 0b10001, 0b10001, 0b01111},
+	'H': {0b10001, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'I': {0b01110, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'J': {0b00111, 0b00010, 0b00010, 0b00010, 0b00010, 0b10010, 0b01100},
+	'K': {0b10001, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010, 0b10001},
+	'L': {0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b11111},
+	'M': {0b10001, 0b11011, 0b10101, 0b10101, 0b10001, 0b10001, 0b10001},
+	'N': {0b10001, 0b10001, 0b11001, 0b10101, 0b10011, 0b10001, 0b10001},
+	'O': {0b01110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'P': {0b11110, 0b10001, 0b10001, 0b11110, 0b10000, 0b10000, 0b10000},
+	'Q': {0b01110, 0b10001, 0b10001, 0b10001, 0b10101, 0b10010, 0b01101},
+	'R': {0b11110, 0b10001, 0b10001, 0b11110, 0b10100, 0b10010, 0b10001},
+	'S': {0b01111, 0b10000, 0b10000, 0b01110, 0b00001, 0b00001, 0b11110},
+	'T': {0b11111, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100},
+	'U': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'V': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01010, 0b00100},
+	'W': {0b10001, 0b10001, 0b10001, 0b10101, 0b10101, 0b10101, 0b01010},
+	'X': {0b10001, 0b10001, 0b01010, 0b00100, 0b01010, 0b10001, 0b10001},
+	'Y': {0b10001, 0b10001, 0b01010, 0b00100, 0b00100, 0b00100, 0b00100},
+	'Z': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b11111},
+	'0': {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	'1': {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'2': {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	'3': {0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	'4': {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	'5': {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	'6': {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	'7': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	'8': {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	'9': {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+	'.': {0, 0, 0, 0, 0, 0b01100, 0b01100},
+	',': {0, 0, 0, 0, 0b01100, 0b00100, 0b01000},
+	':': {0, 0b01100, 0b01100, 0, 0b01100, 0b01100, 0},
+	'-': {0, 0, 0, 0b11111, 0, 0, 0},
+	'+': {0, 0b00100, 0b00100, 0b11111, 0b00100, 0b00100, 0},
+	'=': {0, 0, 0b11111, 0, 0b11111, 0, 0},
+	'<': {0b00010, 0b00100, 0b01000, 0b10000, 0b01000, 0b00100, 0b00010},
+	'>': {0b01000, 0b00100, 0b00010, 0b00001, 0b00010, 0b00100, 0b01000},
+	'(': {0b00010, 0b00100, 0b01000, 0b01000, 0b01000, 0b00100, 0b00010},
+	')': {0b01000, 0b00100, 0b00010, 0b00010, 0b00010, 0b00100, 0b01000},
+	'/': {0b00001, 0b00010, 0b00010, 0b00100, 0b01000, 0b01000, 0b10000},
+	'%': {0b11000, 0b11001, 0b00010, 0b00100, 0b01000, 0b10011, 0b00011},
+}
+
+// Post the report as a single post with the card image. The post text is
+// only the title line, the full report text is the alt text of the image.
+func postReportCard(ctx context.Context, store *Store, reportData ReportData) (*atproto.RepoStrongRef, error) {
+	data, err := renderReportCard(reportData.Report)
+	if err != nil {
+		return nil, err
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report card size: %w", err)
+	}
+
+	client, err := login(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := uploadImage(ctx, client, data)
+	if err != nil {
+		return nil, err
+	}
+
+	post := buildPost("Weekly Earthquake Report " + reportData.WeekKey)
+	post.Embed = &bsky.FeedPost_Embed{EmbedImages: &bsky.EmbedImages{
+		LexiconTypeID: "app.bsky.embed.images",
+		Images: []*bsky.EmbedImages_Image{{
+			Alt:         strings.Join(reportData.Posts(), "\n\n"),
+			AspectRatio: &bsky.EmbedDefs_AspectRatio{Width: int64(config.Width), Height: int64(config.Height)},
+			Image:       blob,
+		}},
+	}}
+	ref, err := createPost(ctx, client, post)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create post: %w", err)
+	}
+	gate(ctx, client, ref)
+	fmt.Println("Successfully posted earthquake report card to Bluesky!")
+
+	if envBool("PIN_LATEST") {
+		if err := pinPost(ctx, client, ref); err != nil {
+			fmt.Printf("Error pinning report: %v\n", err)
+		}
+	}
+	return ref, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func cardTestReport() Report {
+	return newReport("2026-W23", WeekStats{Counts: [7]int{1500, 900, 120, 14, 2, 0, 0}, MagnitudeSum: 4000,
+		StartDate: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 6, 7, 23, 59, 59, 0, time.UTC),
+		Largest: Earthquake{Magnitude: 6.4, Place: "Near the coast of Peru"}})
+}
+
+func TestRenderReportCard(t *testing.T) {
+	data, err := renderReportCard(cardTestReport())
+	if err != nil {
+		t.Fatalf("renderReportCard returned error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("report card is not a PNG: %v", err)
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() != cardWidth || bounds.Dy() < 600 || bounds.Dy() > cardWidth {
+		t.Fatalf("unexpected card size %v", bounds)
+	}
+	if got := img.At(0, 0); got != mapBackground {
+		t.Fatalf("expected the background in the corner, got %v", got)
+	}
+
+	// Every category with earthquakes has a bar in its color, empty ones none
+	rowsTop := cardMargin + 7*cardTitleScale + 20 + 7*cardSmallScale + 40
+	colors := make(map[string]bool)
+	for y := rowsTop; y < rowsTop+len(categories)*cardRowHeight; y++ {
+		for x := cardBarLeft; x < cardBarRight; x++ {
+			for i, c := range magnitudePalette {
+				if img.At(x, y) == c {
+					colors[categories[i]] = true
+				}
+			}
+		}
+	}
+	for i, category := range categories {
+		if want := i <= 4; colors[category] != want {
+			t.Fatalf("bar of %s drawn: %v, want %v", category, colors[category], want)
+		}
+	}
+}
+
+func TestPostReportCard(t *testing.T) {
+	pds := newMockPDS(t)
+	reportData := ReportData{WeekKey: "2026-W23", Report: cardTestReport()}
+	reportData.ReportText = renderText(reportData.Report)
+
+	ref, err := postReportCard(context.Background(), nil, reportData)
+	if err != nil {
+		t.Fatalf("postReportCard returned error: %v", err)
+	}
+	if ref == nil || len(pds.calls("com.atproto.repo.uploadBlob")) != 1 {
+		t.Fatalf("expected one upload and a post, got %v", ref)
+	}
+
+	records := pds.calls("com.atproto.repo.createRecord")
+	if len(records) != 1 {
+		t.Fatalf("expected a single post, got %d", len(records))
+	}
+	record := records[0]["record"].(map[string]any)
+	if record["text"] != "Weekly Earthquake Report 2026-W23" {
+		t.Fatalf("unexpected post text %q", record["text"])
+	}
+	embed := record["embed"].(map[string]any)
+	image := embed["images"].([]any)[0].(map[string]any)
+	if embed["$type"] != "app.bsky.embed.images" || image["alt"] != reportData.ReportText {
+		t.Fatalf("expected the report text as alt text, got %v", embed)
+	}
+	if ratio := image["aspectRatio"].(map[string]any); ratio["width"] != float64(cardWidth) {
+		t.Fatalf("unexpected aspect ratio %v", ratio)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// A continent or ocean as a latitude/longitude box. The boxes are coarse:
+// they follow no coastlines or plate boundaries, overlap in places and are
+// checked in order, so the first box containing a point wins. Points in no
+// box are assigned to the ocean of their longitude.
+type continentBox struct {
+	name           string
+	minLat, maxLat float64
+	minLon, maxLon float64
+}
+
+var continentBoxes = []continentBox{
+	{"Antarctica", -90, -60, -180, 180},
+	{"Europe", 36, 72, -25, 40},
+	// The Aleutians cross the antimeridian, before Asia takes the western part
+	{"North America", 50, 66, -180, -170},
+	{"North America", 50, 56, 170, 180},
+	// The Middle East from 35°E and 12°N, South and Southeast Asia further south
+	{"Asia", 12, 82, 35, 180},
+	{"Asia", -10, 12, 60, 130},
+	// Australia, New Guinea and the Pacific islands down to New Zealand
+	{"Oceania", -50, 0, 110, 180},
+	{"Oceania", -50, 30, -180, -130},
+	{"Africa", -35, 37, -18, 52},
+	{"North America", 7, 85, -170, -50},
+	{"South America", -56, 13, -82, -34},
+}
+
+// Continent or ocean of a point, "Unknown" without coordinates
+func continentOf(lat, lon float64) string {
+	if math.IsNaN(lat) || math.IsNaN(lon) {
+		return "Unknown"
+	}
+	for _, box := range continentBoxes {
+		if lat >= box.minLat && lat <= box.maxLat && lon >= box.minLon && lon <= box.maxLon {
+			return box.name
+		}
+	}
+	switch {
+	case lon >= -70 && lon < 20:
+		return "Atlantic Ocean"
+	case lon >= 20 && lon < 120 && lat < 30:
+		return "Indian Ocean"
+	default:
+		return "Pacific Ocean"
+	}
+}
+
+// Number of events per continent or ocean, largest first. Buckets without
+// events are left out.
+func groupByContinent(events []Earthquake) []CategoryCount {
+	counts := make(map[string]int)
+	for _, eq := range events {
+		counts[continentOf(eq.Latitude, eq.Longitude)]++
+	}
+
+	return sortedCounts(counts)
+}
+
+// Counts by label, largest first and by label on ties
+func sortedCounts(counts map[string]int) []CategoryCount {
+	groups := make([]CategoryCount, 0, len(counts))
+	for name, count := range counts {
+		groups = append(groups, CategoryCount{Label: name, Count: count})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Label < groups[j].Label
+	})
+	return groups
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContinentOfBoxes(t *testing.T) {
+	tests := []struct {
+		place    string
+		lat, lon float64
+		want     string
+	}{
+		{"Ross Sea", -75, 170, "Antarctica"},
+		{"Central Italy", 42.8, 13.1, "Europe"},
+		{"Iceland", 64.0, -21.9, "Europe"},
+		{"Rat Islands, Aleutian Islands", 51.6, 178.3, "North America"},
+		{"Andreanof Islands, Aleutian Islands", 51.5, -175.5, "North America"},
+		{"Honshu, Japan", 38.3, 142.4, "Asia"},
+		{"Iran", 33.0, 52.0, "Asia"},
+		{"Sumatra, Indonesia", -1.0, 100.0, "Asia"},
+		{"New Britain, Papua New Guinea", -5.5, 151.5, "Oceania"},
+		{"Fiji", -18.0, 178.0, "Oceania"},
+		{"Tonga", -20.0, -175.0, "Oceania"},
+		{"East African Rift", -3.0, 36.0, "Africa"},
+		{"Southern California", 34.0, -117.0, "North America"},
+		{"Central Chile", -33.0, -71.5, "South America"},
+		{"Mid-Atlantic Ridge", 0.0, -25.0, "Atlantic Ocean"},
+		{"Central Indian Ridge", -20.0, 67.0, "Indian Ocean"},
+		{"East Pacific Rise", -10.0, -110.0, "Pacific Ocean"},
+		{"No coordinates", math.NaN(), math.NaN(), "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := continentOf(tt.lat, tt.lon); got != tt.want {
+			t.Errorf("%s (%v, %v): expected %s, got %s", tt.place, tt.lat, tt.lon, tt.want, got)
+		}
+	}
+}
+
+func TestContinentBreakdownInReport(t *testing.T) {
+	store := openTestStore(t)
+	t.Setenv("CONTINENT_BREAKDOWN", "true")
+
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	stats := groupByWeek([]Earthquake{
+		{Time: start.Add(time.Hour), Magnitude: 4.1, Latitude: 38.3, Longitude: 142.4},
+		{Time: start.Add(2 * time.Hour), Magnitude: 3.2, Latitude: 36.1, Longitude: 140.1},
+		{Time: start.Add(3 * time.Hour), Magnitude: 2.5, Latitude: -33.0, Longitude: -71.5},
+		{Time: start.Add(4 * time.Hour), Magnitude: 1.5, Latitude: math.NaN(), Longitude: math.NaN()},
+	})["2026-W23"]
+
+	text := renderText(buildReport(store, "2026-W23", stats))
+	if !strings.HasSuffix(text, "\n\nBy continent: Asia 2, South America 1, Unknown 1") {
+		t.Fatalf("expected the continent breakdown, got %q", text)
+	}
+}
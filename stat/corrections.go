@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/cockroachdb/pebble"
+)
+
+// Default change of a category, in percent of the posted count, that
+// triggers a correction
+const defaultCorrectionThreshold = 10.0
+
+// PostedWeek is the first post of a weekly report and the category counts
+// it showed, kept to post corrections when USGS revises the week
+type PostedWeek struct {
+	Root       *atproto.RepoStrongRef `json:"root"`
+	Categories []CategoryCount        `json:"categories"`
+}
+
+func (s *Store) SavePostedWeek(weekKey string, posted PostedWeek) error {
+	data, err := json.Marshal(posted)
+	if err != nil {
+		return fmt.Errorf("failed to encode posted week %s: %w", weekKey, err)
+	}
+	return s.db.Set([]byte(postedKeyPrefix+weekKey), data, pebble.Sync)
+}
+
+// Load the posted report of a week, false when none is stored
+func (s *Store) LoadPostedWeek(weekKey string) (PostedWeek, bool, error) {
+	var posted PostedWeek
+	value, closer, err := s.db.Get([]byte(postedKeyPrefix + weekKey))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return posted, false, nil
+	}
+	if err != nil {
+		return posted, false, err
+	}
+	defer closer.Close()
+
+	if err := json.Unmarshal(value, &posted); err != nil {
+		return posted, false, fmt.Errorf("failed to decode posted week %s: %w", weekKey, err)
+	}
+	return posted, true, nil
+}
+
+// Minimum change of a category in percent from CORRECTION_THRESHOLD
+func correctionThreshold() float64 {
+	if value := os.Getenv("CORRECTION_THRESHOLD"); value != "" {
+		if threshold, err := strconv.ParseFloat(value, 64); err == nil && threshold >= 0 {
+			return threshold
+		}
+		fmt.Printf("Warning: ignoring invalid CORRECTION_THRESHOLD %q\n", value)
+	}
+	return defaultCorrectionThreshold
+}
+
+// Describe the categories that changed by at least threshold percent of the
+// posted count, e.g. "Strong 6.0 - 6.9 now 4, was 3". Returns an empty
+// string when no category changed enough.
+func correctionText(posted, current []CategoryCount, threshold float64) string {
+	was := make(map[string]int)
+	for _, category := range posted {
+		was[category.Label] = category.Count
+	}
+
+	var changes []string
+	for _, category := range current {
+		previous := was[category.Label]
+		diff := math.Abs(float64(category.Count - previous))
+		if diff == 0 || diff*100 < threshold*float64(previous) {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s now %s, was %s", category.Label, formatCount(category.Count), formatCount(previous)))
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	return "Updated: " + strings.Join(changes, "; ")
+}
+
+// Re-aggregate the posted weeks that the feed covers completely and reply to
+// the original post when USGS revisions changed a category. weeklyStats
+// holds the weeks of the fresh data; a week counts as covered when the feed
+// has an event before its start.
+func postCorrections(ctx context.Context, store *Store, weeklyStats map[string]WeekStats, earthquakes []Earthquake) (int, error) {
+	if len(earthquakes) == 0 {
+		return 0, nil
+	}
+	earliest := earthquakes[0].Time
+	for _, eq := range earthquakes[1:] {
+		if eq.Time.Before(earliest) {
+			earliest = eq.Time
+		}
+	}
+
+	var weekKeys []string
+	for weekKey, stats := range getFullWeeks(weeklyStats) {
+		if earliest.Before(stats.StartDate) {
+			weekKeys = append(weekKeys, weekKey)
+		}
+	}
+	sortWeekKeys(weekKeys)
+
+	threshold := correctionThreshold()
+	corrected := 0
+	for _, weekKey := range weekKeys {
+		posted, ok, err := store.LoadPostedWeek(weekKey)
+		if err != nil {
+			return corrected, err
+		}
+		if !ok || posted.Root == nil {
+			continue
+		}
+
+		current := newReport(weekKey, weeklyStats[weekKey]).Categories
+		text := correctionText(posted.Categories, current, threshold)
+		if text == "" {
+			continue
+		}
+
+		fmt.Printf("Correcting %s: %s\n", weekKey, text)
+		if err := postReply(ctx, store, posted.Root, text); err != nil {
+			return corrected, fmt.Errorf("failed to post correction of %s: %w", weekKey, err)
+		}
+		corrected++
+
+		// Later corrections compare with the corrected counts
+		posted.Categories = current
+		if err := store.SavePostedWeek(weekKey, posted); err != nil {
+			return corrected, err
+		}
+	}
+	return corrected, nil
+}
+
+// Post text as a direct reply to a post
+func postReply(ctx context.Context, store *Store, root *atproto.RepoStrongRef, text string) error {
+	client, err := login(ctx, store)
+	if err != nil {
+		return err
+	}
+	if postLength(text) > maxPostLength {
+		text = string([]rune(text)[:maxPostLength-1]) + "…"
+	}
+	post := buildPost(text)
+	post.Reply = &bsky.FeedPost_ReplyRef{Root: root, Parent: root}
+	_, err = createPost(ctx, client, post)
+	return err
+}
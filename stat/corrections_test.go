@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+)
+
+func TestCorrectionText(t *testing.T) {
+	posted := []CategoryCount{{Label: "Minor 2.0 - 3.9", Count: 5000}, {Label: "Strong 6.0 - 6.9", Count: 3}}
+
+	if got := correctionText(posted, posted, 10); got != "" {
+		t.Fatalf("expected no correction without changes, got %q", got)
+	}
+	current := []CategoryCount{{Label: "Minor 2.0 - 3.9", Count: 5100}, {Label: "Strong 6.0 - 6.9", Count: 4}}
+	if got, want := correctionText(posted, current, 10), "Updated: Strong 6.0 - 6.9 now 4, was 3"; got != want {
+		t.Fatalf("unexpected correction:\nwant %q\ngot  %q", want, got)
+	}
+	if got, want := correctionText(posted, current, 0), "Updated: Minor 2.0 - 3.9 now 5100, was 5000; Strong 6.0 - 6.9 now 4, was 3"; got != want {
+		t.Fatalf("unexpected correction without threshold:\nwant %q\ngot  %q", want, got)
+	}
+}
+
+func TestPostCorrectionsRepliesWhenACategoryIsRevised(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	setNow(t, time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	events := []Earthquake{
+		{Time: time.Date(2026, 5, 30, 0, 0, 0, 0, time.UTC), Magnitude: 3.0},
+		{Time: time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC), Magnitude: 6.1},
+		{Time: time.Date(2026, 6, 3, 0, 0, 0, 0, time.UTC), Magnitude: 5.9},
+	}
+	weeks := groupByWeek(events)
+	root := &atproto.RepoStrongRef{Uri: "at://did:plc:bot/app.bsky.feed.post/root", Cid: "rootcid"}
+	posted := PostedWeek{Root: root, Categories: newReport("2026-W23", weeks["2026-W23"]).Categories}
+	if err := store.SavePostedWeek("2026-W23", posted); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged counts post nothing
+	if n, err := postCorrections(context.Background(), store, weeks, events); n != 0 || err != nil {
+		t.Fatalf("expected no correction, got %d (err %v)", n, err)
+	}
+
+	// USGS revises the M5.9 to M6.0
+	events[2].Magnitude = 6.0
+	weeks = groupByWeek(events)
+	if n, err := postCorrections(context.Background(), store, weeks, events); n != 1 || err != nil {
+		t.Fatalf("expected one correction, got %d (err %v)", n, err)
+	}
+	created := pds.calls("com.atproto.repo.createRecord")
+	if len(created) != 1 {
+		t.Fatalf("expected one reply, got %d posts", len(created))
+	}
+	record := created[0]["record"].(map[string]any)
+	if text := record["text"]; text != "Updated: Moderate 5.0 - 5.9 now 0, was 1; Strong 6.0 - 6.9 now 2, was 1" {
+		t.Fatalf("unexpected correction text %q", text)
+	}
+	reply := record["reply"].(map[string]any)
+	if reply["root"].(map[string]any)["uri"] != root.Uri || reply["parent"].(map[string]any)["uri"] != root.Uri {
+		t.Fatalf("expected a reply to the original post, got %v", reply)
+	}
+
+	// The corrected counts are stored, so the next run does not repeat it
+	if n, _ := postCorrections(context.Background(), store, weeks, events); n != 0 {
+		t.Fatalf("expected the correction to be posted once, got %d", n)
+	}
+
+	// Without events before the week, the feed may miss part of it
+	events[1].Magnitude = 5.5
+	if n, _ := postCorrections(context.Background(), store, groupByWeek(events[1:]), events[1:]); n != 0 {
+		t.Fatalf("expected a partly covered week to be skipped, got %d", n)
+	}
+}
+
+func TestRunPostsCorrectionsWithIncrementalWeeks(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	mag := "5.9"
+	header := "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n"
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, header+
+			"2026-05-30T10:00:00Z,1,2,10,3.0,ml,,,,,us,a,,Place A,earthquake,reviewed\n"+
+			"2026-06-02T10:00:00Z,1,2,10,6.1,mww,,,,,us,b,,Place B,earthquake,reviewed\n"+
+			"2026-06-03T10:00:00Z,1,2,10,"+mag+",mww,,,,,us,c,,Place C,earthquake,reviewed\n")
+	}))
+	t.Cleanup(feed.Close)
+	t.Setenv("USGS_FEED_URL", feed.URL)
+	t.Setenv("MIN_WEEKLY_EVENTS", "1")
+	t.Setenv("INCREMENTAL_WEEKS", "true")
+	t.Setenv("POST_CORRECTIONS", "true")
+	setNow(t, time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	if summary := run(context.Background(), store, runConfig{format: "text"}); summary.WeekPosted == nil || *summary.WeekPosted != "2026-W23" {
+		t.Fatalf("expected 2026-W23 to be posted, got %+v", summary)
+	}
+
+	// USGS revises the M5.9 to M6.0 after the week was posted
+	mag = "6.0"
+	setNow(t, time.Date(2026, 6, 10, 13, 0, 0, 0, time.UTC))
+	run(context.Background(), store, runConfig{format: "text"})
+
+	for _, call := range pds.calls("com.atproto.repo.createRecord") {
+		text := call["record"].(map[string]any)["text"].(string)
+		if strings.HasPrefix(text, "Updated: ") {
+			if text != "Updated: Moderate 5.0 - 5.9 now 0, was 1; Strong 6.0 - 6.9 now 2, was 1" {
+				t.Fatalf("unexpected correction text %q", text)
+			}
+			return
+		}
+	}
+	t.Fatal("expected a correction of the posted week")
+}
@@ -0,0 +1,80 @@
+package main
+
+import "math"
+
+const (
+	// Fewer depth and magnitude pairs give a correlation too noisy to mention
+	minCorrelationPairs = 30
+	// Weaker correlations are not worth a line in the report
+	minNotableCorrelation = 0.3
+)
+
+// DepthCorrelation is the Pearson correlation between depth and magnitude
+type DepthCorrelation struct {
+	R     float64 `json:"r"`
+	Pairs int     `json:"pairs"`
+}
+
+// Pearson correlation coefficient of xs and ys. Pairs where either value is
+// NaN are left out. Returns NaN with fewer than two pairs or without variance.
+func correlation(xs, ys []float64) float64 {
+	var n, sumX, sumY float64
+	for i := range min(len(xs), len(ys)) {
+		if math.IsNaN(xs[i]) || math.IsNaN(ys[i]) {
+			continue
+		}
+		n++
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	if n < 2 {
+		return math.NaN()
+	}
+
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX, varY float64
+	for i := range min(len(xs), len(ys)) {
+		if math.IsNaN(xs[i]) || math.IsNaN(ys[i]) {
+			continue
+		}
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// Correlation between depth and magnitude of the events, nil when too few
+// events have a depth
+func depthCorrelation(events []Earthquake) *DepthCorrelation {
+	depths := make([]float64, len(events))
+	mags := make([]float64, len(events))
+	pairs := 0
+	for i, eq := range events {
+		depths[i], mags[i] = eq.Depth, eq.Magnitude
+		if !math.IsNaN(eq.Depth) {
+			pairs++
+		}
+	}
+
+	r := correlation(depths, mags)
+	if pairs < minCorrelationPairs || math.IsNaN(r) {
+		return nil
+	}
+	return &DepthCorrelation{R: r, Pairs: pairs}
+}
+
+// One-line interpretation of the correlation, empty when it is too weak to mention
+func (c *DepthCorrelation) Interpretation() string {
+	if c == nil || math.Abs(c.R) < minNotableCorrelation {
+		return ""
+	}
+	if c.R > 0 {
+		return "Deeper quakes tended to be stronger this week"
+	}
+	return "Shallower quakes tended to be stronger this week"
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCorrelationKnownInputs(t *testing.T) {
+	tests := []struct {
+		name   string
+		xs, ys []float64
+		want   float64
+	}{
+		{"perfect positive", []float64{1, 2, 3, 4}, []float64{2, 4, 6, 8}, 1},
+		{"perfect negative", []float64{1, 2, 3, 4}, []float64{8, 6, 4, 2}, -1},
+		{"textbook", []float64{1, 2, 3, 4, 5}, []float64{2, 4, 5, 4, 5}, 0.7745966692414834},
+		{"NaN pairs excluded", []float64{1, math.NaN(), 2, 3, 4}, []float64{2, 100, 4, 6, math.NaN()}, 1},
+	}
+	for _, tt := range tests {
+		if got := correlation(tt.xs, tt.ys); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected r = %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if got := correlation([]float64{1, 1, 1}, []float64{1, 2, 3}); !math.IsNaN(got) {
+		t.Errorf("expected NaN without variance, got %v", got)
+	}
+}
+
+func TestDepthCorrelationNeedsEnoughEvents(t *testing.T) {
+	var events []Earthquake
+	for i := range minCorrelationPairs {
+		events = append(events, Earthquake{Depth: float64(10 + 10*i), Magnitude: 2 + float64(i%5)/2 + float64(i)/20})
+	}
+	events = append(events, Earthquake{Depth: math.NaN(), Magnitude: 7})
+
+	c := depthCorrelation(events)
+	if c == nil || c.Pairs != minCorrelationPairs || c.R < minNotableCorrelation {
+		t.Fatalf("expected a notable positive correlation over %d pairs, got %+v", minCorrelationPairs, c)
+	}
+	text := renderDetailed(Report{Categories: newReport("", WeekStats{}).Categories, DepthCorrelation: c})
+	if !strings.Contains(text, "Deeper quakes tended to be stronger this week (depth vs. magnitude r = ") {
+		t.Fatalf("expected correlation line, got:\n%s", text)
+	}
+
+	if c := depthCorrelation(events[:minCorrelationPairs-1]); c != nil {
+		t.Fatalf("expected no correlation for too few events, got %+v", c)
+	}
+	if (&DepthCorrelation{R: 0.1, Pairs: 100}).Interpretation() != "" {
+		t.Fatal("expected no interpretation for a weak correlation")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Number of recent weeks listed on the dashboard
+const dashboardWeeks = 8
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Earthquake report status</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40rem; margin: 2rem auto; padding: 0 1rem; }
+pre { background: #f4f4f4; padding: 1rem; white-space: pre-wrap; }
+td, th { padding: 0.2rem 0.8rem 0.2rem 0; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Earthquake report status</h1>
+<p>Last successful run: {{if .LastRun.IsZero}}never{{else}}{{.LastRun.Format "2006-01-02 15:04 MST"}}{{end}}</p>
+{{with .Latest}}
+<h2>Latest report: {{.WeekKey}}</h2>
+<pre>{{$.LatestText}}</pre>
+<p><a href="/latest.json">JSON</a></p>
+{{else}}
+<p>No weeks stored yet.</p>
+{{end}}
+{{if .Weeks}}
+<h2>Recent weeks</h2>
+<table>
+<tr><th>Week</th><th>Events</th><th>Largest</th><th>Posted</th></tr>
+{{range .Weeks}}<tr><td><a href="/weeks/{{.WeekKey}}.json">{{.WeekKey}}</a></td><td>{{.Total}}</td><td>{{.Largest}}</td><td>{{if .Posted}}yes{{else}}no{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// dashboardWeek is one row of the recent weeks table
+type dashboardWeek struct {
+	WeekKey string
+	Total   string
+	Largest string
+	Posted  bool
+}
+
+type dashboardData struct {
+	LastRun    time.Time
+	Latest     *Report
+	LatestText string
+	Weeks      []dashboardWeek
+}
+
+// Render the status page for human viewers: the last successful run, the
+// report of the latest stored week as it would be posted, and the recent
+// weeks with links to their JSON reports
+func serveDashboard(store *Store, w http.ResponseWriter) {
+	var data dashboardData
+	lastRun, _, err := store.LoadHeartbeat()
+	if err != nil {
+		http.Error(w, "failed to read the last run", http.StatusInternalServerError)
+		return
+	}
+	data.LastRun = lastRun
+
+	weeks, err := store.AllWeekStats()
+	if err != nil {
+		http.Error(w, "failed to read stored weeks", http.StatusInternalServerError)
+		return
+	}
+	weekKeys := make([]string, 0, len(weeks))
+	for weekKey := range weeks {
+		weekKeys = append(weekKeys, weekKey)
+	}
+	sortWeekKeys(weekKeys)
+	slices.Reverse(weekKeys)
+
+	if len(weekKeys) > 0 {
+		latest := buildReport(store, weekKeys[0], weeks[weekKeys[0]])
+		data.Latest = &latest
+		data.LatestText = renderText(latest)
+	}
+	for _, weekKey := range weekKeys[:min(len(weekKeys), dashboardWeeks)] {
+		stats := weeks[weekKey]
+		row := dashboardWeek{WeekKey: weekKey, Total: formatCount(stats.ReportedTotal()), Posted: store.WasWeekPosted(weekKey)}
+		if stats.Total() > 0 {
+			row.Largest = "M" + formatMag(stats.Largest.Magnitude)
+		}
+		data.Weeks = append(data.Weeks, row)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = dashboardTemplate.Execute(w, data)
+}
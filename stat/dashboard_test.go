@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardShowsLatestWeek(t *testing.T) {
+	store := openTestStore(t)
+	server := httptest.NewServer(newServer(store))
+	t.Cleanup(server.Close)
+
+	get := func() string {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/")
+		if err != nil {
+			t.Fatalf("GET / failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+			t.Fatalf("unexpected response %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return string(body)
+	}
+
+	if page := get(); !strings.Contains(page, "No weeks stored yet") || !strings.Contains(page, "never") {
+		t.Fatalf("unexpected empty dashboard:\n%s", page)
+	}
+
+	for _, weekKey := range []string{"2026-W22", "2026-W23"} {
+		store.StoreWeekStats(weekKey, WeekStats{Counts: [7]int{0, 1, 0, 0, 0, 0, 0}, MagnitudeSum: 2.5,
+			StartDate: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)})
+	}
+	store.MarkWeekPosted("2026-W23")
+
+	page := get()
+	for _, want := range []string{"Latest report: 2026-W23", `href="/weeks/2026-W22.json"`, `href="/weeks/2026-W23.json"`} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("dashboard is missing %q:\n%s", want, page)
+		}
+	}
+	if strings.Index(page, "/weeks/2026-W23.json") > strings.Index(page, "/weeks/2026-W22.json") {
+		t.Fatalf("expected the most recent week first:\n%s", page)
+	}
+
+	if resp, err := http.Get(server.URL + "/missing"); err != nil {
+		t.Fatalf("GET /missing failed: %v", err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown pages, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Keys of posted weeks, e.g. "2026-W23"
+var weekKeyPattern = regexp.MustCompile(`^\d{4}-W\d{2}$`)
+
+// dbStats summarizes the keys and the size of a Pebble database
+type dbStats struct {
+	// Posted marks of weeks
+	WeekKeys int
+	// Stored WeekStats under statsKeyPrefix
+	StatsKeys int
+	// Unprefixed keys that are not weeks, the event IDs the post command
+	// marks as alerted
+	AlertKeys int
+	// Other keys by prefix, e.g. "draft:"
+	Prefixes   map[string]int
+	OldestWeek string
+	NewestWeek string
+	DiskBytes  uint64
+}
+
+// Walk every key of the database once and read its size from Pebble's
+// metrics. Works on the post command's database too, whose alert keys are
+// the USGS event IDs.
+func collectDBStats(s *Store) (dbStats, error) {
+	stats := dbStats{Prefixes: make(map[string]int)}
+	iter, err := s.db.NewIter(&pebble.IterOptions{})
+	if err != nil {
+		return stats, err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		key := string(iter.Key())
+		week := ""
+		switch {
+		case weekKeyPattern.MatchString(key):
+			stats.WeekKeys++
+			week = key
+		case strings.HasPrefix(key, statsKeyPrefix):
+			stats.StatsKeys++
+			week = strings.TrimPrefix(key, statsKeyPrefix)
+		case strings.Contains(key, ":"):
+			prefix, _, _ := strings.Cut(key, ":")
+			stats.Prefixes[prefix+":"]++
+		case key == outageKey || key == heartbeatKey:
+			stats.Prefixes[key]++
+		default:
+			stats.AlertKeys++
+		}
+		if week == "" {
+			continue
+		}
+		// Week keys sort chronologically
+		if stats.OldestWeek == "" || week < stats.OldestWeek {
+			stats.OldestWeek = week
+		}
+		if week > stats.NewestWeek {
+			stats.NewestWeek = week
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return stats, err
+	}
+
+	stats.DiskBytes = s.db.Metrics().DiskSpaceUsage()
+	return stats, nil
+}
+
+func writeDBStats(w io.Writer, path string, stats dbStats) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Database: %s\n", path)
+	fmt.Fprintf(&b, "Posted weeks: %s\n", formatThousands(stats.WeekKeys))
+	fmt.Fprintf(&b, "Week stats: %s\n", formatThousands(stats.StatsKeys))
+	fmt.Fprintf(&b, "Quake alerts: %s\n", formatThousands(stats.AlertKeys))
+	if stats.NewestWeek != "" {
+		fmt.Fprintf(&b, "Weeks: %s to %s\n", stats.OldestWeek, stats.NewestWeek)
+	}
+
+	prefixes := make([]string, 0, len(stats.Prefixes))
+	for prefix := range stats.Prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		fmt.Fprintf(&b, "%s %s\n", prefix, formatThousands(stats.Prefixes[prefix]))
+	}
+
+	fmt.Fprintf(&b, "Disk size: %.1f MB\n", float64(stats.DiskBytes)/(1<<20))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Print the stats of the database at path. Missing databases are an error
+// instead of being created empty.
+func printDBStats(w io.Writer, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	store, err := openStore(path, false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	stats, err := collectDBStats(store)
+	if err != nil {
+		return err
+	}
+	return writeDBStats(w, path, stats)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+)
+
+func TestCollectDBStats(t *testing.T) {
+	store := openTestStore(t)
+	store.MarkWeekPosted("2026-W22")
+	store.MarkWeekPosted("2026-W23")
+	store.StoreWeekStats("2025-W50", WeekStats{})
+	store.StoreWeekStats("2026-W23", WeekStats{})
+	store.MarkInterimPosted("2026-W24")
+	if err := store.SaveDraft("2026-W24", []string{"draft"}); err != nil {
+		t.Fatalf("SaveDraft returned error: %v", err)
+	}
+	if err := store.SaveHeartbeat(time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("SaveHeartbeat returned error: %v", err)
+	}
+	// Alert marks as the post command writes them
+	for _, id := range []string{"us7000abcd", "ci40123456", "nc75012345"} {
+		if err := store.db.Set([]byte(id), []byte("4.6"), pebble.Sync); err != nil {
+			t.Fatalf("failed to seed alert key: %v", err)
+		}
+	}
+
+	stats, err := collectDBStats(store)
+	if err != nil {
+		t.Fatalf("collectDBStats returned error: %v", err)
+	}
+	if stats.WeekKeys != 2 || stats.StatsKeys != 2 || stats.AlertKeys != 3 {
+		t.Fatalf("unexpected key counts %+v", stats)
+	}
+	if stats.OldestWeek != "2025-W50" || stats.NewestWeek != "2026-W23" {
+		t.Fatalf("expected weeks 2025-W50 to 2026-W23, got %s to %s", stats.OldestWeek, stats.NewestWeek)
+	}
+	if stats.Prefixes["interim:"] != 1 || stats.Prefixes["draft:"] != 1 || stats.Prefixes["heartbeat"] != 1 {
+		t.Fatalf("unexpected prefix counts %v", stats.Prefixes)
+	}
+
+	var out bytes.Buffer
+	if err := writeDBStats(&out, "db", stats); err != nil {
+		t.Fatalf("writeDBStats returned error: %v", err)
+	}
+	for _, want := range []string{"Posted weeks: 2\n", "Quake alerts: 3\n", "Weeks: 2025-W50 to 2026-W23\n", "draft: 1\n", "Disk size: "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in the output, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPrintDBStatsRejectsMissingDatabase(t *testing.T) {
+	if err := printDBStats(&bytes.Buffer{}, t.TempDir()+"/missing"); err == nil {
+		t.Fatal("expected an error for a missing database")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Post the draft of a week, mark the week as posted and delete the draft
+func publishDraft(ctx context.Context, store *Store, weekKey string) error {
+	if store.WasWeekPosted(weekKey) {
+		return fmt.Errorf("week %s was already posted", weekKey)
+	}
+
+	texts, err := store.LoadDraft(weekKey)
+	if err != nil {
+		return err
+	}
+	if _, err := postThread(ctx, store, weekKey, texts); err != nil {
+		return fmt.Errorf("failed to post draft: %w", err)
+	}
+
+	store.MarkWeekPosted(weekKey)
+	return store.DeleteDraft(weekKey)
+}
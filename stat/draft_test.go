@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSaveAndPublishDraft(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+
+	if err := publishDraft(context.Background(), store, "2026-W23"); err == nil {
+		t.Fatal("expected an error when publishing a missing draft")
+	}
+
+	if err := store.SaveDraft("2026-W23", []string{"report", "reply"}); err != nil {
+		t.Fatalf("SaveDraft returned error: %v", err)
+	}
+	if store.WasWeekPosted("2026-W23") {
+		t.Fatal("expected saving a draft not to mark the week as posted")
+	}
+
+	if err := publishDraft(context.Background(), store, "2026-W23"); err != nil {
+		t.Fatalf("publishDraft returned error: %v", err)
+	}
+
+	created := pds.calls("com.atproto.repo.createRecord")
+	if len(created) != 2 {
+		t.Fatalf("expected the draft thread of 2 posts, got %d", len(created))
+	}
+	if text := created[0]["record"].(map[string]any)["text"]; text != "report" {
+		t.Fatalf("expected draft text to be posted, got %v", text)
+	}
+	if !store.WasWeekPosted("2026-W23") {
+		t.Fatal("expected the week to be marked as posted")
+	}
+	if _, err := store.LoadDraft("2026-W23"); err == nil {
+		t.Fatal("expected the draft to be deleted after publishing")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Write the events of the feeds as newline-delimited JSON, one Earthquake per
+// line in feed order. Rows are written while the feed is read, so large feeds
+// are not held in memory; only the IDs are kept to drop duplicates across
+// feeds like fetchFeeds does. Returns the number of events written.
+func dumpEvents(ctx context.Context, w io.Writer, urls []string) (int, error) {
+	encoder := json.NewEncoder(w)
+	seen := make(map[string]bool)
+	written := 0
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		body, err := openFeed(ctx, url)
+		if err != nil {
+			return written, fmt.Errorf("fetching %s: %w", url, err)
+		}
+
+		quality := newDataQuality()
+		err = scanFeed(url, body, &quality, func(eq Earthquake) error {
+			if eq.ID != "" {
+				if seen[eq.ID] {
+					return nil
+				}
+				seen[eq.ID] = true
+			}
+			written++
+			return encoder.Encode(eq)
+		})
+		body.Close()
+		if err != nil {
+			return written, fmt.Errorf("reading %s: %w", url, err)
+		}
+	}
+	return written, nil
+}
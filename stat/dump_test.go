@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpEventsWritesNDJSON(t *testing.T) {
+	feed := filepath.Join("testdata", "all_week.csv")
+	var out bytes.Buffer
+	// The same feed twice: duplicate IDs are written once
+	written, err := dumpEvents(context.Background(), &out, []string{feed, feed})
+	if err != nil {
+		t.Fatalf("dumpEvents returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if written != 3 || len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d (written %d):\n%s", len(lines), written, out.String())
+	}
+
+	want := `{"id":"us7000a1b2","time":"2026-06-07T23:10:05.12Z","magnitude":5.8,"place":"76 km E of Ishinomaki, Japan","status":"reviewed","network":"us","magType":"mww","latitude":38.297,"longitude":142.373,"depth":29}`
+	if lines[0] != want {
+		t.Fatalf("unexpected first line:\nwant %s\ngot  %s", want, lines[0])
+	}
+
+	var eq Earthquake
+	if err := json.Unmarshal([]byte(lines[1]), &eq); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if eq.ID != "ak0265abc" || eq.Magnitude != 2.4 || eq.Network != "ak" || eq.Status != "automatic" {
+		t.Fatalf("unexpected second event %+v", eq)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/cockroachdb/pebble"
+)
+
+// Notifications requested per page
+const notificationPageSize = 50
+
+// Check if a notification was handled by an earlier engagement pass
+func (s *Store) WasNotificationProcessed(uri string) bool {
+	return s.WasWeekPosted(engagedKeyPrefix + uri)
+}
+
+func (s *Store) MarkNotificationProcessed(uri string) {
+	s.MarkWeekPosted(engagedKeyPrefix + uri)
+}
+
+// Key of the index time of the newest notification seen by the engagement pass
+const engageSeenKey = engagedKeyPrefix + "seenAt"
+
+// Record the index time of the newest notification seen by the engagement pass
+func (s *Store) SaveEngageSeen(t time.Time) error {
+	return s.db.Set([]byte(engageSeenKey), []byte(t.UTC().Format(time.RFC3339Nano)), pebble.Sync)
+}
+
+// Load the index time of the newest notification seen, false before the
+// first engagement pass
+func (s *Store) LoadEngageSeen() (time.Time, bool, error) {
+	value, closer, err := s.db.Get([]byte(engageSeenKey))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer closer.Close()
+
+	t, err := time.Parse(time.RFC3339Nano, string(value))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to decode engagement cursor: %w", err)
+	}
+	return t, true, nil
+}
+
+// Follow back the accounts that followed the bot since the last pass. The
+// first pass only records the current time, so the follows from before the
+// bot engaged are not followed back all at once. Pages of notifications are
+// read, newest first, until a notification indexed before the last pass.
+// Accounts the bot already follows are skipped. Returns the number of
+// accounts followed.
+func engage(ctx context.Context, store *Store) (int, error) {
+	client, err := login(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+
+	seenAt, ok, err := store.LoadEngageSeen()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		fmt.Println("First engagement pass, only accounts that follow from now on are followed back")
+		return 0, store.SaveEngageSeen(now())
+	}
+
+	followed := 0
+	newest := seenAt
+	cursor := ""
+	for {
+		out, err := bsky.NotificationListNotifications(ctx, client, cursor, notificationPageSize, false, []string{"follow"}, "")
+		if err != nil {
+			return followed, fmt.Errorf("failed to list notifications: %w", err)
+		}
+
+		seen := false
+		for _, notification := range out.Notifications {
+			indexedAt, err := time.Parse(time.RFC3339, notification.IndexedAt)
+			if err != nil || !indexedAt.After(seenAt) {
+				seen = true
+				continue
+			}
+			if indexedAt.After(newest) {
+				newest = indexedAt
+			}
+			if notification.Reason != "follow" || notification.Author == nil || store.WasNotificationProcessed(notification.Uri) {
+				continue
+			}
+			if viewer := notification.Author.Viewer; viewer == nil || viewer.Following == nil {
+				if err := follow(ctx, client, notification.Author.Did); err != nil {
+					return followed, fmt.Errorf("failed to follow %s: %w", notification.Author.Handle, err)
+				}
+				fmt.Printf("Followed back %s\n", notification.Author.Handle)
+				followed++
+			}
+			store.MarkNotificationProcessed(notification.Uri)
+		}
+
+		if seen || out.Cursor == nil || *out.Cursor == "" {
+			return followed, store.SaveEngageSeen(newest)
+		}
+		cursor = *out.Cursor
+	}
+}
+
+// Create a follow record for an account
+func follow(ctx context.Context, client *xrpc.Client, did string) error {
+	_, err := atproto.RepoCreateRecord(ctx, client, &atproto.RepoCreateRecord_Input{
+		Repo:       client.Auth.Did,
+		Collection: "app.bsky.graph.follow",
+		Record: &util.LexiconTypeDecoder{Val: &bsky.GraphFollow{
+			LexiconTypeID: "app.bsky.graph.follow",
+			CreatedAt:     now().Format(time.RFC3339),
+			Subject:       did,
+		}},
+	})
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEngageFollowsBackNewFollowers(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	setNow(t, time.Date(2026, 6, 10, 9, 0, 0, 0, time.UTC))
+	notification := func(uri, did, handle, indexedAt string) map[string]any {
+		return map[string]any{
+			"uri": uri, "cid": "cid", "reason": "follow", "isRead": false, "indexedAt": indexedAt,
+			"author": map[string]any{"did": did, "handle": handle},
+			"record": map[string]any{"$type": "app.bsky.graph.follow", "subject": "did:plc:bot", "createdAt": indexedAt},
+		}
+	}
+	followedBack := notification("at://did:plc:cat/app.bsky.graph.follow/1", "did:plc:cat", "cat.example.com", "2026-06-10T10:00:00Z")
+	followedBack["author"].(map[string]any)["viewer"] = map[string]any{"following": "at://did:plc:bot/app.bsky.graph.follow/1"}
+	pds.notifications = [][]map[string]any{
+		{notification("at://did:plc:ann/app.bsky.graph.follow/1", "did:plc:ann", "ann.example.com", "2026-06-10T10:00:00Z"), followedBack},
+		{
+			notification("at://did:plc:bob/app.bsky.graph.follow/1", "did:plc:bob", "bob.example.com", "2026-06-10T10:00:00Z"),
+			notification("at://did:plc:zed/app.bsky.graph.follow/1", "did:plc:zed", "zed.example.com", "2026-06-10T08:00:00Z"),
+		},
+	}
+
+	// The first pass only starts the cursor, the existing followers are not
+	// followed back
+	if followed, err := engage(context.Background(), store); err != nil || followed != 0 {
+		t.Fatalf("expected the first pass to follow nobody, got %d (err %v)", followed, err)
+	}
+	if lists := pds.calls("app.bsky.notification.listNotifications"); len(lists) != 0 {
+		t.Fatalf("expected the first pass not to read notifications, got %d requests", len(lists))
+	}
+
+	followed, err := engage(context.Background(), store)
+	if err != nil || followed != 2 {
+		t.Fatalf("expected 2 follows, got %d (err %v)", followed, err)
+	}
+	created := pds.calls("com.atproto.repo.createRecord")
+	if len(created) != 2 {
+		t.Fatalf("expected 2 follow records, got %d", len(created))
+	}
+	for i, did := range []string{"did:plc:ann", "did:plc:bob"} {
+		record := created[i]["record"].(map[string]any)
+		if created[i]["collection"] != "app.bsky.graph.follow" || record["subject"] != did {
+			t.Fatalf("unexpected follow record %v", created[i])
+		}
+	}
+
+	// Only notifications newer than the last pass are read
+	pds.notifications[0] = append([]map[string]any{
+		notification("at://did:plc:cy/app.bsky.graph.follow/1", "did:plc:cy", "cy.example.com", "2026-06-10T11:00:00Z"),
+	}, pds.notifications[0]...)
+	if followed, err := engage(context.Background(), store); err != nil || followed != 1 {
+		t.Fatalf("expected only the new follower, got %d (err %v)", followed, err)
+	}
+	if lists := pds.calls("app.bsky.notification.listNotifications"); len(lists) != 3 {
+		t.Fatalf("expected the third pass to stop after the first page, got %d requests in total", len(lists))
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default number of "Did You Feel It?" reports from which an event counts as
+// widely felt
+const defaultFeltThreshold = 100
+
+// Number of widely felt events listed in the report
+const maxFeltEvents = 3
+
+// FeltEvent is an event with many felt reports, whatever its magnitude
+type FeltEvent struct {
+	Event Earthquake `json:"event"`
+	Felt  int        `json:"felt"`
+}
+
+// Events with more than threshold felt reports, most reports first and then
+// by the shown place and ID. Only GeoJSON feeds have felt reports, with CSV feeds this
+// is always empty.
+func widelyFelt(events []Earthquake, threshold int) []FeltEvent {
+	var felt []FeltEvent
+	for _, eq := range events {
+		if eq.Felt > threshold {
+			felt = append(felt, FeltEvent{Event: eq, Felt: eq.Felt})
+		}
+	}
+	sort.Slice(felt, func(i, j int) bool {
+		if felt[i].Felt != felt[j].Felt {
+			return felt[i].Felt > felt[j].Felt
+		}
+		if a, b := shortPlace(felt[i].Event.Place), shortPlace(felt[j].Event.Place); a != b {
+			return a < b
+		}
+		return felt[i].Event.ID < felt[j].Event.ID
+	})
+	return felt
+}
+
+// "Widely felt: M3.4 near Berkeley, CA (2,130 reports, MMI 5), ..." with the
+// events with the most reports. Empty without widely felt events.
+func feltLine(felt []FeltEvent) string {
+	if len(felt) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, maxFeltEvents)
+	for _, f := range felt[:min(len(felt), maxFeltEvents)] {
+		part := fmt.Sprintf("M%s near %s (%s reports", formatMag(f.Event.Magnitude), shortPlace(f.Event.Place), formatCount(f.Felt))
+		if f.Event.MMI > 0 {
+			part += fmt.Sprintf(", MMI %.0f", f.Event.MMI)
+		}
+		parts = append(parts, part+")")
+	}
+	line := "Widely felt: " + strings.Join(parts, ", ")
+	if more := len(felt) - maxFeltEvents; more > 0 {
+		line += fmt.Sprintf(" and %d more", more)
+	}
+	return line
+}
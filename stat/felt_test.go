@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWidelyFeltSelectsAcrossMagnitudes(t *testing.T) {
+	events := []Earthquake{
+		{ID: "micro", Magnitude: 1.8, Place: "2 km N of Hollister, CA", Felt: 150},
+		{ID: "minor", Magnitude: 3.2, Place: "5 km SE of Berkeley, CA", Felt: 2130, MMI: 4.6},
+		{ID: "light", Magnitude: 4.4, Place: "Tokyo, Japan", Felt: 100},
+		{ID: "strong", Magnitude: 6.4, Place: "Off the coast of Oregon", Felt: 12},
+		{ID: "moderate", Magnitude: 5.1, Place: "Central Italy", Felt: 870},
+		{ID: "unknown", Magnitude: 5.5, Place: "South Sandwich Islands"},
+	}
+
+	felt := widelyFelt(events, 100)
+	var ids []string
+	for _, f := range felt {
+		ids = append(ids, f.Event.ID)
+	}
+	if want := []string{"minor", "moderate", "micro"}; len(ids) != len(want) || ids[0] != want[0] || ids[1] != want[1] || ids[2] != want[2] {
+		t.Fatalf("widelyFelt = %v, want %v", ids, want)
+	}
+
+	if line := feltLine(felt); !strings.HasPrefix(line, "Widely felt: M3.2 near Berkeley, CA (2130 reports") {
+		t.Fatalf("expected ungrouped digits without GROUP_DIGITS, got %q", line)
+	}
+	t.Setenv("GROUP_DIGITS", "true")
+	want := "Widely felt: M3.2 near Berkeley, CA (2,130 reports, MMI 5), M5.1 near Central Italy (870 reports), M1.8 near Hollister, CA (150 reports)"
+	if line := feltLine(felt); line != want {
+		t.Fatalf("feltLine = %q, want %q", line, want)
+	}
+
+	if line := feltLine(widelyFelt(events, 10)); line != want+" and 2 more" {
+		t.Fatalf("expected the remaining events to be counted, got %q", line)
+	}
+	if line := feltLine(nil); line != "" {
+		t.Fatalf("expected no line without felt events, got %q", line)
+	}
+}
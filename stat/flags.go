@@ -0,0 +1,270 @@
+package main
+
+// ISO 3166 codes of the countries and territories that end USGS place names,
+// as returned by regionOf. US places end with a state name or abbreviation.
+var regionCountryCodes = map[string]string{
+	"Afghanistan":                      "AF",
+	"Albania":                          "AL",
+	"Algeria":                          "DZ",
+	"American Samoa":                   "AS",
+	"Argentina":                        "AR",
+	"Armenia":                          "AM",
+	"Australia":                        "AU",
+	"Austria":                          "AT",
+	"Azerbaijan":                       "AZ",
+	"Bangladesh":                       "BD",
+	"Bhutan":                           "BT",
+	"Bolivia":                          "BO",
+	"Bosnia and Herzegovina":           "BA",
+	"Brazil":                           "BR",
+	"Bulgaria":                         "BG",
+	"Burma":                            "MM",
+	"Burma (Myanmar)":                  "MM",
+	"Myanmar":                          "MM",
+	"Cabo Verde":                       "CV",
+	"Cameroon":                         "CM",
+	"Canada":                           "CA",
+	"Cayman Islands":                   "KY",
+	"Chile":                            "CL",
+	"China":                            "CN",
+	"Colombia":                         "CO",
+	"Comoros":                          "KM",
+	"Costa Rica":                       "CR",
+	"Croatia":                          "HR",
+	"Cuba":                             "CU",
+	"Cyprus":                           "CY",
+	"Democratic Republic of the Congo": "CD",
+	"Djibouti":                         "DJ",
+	"Dominica":                         "DM",
+	"Dominican Republic":               "DO",
+	"Ecuador":                          "EC",
+	"Egypt":                            "EG",
+	"El Salvador":                      "SV",
+	"Eritrea":                          "ER",
+	"Ethiopia":                         "ET",
+	"Fiji":                             "FJ",
+	"France":                           "FR",
+	"French Polynesia":                 "PF",
+	"Germany":                          "DE",
+	"Greece":                           "GR",
+	"Greenland":                        "GL",
+	"Guadeloupe":                       "GP",
+	"Guam":                             "GU",
+	"Guatemala":                        "GT",
+	"Haiti":                            "HT",
+	"Honduras":                         "HN",
+	"Iceland":                          "IS",
+	"India":                            "IN",
+	"Indonesia":                        "ID",
+	"Iran":                             "IR",
+	"Iraq":                             "IQ",
+	"Italy":                            "IT",
+	"Jamaica":                          "JM",
+	"Japan":                            "JP",
+	"Jordan":                           "JO",
+	"Kazakhstan":                       "KZ",
+	"Kenya":                            "KE",
+	"Kermadec Islands":                 "NZ",
+	"Kyrgyzstan":                       "KG",
+	"Laos":                             "LA",
+	"Lebanon":                          "LB",
+	"Madagascar":                       "MG",
+	"Malawi":                           "MW",
+	"Malaysia":                         "MY",
+	"Martinique":                       "MQ",
+	"Mauritius":                        "MU",
+	"Mexico":                           "MX",
+	"Micronesia":                       "FM",
+	"Mongolia":                         "MN",
+	"Montenegro":                       "ME",
+	"Morocco":                          "MA",
+	"Mozambique":                       "MZ",
+	"Nepal":                            "NP",
+	"New Caledonia":                    "NC",
+	"New Zealand":                      "NZ",
+	"Nicaragua":                        "NI",
+	"North Korea":                      "KP",
+	"North Macedonia":                  "MK",
+	"Northern Mariana Islands":         "MP",
+	"Norway":                           "NO",
+	"Oman":                             "OM",
+	"Pakistan":                         "PK",
+	"Palau":                            "PW",
+	"Panama":                           "PA",
+	"Papua New Guinea":                 "PG",
+	"Peru":                             "PE",
+	"Philippines":                      "PH",
+	"Portugal":                         "PT",
+	"Puerto Rico":                      "PR",
+	"Romania":                          "RO",
+	"Russia":                           "RU",
+	"Rwanda":                           "RW",
+	"Saint Lucia":                      "LC",
+	"Samoa":                            "WS",
+	"Saudi Arabia":                     "SA",
+	"Serbia":                           "RS",
+	"Slovenia":                         "SI",
+	"Solomon Islands":                  "SB",
+	"Somalia":                          "SO",
+	"South Africa":                     "ZA",
+	"South Georgia and the South Sandwich Islands": "GS",
+	"South Korea":            "KR",
+	"South Sudan":            "SS",
+	"Spain":                  "ES",
+	"Sri Lanka":              "LK",
+	"Sudan":                  "SD",
+	"Svalbard and Jan Mayen": "SJ",
+	"Switzerland":            "CH",
+	"Syria":                  "SY",
+	"Taiwan":                 "TW",
+	"Tajikistan":             "TJ",
+	"Tanzania":               "TZ",
+	"Thailand":               "TH",
+	"Timor Leste":            "TL",
+	"Timor-Leste":            "TL",
+	"Tonga":                  "TO",
+	"Trinidad and Tobago":    "TT",
+	"Tunisia":                "TN",
+	"Turkey":                 "TR",
+	"Turkmenistan":           "TM",
+	"Türkiye":                "TR",
+	"Uganda":                 "UG",
+	"Ukraine":                "UA",
+	"United Kingdom":         "GB",
+	"Uzbekistan":             "UZ",
+	"Vanuatu":                "VU",
+	"Venezuela":              "VE",
+	"Vietnam":                "VN",
+	"Wallis and Futuna":      "WF",
+	"Yemen":                  "YE",
+	"Zambia":                 "ZM",
+	"Zimbabwe":               "ZW",
+
+	// US states as names and as the abbreviations USGS uses. "Georgia" is left
+	// out, USGS uses it for both the state and the country.
+	"Alabama":              "US",
+	"AL":                   "US",
+	"Alaska":               "US",
+	"AK":                   "US",
+	"Arizona":              "US",
+	"AZ":                   "US",
+	"Arkansas":             "US",
+	"AR":                   "US",
+	"California":           "US",
+	"CA":                   "US",
+	"Colorado":             "US",
+	"CO":                   "US",
+	"Connecticut":          "US",
+	"CT":                   "US",
+	"Delaware":             "US",
+	"DE":                   "US",
+	"Florida":              "US",
+	"FL":                   "US",
+	"GA":                   "US",
+	"Hawaii":               "US",
+	"HI":                   "US",
+	"Idaho":                "US",
+	"ID":                   "US",
+	"Illinois":             "US",
+	"IL":                   "US",
+	"Indiana":              "US",
+	"IN":                   "US",
+	"Iowa":                 "US",
+	"IA":                   "US",
+	"Kansas":               "US",
+	"KS":                   "US",
+	"Kentucky":             "US",
+	"KY":                   "US",
+	"Louisiana":            "US",
+	"LA":                   "US",
+	"Maine":                "US",
+	"ME":                   "US",
+	"Maryland":             "US",
+	"MD":                   "US",
+	"Massachusetts":        "US",
+	"MA":                   "US",
+	"Michigan":             "US",
+	"MI":                   "US",
+	"Minnesota":            "US",
+	"MN":                   "US",
+	"Mississippi":          "US",
+	"MS":                   "US",
+	"Missouri":             "US",
+	"MO":                   "US",
+	"Montana":              "US",
+	"MT":                   "US",
+	"Nebraska":             "US",
+	"NE":                   "US",
+	"Nevada":               "US",
+	"NV":                   "US",
+	"New Hampshire":        "US",
+	"NH":                   "US",
+	"New Jersey":           "US",
+	"NJ":                   "US",
+	"New Mexico":           "US",
+	"NM":                   "US",
+	"New York":             "US",
+	"NY":                   "US",
+	"North Carolina":       "US",
+	"NC":                   "US",
+	"North Dakota":         "US",
+	"ND":                   "US",
+	"Ohio":                 "US",
+	"OH":                   "US",
+	"Oklahoma":             "US",
+	"OK":                   "US",
+	"Oregon":               "US",
+	"OR":                   "US",
+	"Pennsylvania":         "US",
+	"PA":                   "US",
+	"Rhode Island":         "US",
+	"RI":                   "US",
+	"South Carolina":       "US",
+	"SC":                   "US",
+	"South Dakota":         "US",
+	"SD":                   "US",
+	"Tennessee":            "US",
+	"TN":                   "US",
+	"Texas":                "US",
+	"TX":                   "US",
+	"Utah":                 "US",
+	"UT":                   "US",
+	"Vermont":              "US",
+	"VT":                   "US",
+	"Virginia":             "US",
+	"VA":                   "US",
+	"Washington":           "US",
+	"WA":                   "US",
+	"West Virginia":        "US",
+	"WV":                   "US",
+	"Wisconsin":            "US",
+	"WI":                   "US",
+	"Wyoming":              "US",
+	"WY":                   "US",
+	"District of Columbia": "US",
+	"DC":                   "US",
+	"U.S. Virgin Islands":  "VI",
+}
+
+// Flag emoji of a region such as "Japan", empty for regions that are not a
+// known country. A flag is the pair of regional indicator symbols of the
+// country code.
+func regionFlag(region string) string {
+	code, ok := regionCountryCodes[region]
+	if !ok {
+		return ""
+	}
+	flag := make([]rune, 0, 2)
+	for _, letter := range code {
+		flag = append(flag, '🇦'+letter-'A')
+	}
+	return string(flag)
+}
+
+// Region prefixed with its flag when REGION_FLAGS=true, e.g. "🇯🇵 Japan"
+func regionLabel(region string) string {
+	if flag := regionFlag(region); flag != "" && envBool("REGION_FLAGS") {
+		return flag + " " + region
+	}
+	return region
+}
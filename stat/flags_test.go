@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegionFlag(t *testing.T) {
+	tests := map[string]string{
+		"Japan":                     "🇯🇵",
+		"Indonesia":                 "🇮🇩",
+		"Chile":                     "🇨🇱",
+		"CA":                        "🇺🇸",
+		"Alaska":                    "🇺🇸",
+		"Puerto Rico":               "🇵🇷",
+		"Virginia":                  "🇺🇸",
+		"GA":                        "🇺🇸",
+		"Georgia":                   "",
+		"Mid-Atlantic Ridge":        "",
+		"South of the Fiji Islands": "",
+	}
+	for region, want := range tests {
+		if got := regionFlag(region); got != want {
+			t.Errorf("regionFlag(%q) = %q, want %q", region, got, want)
+		}
+	}
+}
+
+func TestRegionFlagsInReport(t *testing.T) {
+	report := Report{MostActive: &RegionActivity{Region: "Japan", Count: 42}}
+	if text := renderText(report); !strings.Contains(text, "Most active region: Japan (42)") {
+		t.Fatalf("expected no flag by default, got:\n%s", text)
+	}
+
+	t.Setenv("REGION_FLAGS", "true")
+	if text := renderText(report); !strings.Contains(text, "Most active region: 🇯🇵 Japan (42)") {
+		t.Fatalf("expected a flag, got:\n%s", text)
+	}
+	report.MostActive.Region = "Mid-Atlantic Ridge"
+	if text := renderText(report); !strings.Contains(text, "Most active region: Mid-Atlantic Ridge (42)") {
+		t.Fatalf("expected no flag for an unknown country, got:\n%s", text)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// A stretch without events is a possible data gap when it is this many times
+// the mean time between events, and at least minDataGap. Events arrive
+// roughly at random, so a quiet stretch of 20 mean gaps is very unlikely even
+// in a month of events.
+const (
+	dataGapFactor = 20
+	minDataGap    = 2 * time.Hour
+	// Sparse feeds, such as M4.5+ only, are too quiet to tell gaps from lulls
+	minDataGapEvents = 50
+)
+
+// DataGap is a stretch without any events that is implausibly long for the
+// feed's event rate, likely feed downtime rather than seismic quiet
+type DataGap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Find the possible data gaps between event times, oldest first
+func findDataGaps(times []time.Time) []DataGap {
+	if len(times) < minDataGapEvents {
+		return nil
+	}
+	sorted := slices.Clone(times)
+	slices.SortFunc(sorted, func(a, b time.Time) int { return a.Compare(b) })
+
+	// The mean, unlike the median, is not lowered by aftershock swarms
+	mean := sorted[len(sorted)-1].Sub(sorted[0]) / time.Duration(len(sorted)-1)
+	threshold := max(dataGapFactor*mean, minDataGap)
+
+	var gaps []DataGap
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Sub(sorted[i-1]) >= threshold {
+			gaps = append(gaps, DataGap{Start: sorted[i-1], End: sorted[i]})
+		}
+	}
+	return gaps
+}
+
+// Print a warning for each possible data gap
+func printDataGaps(w io.Writer, gaps []DataGap) {
+	for _, gap := range gaps {
+		fmt.Fprintf(w, "Warning: possible data gap detected, no events from %s to %s (%s)\n",
+			gap.Start.UTC().Format("2006-01-02 15:04 UTC"), gap.End.UTC().Format("2006-01-02 15:04 UTC"),
+			formatInterval(gap.End.Sub(gap.Start).Seconds()))
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindDataGaps(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	gapStart := start.Add(3 * 24 * time.Hour)
+	gapEnd := gapStart.Add(6 * time.Hour)
+
+	// An event every 5 minutes for a week, with 6 hours missing in the middle
+	var steady, withGap []time.Time
+	for at := start; at.Before(start.AddDate(0, 0, 7)); at = at.Add(5 * time.Minute) {
+		steady = append(steady, at)
+		if at.Compare(gapStart) <= 0 || at.Compare(gapEnd) >= 0 {
+			withGap = append(withGap, at)
+		}
+	}
+
+	if gaps := findDataGaps(steady); len(gaps) != 0 {
+		t.Fatalf("expected no gaps at a steady rate, got %v", gaps)
+	}
+	gaps := findDataGaps(withGap)
+	if len(gaps) != 1 || !gaps[0].Start.Equal(gapStart) || !gaps[0].End.Equal(gapEnd) {
+		t.Fatalf("expected the injected gap, got %v", gaps)
+	}
+
+	var out bytes.Buffer
+	printDataGaps(&out, gaps)
+	if !strings.Contains(out.String(), "possible data gap detected, no events from 2026-06-04 00:00 UTC to 2026-06-04 06:00 UTC (6h 00m)") {
+		t.Fatalf("unexpected warning %q", out.String())
+	}
+
+	// Too few events to tell a gap from a lull
+	if gaps := findDataGaps(withGap[:minDataGapEvents-1]); gaps != nil {
+		t.Fatalf("expected no gaps for a sparse feed, got %v", gaps)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+const earthRadiusKm = 6371.0
+
+// NearbyEvent is an earthquake with its distance to the home location
+type NearbyEvent struct {
+	Event      Earthquake `json:"event"`
+	DistanceKm float64    `json:"distanceKm"`
+}
+
+// Great-circle distance between two points in kilometers
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// Find the located event closest to the given point, nil if none has coordinates
+func closestEvent(events []Earthquake, lat, lon float64) *NearbyEvent {
+	var closest *NearbyEvent
+	for _, eq := range events {
+		if !eq.HasLocation() {
+			continue
+		}
+		distance := haversineKm(lat, lon, eq.Latitude, eq.Longitude)
+		if closest == nil || distance < closest.DistanceKm {
+			closest = &NearbyEvent{Event: eq, DistanceKm: distance}
+		}
+	}
+	return closest
+}
+
+// Read the home location from HOME_LAT and HOME_LON, ok is false when unset or invalid
+func homeLocation() (lat, lon float64, ok bool) {
+	latStr, lonStr := os.Getenv("HOME_LAT"), os.Getenv("HOME_LON")
+	if latStr == "" || lonStr == "" {
+		return 0, 0, false
+	}
+
+	lat, latErr := strconv.ParseFloat(latStr, 64)
+	lon, lonErr := strconv.ParseFloat(lonStr, 64)
+	if latErr != nil || lonErr != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		fmt.Printf("Ignoring invalid home location %q, %q\n", latStr, lonStr)
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
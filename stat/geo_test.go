@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHaversineKmParisToLondon(t *testing.T) {
+	got := haversineKm(48.8566, 2.3522, 51.5074, -0.1278)
+	if math.Abs(got-343.5) > 1 {
+		t.Fatalf("expected about 343.5 km between Paris and London, got %.1f", got)
+	}
+}
+
+func TestReportShowsClosestEventToHome(t *testing.T) {
+	store := openTestStore(t)
+	weeks := groupByWeek([]Earthquake{
+		{Time: time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC), Magnitude: 5.0, Place: "100 km W of Far, Away", Latitude: 10, Longitude: 10},
+		{Time: time.Date(2026, 6, 3, 0, 0, 0, 0, time.UTC), Magnitude: 3.4, Place: "5 km N of Near, Home", Latitude: 47.5, Longitude: 8.5},
+		{Time: time.Date(2026, 6, 4, 0, 0, 0, 0, time.UTC), Magnitude: 2.0, Place: "Unknown", Latitude: math.NaN(), Longitude: math.NaN()},
+	})
+	stats := weeks["2026-W23"]
+
+	if report := buildReport(store, "2026-W23", stats); report.Closest != nil {
+		t.Fatalf("expected no closest event without a home location, got %+v", report.Closest)
+	}
+
+	t.Setenv("HOME_LAT", "47.3769")
+	t.Setenv("HOME_LON", "8.5417")
+	text := renderText(buildReport(store, "2026-W23", stats))
+	if !strings.Contains(text, "Closest to you: M3.4, 14 km away near Near, Home") {
+		t.Fatalf("expected closest event line, got:\n%s", text)
+	}
+}
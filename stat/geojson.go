@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// geoJSONFeature is one event of a USGS GeoJSON feed. Pointers tell missing
+// values apart from zero.
+type geoJSONFeature struct {
+	ID         string `json:"id"`
+	Properties struct {
+		Mag     *float64 `json:"mag"`
+		Place   string   `json:"place"`
+		Time    *int64   `json:"time"`
+		Status  string   `json:"status"`
+		Net     string   `json:"net"`
+		MagType string   `json:"magType"`
+		Felt    *int     `json:"felt"`
+		CDI     *float64 `json:"cdi"`
+		MMI     *float64 `json:"mmi"`
+	} `json:"properties"`
+	Geometry struct {
+		// Longitude, latitude and depth in km
+		Coordinates []*float64 `json:"coordinates"`
+	} `json:"geometry"`
+}
+
+// Feeds ending in .geojson are read as GeoJSON, everything else as CSV
+func isGeoJSONFeed(feedURL string) bool {
+	path := feedURL
+	if u, err := url.Parse(feedURL); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	return strings.HasSuffix(strings.ToLower(path), ".geojson")
+}
+
+// Parse a feed in the format of its URL, see scanCSV
+func scanFeed(feedURL string, r io.Reader, quality *DataQuality, fn func(Earthquake) error) error {
+	if isGeoJSONFeed(feedURL) {
+		return scanGeoJSON(r, quality, fn)
+	}
+	return scanCSV(r, quality, fn)
+}
+
+// Parse a USGS GeoJSON feed and call fn for every event. Unlike the CSV feeds
+// it has the felt reports and intensities. The features are decoded one at a
+// time, so large feeds are not held in memory.
+func scanGeoJSON(r io.Reader, quality *DataQuality, fn func(Earthquake) error) error {
+	decoder := json.NewDecoder(r)
+	if err := seekFeatures(decoder); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		var feature geoJSONFeature
+		if err := decoder.Decode(&feature); err != nil {
+			return fmt.Errorf("failed to decode feature: %w", err)
+		}
+
+		props := feature.Properties
+		if props.Time == nil {
+			quality.RowsSkipped["invalidTime"]++
+			continue
+		}
+		if props.Mag == nil {
+			quality.RowsSkipped["invalidMagnitude"]++
+			continue
+		}
+
+		eq := Earthquake{
+			ID:        feature.ID,
+			Time:      time.UnixMilli(*props.Time).UTC(),
+			Magnitude: *props.Mag,
+			Place:     props.Place,
+			Status:    props.Status,
+			Longitude: coordinate(feature.Geometry.Coordinates, 0),
+			Latitude:  coordinate(feature.Geometry.Coordinates, 1),
+			Depth:     coordinate(feature.Geometry.Coordinates, 2),
+			Network:   props.Net,
+			MagType:   strings.ToLower(props.MagType),
+		}
+		if props.Felt != nil {
+			eq.Felt = *props.Felt
+		}
+		if props.CDI != nil {
+			eq.CDI = *props.CDI
+		}
+		if props.MMI != nil {
+			eq.MMI = *props.MMI
+		}
+		quality.add(eq)
+		if err := fn(eq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Advance the decoder into the features array of a FeatureCollection
+func seekFeatures(decoder *json.Decoder) error {
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return fmt.Errorf("unexpected GeoJSON, expected a FeatureCollection object")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if token == "features" {
+			if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+				return fmt.Errorf("unexpected GeoJSON, features is not an array")
+			}
+			return nil
+		}
+		// Skip the value of any other member, such as metadata
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("unexpected GeoJSON, no features")
+}
+
+// Coordinate i of a GeoJSON point, NaN when missing like the CSV parser
+func coordinate(coordinates []*float64, i int) float64 {
+	if i >= len(coordinates) || coordinates[i] == nil {
+		return math.NaN()
+	}
+	return *coordinates[i]
+}
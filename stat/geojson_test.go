@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanGeoJSONReadsFeltFields(t *testing.T) {
+	file, err := os.Open("testdata/all_week.geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	quality := newDataQuality()
+	var events []Earthquake
+	err = scanFeed("https://example.com/all_week.geojson", file, &quality, func(eq Earthquake) error {
+		events = append(events, eq)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scanFeed returned error: %v", err)
+	}
+	if len(events) != 3 || quality.RowsSkipped["invalidMagnitude"] != 1 {
+		t.Fatalf("expected 3 events and 1 skipped, got %d events and %v", len(events), quality.RowsSkipped)
+	}
+
+	berkeley := events[0]
+	if berkeley.ID != "nc75000001" || berkeley.Felt != 2130 || berkeley.CDI != 5.1 || berkeley.MMI != 4.6 || berkeley.MagType != "md" {
+		t.Fatalf("unexpected first event %+v", berkeley)
+	}
+	if !berkeley.Time.Equal(time.UnixMilli(1780740000000)) || berkeley.Latitude != 37.89 || berkeley.Longitude != -122.28 || berkeley.Depth != 8.2 {
+		t.Fatalf("unexpected time or location %+v", berkeley)
+	}
+	if tonga := events[1]; tonga.Felt != 0 || !math.IsNaN(tonga.Depth) {
+		t.Fatalf("expected no felt reports and a missing depth, got %+v", tonga)
+	}
+}
+
+func TestIsGeoJSONFeed(t *testing.T) {
+	tests := map[string]bool{
+		"https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_week.geojson": true,
+		"https://example.com/feed.GeoJSON?cache=1":                                   true,
+		"https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_week.csv":     false,
+		"testdata/all_week.geojson":                                                  true,
+	}
+	for url, want := range tests {
+		if got := isGeoJSONFeed(url); got != want {
+			t.Errorf("isGeoJSONFeed(%q) = %v, want %v", url, got, want)
+		}
+	}
+
+	quality := newDataQuality()
+	if err := scanGeoJSON(strings.NewReader(`[1,2]`), &quality, nil); err == nil {
+		t.Fatal("expected an error for a document that is not a FeatureCollection")
+	}
+}
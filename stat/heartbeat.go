@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Record the time of a run that ended without errors
+func (s *Store) SaveHeartbeat(t time.Time) error {
+	return s.db.Set([]byte(heartbeatKey), []byte(t.UTC().Format(time.RFC3339)), pebble.Sync)
+}
+
+// Load the time of the last successful run, false when there was none
+func (s *Store) LoadHeartbeat() (time.Time, bool, error) {
+	value, closer, err := s.db.Get([]byte(heartbeatKey))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer closer.Close()
+
+	t, err := time.Parse(time.RFC3339, string(value))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to decode heartbeat: %w", err)
+	}
+	return t, true, nil
+}
+
+// Check that the last successful run is at most maxAge ago, so external
+// monitoring notices when the cron job stops running or keeps failing
+func checkHeartbeat(store *Store, maxAge time.Duration) error {
+	last, ok, err := store.LoadHeartbeat()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("no successful run recorded")
+	}
+	if age := now().Sub(last); age > maxAge {
+		return fmt.Errorf("last successful run at %s, %s ago", last.Format(time.RFC3339), age.Round(time.Minute))
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckHeartbeatDetectsStaleRuns(t *testing.T) {
+	store := openTestStore(t)
+	if err := checkHeartbeat(store, 26*time.Hour); err == nil || !strings.Contains(err.Error(), "no successful run") {
+		t.Fatalf("expected an error without a heartbeat, got %v", err)
+	}
+
+	if err := store.SaveHeartbeat(time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	setNow(t, time.Date(2026, 6, 11, 7, 0, 0, 0, time.UTC))
+	if err := checkHeartbeat(store, 26*time.Hour); err != nil {
+		t.Fatalf("expected a heartbeat of 25h to pass, got %v", err)
+	}
+
+	setNow(t, time.Date(2026, 6, 12, 7, 0, 0, 0, time.UTC))
+	err := checkHeartbeat(store, 26*time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "49h0m0s ago") {
+		t.Fatalf("expected a stale heartbeat, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Default magnitude range of the histogram, covering the worldwide feed
+const (
+	defaultHistogramMin = 2.0
+	defaultHistogramMax = 8.0
+)
+
+// HistogramBin counts the magnitudes from Lower up to the next bin
+type HistogramBin struct {
+	Lower float64 `json:"lower"`
+	Count int     `json:"count"`
+}
+
+// Count magnitudes in bins of the given width from minMag to maxMag, keyed by
+// the lower edge of each bin. A magnitude on an edge belongs to the bin above
+// it, except maxMag itself, which closes the last bin. Magnitudes outside the range
+// are clamped into the first or last bin. Every bin is present, empty ones
+// with a count of zero.
+func histogramBins(mags []float64, width, minMag, maxMag float64) map[float64]int {
+	if width <= 0 || maxMag <= minMag {
+		return nil
+	}
+	n := int(math.Ceil((maxMag-minMag)/width - 1e-9))
+
+	bins := make(map[float64]int, n)
+	for i := range n {
+		bins[binEdge(minMag, width, i)] = 0
+	}
+	for _, mag := range mags {
+		// The small offset keeps edges such as 2.0 + 0.1*3 in their own bin
+		i := int(math.Floor((mag-minMag)/width + 1e-9))
+		bins[binEdge(minMag, width, max(0, min(i, n-1)))]++
+	}
+	return bins
+}
+
+// Lower edge of bin i, rounded so that keys do not drift with the width
+func binEdge(minMag, width float64, i int) float64 {
+	return math.Round((minMag+float64(i)*width)*1e6) / 1e6
+}
+
+// Histogram of the event magnitudes when HISTOGRAM_BIN_WIDTH is set, from
+// HISTOGRAM_MIN to HISTOGRAM_MAX. Nil when it is unset or the range is empty.
+func magnitudeHistogram(events []Earthquake) []HistogramBin {
+	width, err := strconv.ParseFloat(os.Getenv("HISTOGRAM_BIN_WIDTH"), 64)
+	if err != nil || width <= 0 || len(events) == 0 {
+		return nil
+	}
+	minMag, maxMag := defaultHistogramMin, defaultHistogramMax
+	if value, err := strconv.ParseFloat(os.Getenv("HISTOGRAM_MIN"), 64); err == nil {
+		minMag = value
+	}
+	if value, err := strconv.ParseFloat(os.Getenv("HISTOGRAM_MAX"), 64); err == nil {
+		maxMag = value
+	}
+
+	mags := make([]float64, len(events))
+	for i, eq := range events {
+		mags[i] = eq.Magnitude
+	}
+	bins := histogramBins(mags, width, minMag, maxMag)
+	histogram := make([]HistogramBin, 0, len(bins))
+	for lower, count := range bins {
+		histogram = append(histogram, HistogramBin{Lower: lower, Count: count})
+	}
+	slices.SortFunc(histogram, func(a, b HistogramBin) int { return cmp.Compare(a.Lower, b.Lower) })
+	return histogram
+}
+
+// Render the histogram with one bar per bin, e.g. "M4.5 ▆ 312". The first
+// and last bins also hold the clamped magnitudes, marked with ≤ and ≥.
+func histogramLines(histogram []HistogramBin) string {
+	if len(histogram) == 0 {
+		return ""
+	}
+	peak := 0
+	for _, bin := range histogram {
+		peak = max(peak, bin.Count)
+	}
+
+	var lines strings.Builder
+	lines.WriteString("Magnitude histogram:")
+	for i, bin := range histogram {
+		bar := ' '
+		if bin.Count > 0 {
+			bar = sparkBars[bin.Count*(len(sparkBars)-1)/peak]
+		}
+		label := "M" + formatMag(bin.Lower)
+		switch i {
+		case 0:
+			label = "≤" + label
+		case len(histogram) - 1:
+			label = "≥" + label
+		}
+		fmt.Fprintf(&lines, "\n%s %c %s", label, bar, formatCount(bin.Count))
+	}
+	return lines.String()
+}
+
+// Posts of the histogram, split at bin lines over several posts when the
+// bins do not fit into one. Nil without a histogram.
+func histogramPosts(histogram []HistogramBin) []string {
+	lines := histogramLines(histogram)
+	if lines == "" {
+		return nil
+	}
+	var posts []string
+	post := ""
+	for line := range strings.SplitSeq(lines, "\n") {
+		switch {
+		case post == "":
+			post = line
+		case postLength(post+"\n"+line) > maxPostLength:
+			posts = append(posts, post)
+			post = line
+		default:
+			post += "\n" + line
+		}
+	}
+	return append(posts, post)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHistogramBinsAssignsEdges(t *testing.T) {
+	mags := []float64{
+		1.2,  // below the range, clamped into the first bin
+		2.0,  // lower edge of the first bin
+		2.49, // just below an edge
+		2.5,  // on an edge, belongs to the bin above
+		4.9,
+		8.0, // the upper end closes the last bin
+		9.1, // above the range, clamped into the last bin
+	}
+
+	bins := histogramBins(mags, 0.5, 2.0, 8.0)
+	if len(bins) != 12 {
+		t.Fatalf("expected 12 bins from 2.0 to 8.0, got %d: %v", len(bins), bins)
+	}
+	want := map[float64]int{2.0: 3, 2.5: 1, 4.5: 1, 7.5: 2}
+	for lower, count := range bins {
+		if count != want[lower] {
+			t.Errorf("bin %v has %d magnitudes, want %d", lower, count, want[lower])
+		}
+	}
+
+	// A width of 0.1 must not drift off the decimal edges
+	fine := histogramBins([]float64{2.3, 2.7}, 0.1, 2.0, 3.0)
+	if fine[2.3] != 1 || fine[2.7] != 1 || len(fine) != 10 {
+		t.Fatalf("expected 2.3 and 2.7 in their own bins, got %v", fine)
+	}
+
+	if bins := histogramBins(mags, 0, 2, 8); bins != nil {
+		t.Fatalf("expected no bins for a zero width, got %v", bins)
+	}
+	if bins := histogramBins(mags, 0.5, 8, 2); bins != nil {
+		t.Fatalf("expected no bins for an empty range, got %v", bins)
+	}
+}
+
+func TestMagnitudeHistogramIsADetailedReply(t *testing.T) {
+	events := []Earthquake{{Magnitude: 2.1}, {Magnitude: 2.2}, {Magnitude: 3.4}, {Magnitude: 6.0}}
+	if histogram := magnitudeHistogram(events); histogram != nil {
+		t.Fatalf("expected no histogram without HISTOGRAM_BIN_WIDTH, got %v", histogram)
+	}
+
+	t.Setenv("HISTOGRAM_BIN_WIDTH", "1")
+	t.Setenv("HISTOGRAM_MIN", "2")
+	t.Setenv("HISTOGRAM_MAX", "6")
+	report := newReport("2026-W23", WeekStats{})
+	report.Histogram = magnitudeHistogram(events)
+	if text := renderDetailed(report); strings.Contains(text, "histogram") {
+		t.Fatalf("expected the histogram not to be part of the report, got:\n%s", text)
+	}
+
+	texts, err := renderReportPosts(report, nil, "detailed", nil)
+	if err != nil {
+		t.Fatalf("renderReportPosts returned error: %v", err)
+	}
+	want := "Magnitude histogram:\n≤M2.0 █ 2\nM3.0 ▄ 1\nM4.0   0\n≥M5.0 ▄ 1"
+	if len(texts) != 2 || texts[1] != want {
+		t.Fatalf("expected the histogram reply\n%s\ngot: %q", want, texts)
+	}
+	if texts, _ := renderReportPosts(report, nil, "text", nil); len(texts) != 1 {
+		t.Fatalf("expected no histogram reply in the text format, got %q", texts)
+	}
+}
+
+func TestHistogramPostsSplitLongHistograms(t *testing.T) {
+	var histogram []HistogramBin
+	for i := range 60 {
+		histogram = append(histogram, HistogramBin{Lower: 2 + float64(i)/10, Count: 1000 + i})
+	}
+	posts := histogramPosts(histogram)
+	if len(posts) < 2 || !strings.HasPrefix(posts[0], "Magnitude histogram:\n") || !strings.HasPrefix(posts[1], "M") {
+		t.Fatalf("expected the histogram over several posts, got %q", posts)
+	}
+	lines := 0
+	for _, post := range posts {
+		if postLength(post) > maxPostLength {
+			t.Fatalf("expected every post to fit, got %d graphemes", postLength(post))
+		}
+		lines += strings.Count(post, "\n") + 1
+	}
+	if lines != 61 {
+		t.Fatalf("expected the title and 60 bins, got %d lines", lines)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Bars of the hour sparkline, from fewest to most events
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Events per UTC hour of a week, nil for weeks stored without them
+func hourCounts(stats WeekStats) []int {
+	if stats.HourCounts == [24]int{} {
+		return nil
+	}
+	return stats.HourCounts[:]
+}
+
+// Render the hour counts as a sparkline with the busiest hour, e.g.
+// "By hour (UTC): ▃▄▂…▅ peak 14:00 (120)". Empty without counts.
+func hourLine(counts []int) string {
+	if len(counts) == 0 {
+		return ""
+	}
+	peak := slices.Max(counts)
+	if peak == 0 {
+		return ""
+	}
+	peakHour := slices.Index(counts, peak)
+
+	var spark strings.Builder
+	for _, count := range counts {
+		spark.WriteRune(sparkBars[count*(len(sparkBars)-1)/peak])
+	}
+	return fmt.Sprintf("By hour (UTC): %s peak %02d:00 (%s)", spark.String(), peakHour, formatCount(peak))
+}
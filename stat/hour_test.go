@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHourBreakdown(t *testing.T) {
+	day := time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC)
+	var events []Earthquake
+	for _, hour := range []int{0, 3, 3, 14, 14, 14, 14, 23} {
+		events = append(events, Earthquake{Time: day.Add(time.Duration(hour)*time.Hour + 30*time.Minute), Magnitude: 2.5})
+	}
+	// Local times are counted in their UTC hour
+	zurich := time.FixedZone("CEST", 2*60*60)
+	events = append(events, Earthquake{Time: time.Date(2026, 6, 3, 16, 5, 0, 0, zurich), Magnitude: 3.0})
+
+	stats := groupByWeek(events)["2026-W23"]
+	want := [24]int{0: 1, 3: 2, 14: 5, 23: 1}
+	if stats.HourCounts != want {
+		t.Fatalf("unexpected hour counts %v", stats.HourCounts)
+	}
+
+	store := openTestStore(t)
+	if report := buildReport(store, "2026-W23", stats); report.HourCounts != nil {
+		t.Fatalf("expected no hour counts by default, got %v", report.HourCounts)
+	}
+	t.Setenv("HOUR_BREAKDOWN", "true")
+	text := renderText(buildReport(store, "2026-W23", stats))
+	if line := "By hour (UTC): ▂▁▁▃▁▁▁▁▁▁▁▁▁▁█▁▁▁▁▁▁▁▁▂ peak 14:00 (5)"; !strings.Contains(text, line) {
+		t.Fatalf("expected %q in the report, got:\n%s", line, text)
+	}
+
+	if line := hourLine(hourCounts(WeekStats{})); line != "" {
+		t.Fatalf("expected no line for a week stored without hour counts, got %q", line)
+	}
+}
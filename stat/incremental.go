@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Collected events of weeks that were never posted are dropped after this long
+const partialWeekRetention = 5 * 7 * 24 * time.Hour
+
+// Merge the events of the feed into the events collected by earlier runs and
+// return the stats of every week that is not posted yet. Events are matched
+// by ID and the first version seen is kept, so events that have aged out of
+// the feed window still count. Events without an ID can not be matched and
+// are skipped. Weeks older than partialWeekRetention are removed from the
+// store. Posted weeks are still returned, so a posted week stays the newest
+// week, but their collected events are removed from the store.
+func mergeIncremental(store *Store, earthquakes []Earthquake) (map[string]WeekStats, error) {
+	weekKeys, err := store.PartialWeekKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collected weeks: %w", err)
+	}
+
+	feedWeeks := groupByWeek(earthquakes)
+	for weekKey := range feedWeeks {
+		weekKeys = append(weekKeys, weekKey)
+	}
+
+	weeklyStats := make(map[string]WeekStats)
+	for _, weekKey := range weekKeys {
+		if _, done := weeklyStats[weekKey]; done {
+			continue
+		}
+		if weekStart(weekKey).Before(now().Add(-partialWeekRetention)) {
+			if err := store.DeletePartialWeek(weekKey); err != nil {
+				return nil, fmt.Errorf("failed to delete events of week %s: %w", weekKey, err)
+			}
+			continue
+		}
+
+		events, err := store.LoadPartialWeek(weekKey)
+		if err != nil {
+			return nil, err
+		}
+		stored := len(events) > 0
+		seen := make(map[string]bool, len(events))
+		for _, eq := range events {
+			seen[eq.ID] = true
+		}
+
+		added := 0
+		for _, eq := range feedWeeks[weekKey].Events {
+			if eq.ID == "" || seen[eq.ID] {
+				continue
+			}
+			seen[eq.ID] = true
+			events = append(events, eq)
+			added++
+		}
+		// A posted week needs no collecting anymore, its stats are still returned
+		posted := store.WasWeekPosted(weekKey)
+		switch {
+		case posted && stored:
+			if err := store.DeletePartialWeek(weekKey); err != nil {
+				return nil, fmt.Errorf("failed to delete events of week %s: %w", weekKey, err)
+			}
+		case !posted && added > 0:
+			if err := store.SavePartialWeek(weekKey, events); err != nil {
+				return nil, err
+			}
+		}
+
+		if stats, ok := groupByWeek(events)[weekKey]; ok {
+			weeklyStats[weekKey] = stats
+		}
+	}
+	return weeklyStats, nil
+}
+
+// Start of a week key such as "2026-W23" in UTC, the zero time for invalid keys
+func weekStart(weekKey string) time.Time {
+	year, week, ok := parseWeekKey(weekKey)
+	if !ok {
+		return time.Time{}
+	}
+	// January 4th is always in ISO week 1
+	start, _, _, _ := getWeekBoundaries(time.Date(year, 1, 4, 12, 0, 0, 0, weekLocation))
+	return start.In(weekLocation).AddDate(0, 0, 7*(week-1)).UTC()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeIncrementalKeepsEventsThatAgedOutOfTheFeed(t *testing.T) {
+	store := openTestStore(t)
+	setNow(t, time.Date(2026, 6, 5, 12, 0, 0, 0, time.UTC))
+	monday := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	a := Earthquake{ID: "a", Time: monday.Add(2 * time.Hour), Magnitude: 2.1}
+	b := Earthquake{ID: "b", Time: monday.Add(50 * time.Hour), Magnitude: 3.4}
+	c := Earthquake{ID: "c", Time: monday.Add(100 * time.Hour), Magnitude: 5.2}
+
+	// First snapshot has a and b
+	weeks, err := mergeIncremental(store, []Earthquake{a, b})
+	if err != nil {
+		t.Fatalf("mergeIncremental returned error: %v", err)
+	}
+	if total := weeks["2026-W23"].Total(); total != 2 {
+		t.Fatalf("expected 2 events after the first snapshot, got %d", total)
+	}
+
+	// Second snapshot: a aged out, b is repeated with a revised magnitude, c is new
+	revised := b
+	revised.Magnitude = 3.6
+	weeks, err = mergeIncremental(store, []Earthquake{revised, c})
+	if err != nil {
+		t.Fatalf("mergeIncremental returned error: %v", err)
+	}
+	stats := weeks["2026-W23"]
+	if stats.Total() != 3 || stats.Largest.ID != "c" {
+		t.Fatalf("expected a, b and c once each, got %d events, largest %q", stats.Total(), stats.Largest.ID)
+	}
+	if sum := stats.MagnitudeSum; sum != a.Magnitude+b.Magnitude+c.Magnitude {
+		t.Fatalf("expected the first version of b to be kept, got magnitude sum %v", sum)
+	}
+
+	// An empty feed still returns the collected week until it is posted
+	if weeks, _ := mergeIncremental(store, nil); weeks["2026-W23"].Total() != 3 {
+		t.Fatalf("expected the collected week without feed data, got %+v", weeks)
+	}
+	// Weeks never posted are dropped after the retention period
+	if err := store.SavePartialWeek("2026-W17", []Earthquake{{ID: "old", Time: time.Date(2026, 4, 21, 0, 0, 0, 0, time.UTC)}}); err != nil {
+		t.Fatalf("SavePartialWeek returned error: %v", err)
+	}
+	if weeks, _ := mergeIncremental(store, nil); len(weeks) != 1 {
+		t.Fatalf("expected the week older than the retention to be dropped, got %+v", weeks)
+	}
+
+	store.MarkWeekPosted("2026-W23")
+	if weeks, _ := mergeIncremental(store, nil); weeks["2026-W23"].Total() != 3 {
+		t.Fatalf("expected the posted week to be returned once more, got %+v", weeks)
+	}
+	if keys, _ := store.PartialWeekKeys(); len(keys) != 0 {
+		t.Fatalf("expected the events of the posted week to be deleted, got %v", keys)
+	}
+}
+
+func TestMergeIncrementalKeepsThePostedWeekTheNewest(t *testing.T) {
+	store := openTestStore(t)
+	setNow(t, time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+	older := Earthquake{ID: "a", Time: time.Date(2026, 5, 27, 0, 0, 0, 0, time.UTC), Magnitude: 3.0}
+	newer := Earthquake{ID: "b", Time: time.Date(2026, 6, 3, 0, 0, 0, 0, time.UTC), Magnitude: 4.0}
+
+	// The first run posts the newest full week, the older week stays collected
+	weeks, err := mergeIncremental(store, []Earthquake{older, newer})
+	if err != nil {
+		t.Fatalf("mergeIncremental returned error: %v", err)
+	}
+	if data := generateReports(store, getFullWeeks(weeks)); data.WeekKey != "2026-W23" || !data.ShouldPost {
+		t.Fatalf("expected 2026-W23 to be reported, got %s", data.WeekKey)
+	}
+	store.MarkWeekPosted("2026-W23")
+
+	// The next run must not fall back to the older, unposted week
+	for range 2 {
+		weeks, err = mergeIncremental(store, []Earthquake{older, newer})
+		if err != nil {
+			t.Fatalf("mergeIncremental returned error: %v", err)
+		}
+		if data := generateReports(store, getFullWeeks(weeks)); data.WeekKey != "2026-W23" || data.ShouldPost {
+			t.Fatalf("expected the posted 2026-W23 to stay the last week, got %s (post %v)", data.WeekKey, data.ShouldPost)
+		}
+	}
+	if keys, _ := store.PartialWeekKeys(); len(keys) != 1 || keys[0] != "2026-W22" {
+		t.Fatalf("expected only the events of the unposted week to be kept, got %v", keys)
+	}
+}
+
+func TestWeekStartOfWeekKey(t *testing.T) {
+	for weekKey, want := range map[string]time.Time{
+		"2026-W01": time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC),
+		"2026-W23": time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+		"2020-W53": time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC),
+	} {
+		if got := weekStart(weekKey); !got.Equal(want) {
+			t.Errorf("weekStart(%q) = %v, want %v", weekKey, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// InterEventTime is the mean and median time between consecutive events of
+// a week. Shorter times than usual point to elevated activity.
+type InterEventTime struct {
+	MeanSeconds   float64 `json:"meanSeconds"`
+	MedianSeconds float64 `json:"medianSeconds"`
+}
+
+// Mean and median of the gaps between consecutive event times, nil with
+// fewer than two events
+func interEventStats(times []time.Time) *InterEventTime {
+	if len(times) < 2 {
+		return nil
+	}
+
+	sorted := slices.Clone(times)
+	slices.SortFunc(sorted, func(a, b time.Time) int { return a.Compare(b) })
+	gaps := make([]float64, len(sorted)-1)
+	for i := range gaps {
+		gaps[i] = sorted[i+1].Sub(sorted[i]).Seconds()
+	}
+	slices.Sort(gaps)
+
+	// The mean gap only depends on the first and the last event
+	mean := sorted[len(sorted)-1].Sub(sorted[0]).Seconds() / float64(len(gaps))
+	return &InterEventTime{MeanSeconds: mean, MedianSeconds: percentile(gaps, 50)}
+}
+
+// Event times of a week for interEventStats
+func eventTimes(events []Earthquake) []time.Time {
+	times := make([]time.Time, len(events))
+	for i, eq := range events {
+		times[i] = eq.Time
+	}
+	return times
+}
+
+// Format a gap such as "45s", "4m 12s" or "1h 03m"
+func formatInterval(seconds float64) string {
+	d := time.Duration(math.Round(seconds)) * time.Second
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm %02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterEventStats(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	// Unsorted, with gaps of 1m, 2m, 3m and 14m
+	times := []time.Time{
+		start.Add(6 * time.Minute),
+		start,
+		start.Add(20 * time.Minute),
+		start.Add(time.Minute),
+		start.Add(3 * time.Minute),
+	}
+
+	stats := interEventStats(times)
+	if stats == nil || stats.MeanSeconds != 300 || stats.MedianSeconds != 150 {
+		t.Fatalf("expected a mean of 5m and a median of 2m 30s, got %+v", stats)
+	}
+	if !times[0].Equal(start.Add(6 * time.Minute)) {
+		t.Fatal("expected the input times to stay in order")
+	}
+
+	if got := interEventStats(times[:1]); got != nil {
+		t.Fatalf("expected no stats for a single event, got %+v", got)
+	}
+	if got := interEventStats(nil); got != nil {
+		t.Fatalf("expected no stats without events, got %+v", got)
+	}
+
+	text := renderDetailed(Report{Categories: newReport("", WeekStats{}).Categories, InterEvent: stats})
+	if !strings.HasSuffix(text, "Time between events: mean 5m 00s, median 2m 30s") {
+		t.Fatalf("expected inter-event line, got:\n%s", text)
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	tests := map[float64]string{
+		0:      "0s",
+		44.6:   "45s",
+		252:    "4m 12s",
+		3780:   "1h 03m",
+		100000: "27h 46m",
+	}
+	for seconds, want := range tests {
+		if got := formatInterval(seconds); got != want {
+			t.Errorf("formatInterval(%v) = %q, want %q", seconds, got, want)
+		}
+	}
+}
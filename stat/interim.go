@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Events from this magnitude trigger an interim report with NOTABLE_TRIGGER
+const notableMagnitude = 6.0
+
+// Build the "so far this week" report of the current, incomplete week when it
+// has an event of notableMagnitude or more. Each week gets at most one interim
+// report, which is tracked separately from the final report at week end.
+func interimReport(store *Store, weeklyStats map[string]WeekStats) (ReportData, bool) {
+	_, _, year, week := getWeekBoundaries(now())
+	weekKey := formatWeekKey(year, week)
+
+	stats, ok := weeklyStats[weekKey]
+	if !ok || stats.Largest.Magnitude < notableMagnitude || store.WasInterimPosted(weekKey) {
+		return ReportData{}, false
+	}
+
+	report := buildReport(store, weekKey, stats)
+	return ReportData{
+		WeekKey:    weekKey,
+		Report:     report,
+		ReportText: renderSummaryLine("So far this week", report),
+		ShouldPost: true,
+	}, true
+}
+
+// Post the interim report of the current week if a notable event triggered
+// one, reporting whether it was posted
+func postInterimReport(ctx context.Context, store *Store, weeklyStats map[string]WeekStats) (bool, error) {
+	reportData, ok := interimReport(store, weeklyStats)
+	if !ok {
+		return false, nil
+	}
+
+	fmt.Println(reportData.ReportText)
+	if err := postToBluesky(ctx, store, reportData.ReportText); err != nil {
+		return false, err
+	}
+	store.MarkInterimPosted(reportData.WeekKey)
+	return true, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInterimReportFiresOnNotableEventAndFinalStillPosts(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	earthquakes := []Earthquake{
+		{ID: "a", Time: time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC), Magnitude: 4.2, Place: "10 km N of Quiet, Place"},
+		{ID: "b", Time: time.Date(2026, 6, 3, 9, 0, 0, 0, time.UTC), Magnitude: 6.4, Place: "20 km S of Shaky, Chile"},
+	}
+
+	// Monday, before the notable event
+	setNow(t, time.Date(2026, 6, 2, 12, 0, 0, 0, time.UTC))
+	if _, err := postInterimReport(context.Background(), store, groupByWeek(earthquakes[:1])); err != nil {
+		t.Fatalf("postInterimReport returned error: %v", err)
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 0 {
+		t.Fatalf("expected no interim post without a notable event, got %d", len(created))
+	}
+
+	// Wednesday, after the notable event, run twice
+	setNow(t, time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC))
+	for range 2 {
+		if _, err := postInterimReport(context.Background(), store, groupByWeek(earthquakes)); err != nil {
+			t.Fatalf("postInterimReport returned error: %v", err)
+		}
+	}
+	created := pds.calls("com.atproto.repo.createRecord")
+	if len(created) != 1 {
+		t.Fatalf("expected exactly one interim post, got %d", len(created))
+	}
+	want := "So far this week: 2 quakes (1 strong) · largest M6.4 near Shaky, Chile"
+	if text := created[0]["record"].(map[string]any)["text"]; text != want {
+		t.Fatalf("unexpected interim text %q", text)
+	}
+
+	// The next Monday the final report of the week is still due
+	setNow(t, time.Date(2026, 6, 8, 6, 0, 0, 0, time.UTC))
+	reportData := generateReports(store, getFullWeeks(groupByWeek(earthquakes)))
+	if !reportData.ShouldPost || reportData.WeekKey != "2026-W23" {
+		t.Fatalf("expected the final report of 2026-W23 to be due, got %+v", reportData)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Opening line of the weekly posts from INTRO_TEXT, with {week}, {year},
+// {weekKey}, {start}, {end} and {total} replaced from the report. Empty
+// when INTRO_TEXT is unset. The week and the dates are those of the week key
+// in weekLocation, not of the report time zones, which may start the week a
+// day earlier.
+func introLine(report Report) string {
+	intro := strings.TrimSpace(os.Getenv("INTRO_TEXT"))
+	if intro == "" {
+		return ""
+	}
+
+	year, week, ok := parseWeekKey(report.WeekKey)
+	if !ok {
+		year, week = report.StartDate.In(weekLocation).ISOWeek()
+	}
+	return strings.NewReplacer(
+		"{week}", strconv.Itoa(week),
+		"{year}", strconv.Itoa(year),
+		"{weekKey}", report.WeekKey,
+		"{start}", report.StartDate.In(weekLocation).Format("Jan 2"),
+		"{end}", report.EndDate.In(weekLocation).Format("Jan 2"),
+		"{total}", formatCount(report.Total),
+	).Replace(intro)
+}
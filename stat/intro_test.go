@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntroLineSubstitutesVariables(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := newReport("2026-W23", WeekStats{
+		StartDate: start,
+		EndDate:   start.AddDate(0, 0, 7).Add(-time.Second),
+		Counts:    [7]int{800, 400, 30, 4, 0, 0, 0},
+	})
+
+	if line := introLine(report); line != "" {
+		t.Fatalf("expected no intro by default, got %q", line)
+	}
+
+	t.Setenv("INTRO_TEXT", "🌍 Your weekly shake report for week {week} of {year} ({start} - {end}), {total} quakes!")
+	want := "🌍 Your weekly shake report for week 23 of 2026 (Jun 1 - Jun 7), 1234 quakes!"
+	if line := introLine(report); line != want {
+		t.Fatalf("introLine = %q, want %q", line, want)
+	}
+
+	text, err := renderPostText(report, "text", nil)
+	if err != nil {
+		t.Fatalf("renderPostText returned error: %v", err)
+	}
+	if !strings.HasPrefix(text, want+"\nWeekly Earthquake Report\n") {
+		t.Fatalf("expected the intro before the report, got:\n%s", text)
+	}
+}
+
+func TestIntroLineUsesTheWeekKeyWestOfUTC(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := newReport("2026-W23", WeekStats{StartDate: start, EndDate: start.AddDate(0, 0, 7).Add(-time.Second)})
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("INTRO_TEXT", "Week {week} of {year} ({start} - {end})")
+
+	// In Los Angeles the week starts on Sunday May 31, which is in week 22
+	if line := introLine(report.In(la)); line != "Week 23 of 2026 (Jun 1 - Jun 7)" {
+		t.Fatalf("expected the week of the week key, got %q", line)
+	}
+}
+
+func TestIntroLineCountsTowardPostLength(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := newReport("2026-W23", WeekStats{StartDate: start, EndDate: start.AddDate(0, 0, 7).Add(-time.Second)})
+	report.MostActive = &RegionActivity{Region: "Alaska", Count: 12}
+	t.Setenv("INTRO_TEXT", strings.Repeat("Shake ", 30))
+
+	text, err := renderPostText(report, "text", nil)
+	if err != nil {
+		t.Fatalf("renderPostText returned error: %v", err)
+	}
+	if postLength(text) > maxPostLength || !strings.HasPrefix(text, "Shake Shake") {
+		t.Fatalf("expected the post to fit and keep the intro, got %d graphemes:\n%s", postLength(text), text)
+	}
+	if strings.Contains(text, "Most active region") {
+		t.Fatalf("expected an optional section to make room for the intro, got:\n%s", text)
+	}
+}
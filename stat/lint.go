@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+)
+
+// Render the report of a stored week with the current configuration, including
+// the time zones and the footer, and print the length of each post. Checking
+// this before deploying a new configuration avoids posts that lose sections.
+// Returns false when a post with all its sections exceeds maxPostLength.
+func lintReport(store *Store, w io.Writer, weekKey string, format string, tmpl *template.Template, zones []*time.Location) (bool, error) {
+	if weekKey == "" {
+		latest, err := store.LatestWeekKey()
+		if err != nil {
+			return false, err
+		}
+		if latest == "" {
+			return false, fmt.Errorf("no stored weeks, run stat or -backfill first")
+		}
+		weekKey = latest
+	}
+
+	stats, ok := store.LoadWeekStats(weekKey)
+	if !ok {
+		return false, fmt.Errorf("no stored stats for week %s", weekKey)
+	}
+
+	// Measure the posts with every section, before renderPostText drops any
+	report := buildReport(store, weekKey, stats)
+	if len(zones) == 0 {
+		zones = []*time.Location{weekLocation}
+	}
+	var texts []string
+	for _, loc := range zones {
+		text, err := renderSections(report.In(loc), format, tmpl)
+		if err != nil {
+			return false, err
+		}
+		texts = append(texts, text)
+	}
+
+	texts = withThreadProvenance(texts, format, now(), feedWindow(configuredFeedURLs()))
+
+	fmt.Fprintf(w, "Week %s, format %s\n", weekKey, format)
+	fits := true
+	for i, text := range texts {
+		length := postLength(text)
+		if length > maxPostLength {
+			fits = false
+			fmt.Fprintf(w, "Post %d: %d/%d graphemes, too long by %d, sections would be dropped\n", i+1, length, maxPostLength, length-maxPostLength)
+		} else {
+			fmt.Fprintf(w, "Post %d: %d/%d graphemes, ok\n", i+1, length, maxPostLength)
+		}
+	}
+	if len(texts) > 1 {
+		fmt.Fprintf(w, "Posted as a thread of %d posts, one per time zone\n", len(texts))
+	} else {
+		fmt.Fprintln(w, "Posted as a single post")
+	}
+	return fits, nil
+}
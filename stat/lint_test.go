@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestLintReportPrintsPostLengths(t *testing.T) {
+	store := openTestStore(t)
+	setNow(t, time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC))
+	t.Setenv("USGS_FEED_URL", "")
+
+	var out strings.Builder
+	if _, err := lintReport(store, &out, "", "text", nil, nil); err == nil {
+		t.Fatal("expected an error without stored weeks")
+	}
+
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	store.StoreWeekStats("2026-W23", WeekStats{
+		StartDate: start,
+		EndDate:   start.AddDate(0, 0, 7),
+		Counts:    [7]int{120, 80, 40, 10, 2, 1},
+		Largest:   Earthquake{Magnitude: 6.4, Place: "South of the Fiji Islands"},
+	})
+
+	out.Reset()
+	zurich, _ := time.LoadLocation("Europe/Zurich")
+	fits, err := lintReport(store, &out, "", "text", nil, []*time.Location{time.UTC, zurich})
+	if err != nil || !fits {
+		t.Fatalf("expected the report to fit, got %v (err %v):\n%s", fits, err, out.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || lines[0] != "Week 2026-W23, format text" || !strings.HasSuffix(lines[1], "/300 graphemes, ok") {
+		t.Fatalf("unexpected lint output:\n%s", out.String())
+	}
+	if lines[3] != "Posted as a thread of 2 posts, one per time zone" {
+		t.Fatalf("expected threading to be reported, got %q", lines[3])
+	}
+
+	tmpl, err := template.New("long").Parse(strings.Repeat("x", 301))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if fits, _ := lintReport(store, &out, "2026-W23", "text", tmpl, nil); fits || !strings.Contains(out.String(), "301/300 graphemes, too long by 1") {
+		t.Fatalf("expected an overlong post to be reported, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import "strings"
+
+// Families of magnitude types, keyed by the prefix of the feed's magType.
+// Variants such as mww, mwc and mwr are all moment magnitudes.
+var magTypeFamilies = []struct {
+	prefix string
+	label  string
+}{
+	{"mw", "Mw"},
+	{"ms", "Ms"},
+	{"mb", "mb"},
+	{"ml", "ML"},
+	{"md", "Md"},
+}
+
+// Family of a magnitude type, e.g. "Mw" for "mww". Other types are kept as
+// they are, events without a type count as "unknown".
+func magTypeFamily(magType string) string {
+	magType = strings.ToLower(strings.TrimSpace(magType))
+	if magType == "" {
+		return "unknown"
+	}
+	for _, family := range magTypeFamilies {
+		if strings.HasPrefix(magType, family.prefix) {
+			return family.label
+		}
+	}
+	return magType
+}
+
+// Number of events per magnitude type family, largest first
+func groupByMagType(events []Earthquake) []CategoryCount {
+	counts := make(map[string]int)
+	for _, eq := range events {
+		counts[magTypeFamily(eq.MagType)]++
+	}
+	return sortedCounts(counts)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMagTypeBreakdown(t *testing.T) {
+	csv := `time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status
+2026-06-01T10:00:00Z,1,2,3,6.1,mww,,,,,us,a,,A,earthquake,reviewed
+2026-06-02T10:00:00Z,1,2,3,4.4,mb,,,,,us,b,,B,earthquake,reviewed
+2026-06-03T10:00:00Z,1,2,3,1.2,ml,,,,,ak,c,,C,earthquake,reviewed
+2026-06-03T11:00:00Z,1,2,3,1.4,ML,,,,,nc,d,,D,earthquake,reviewed
+2026-06-04T10:00:00Z,1,2,3,0.9,md,,,,,nc,e,,E,earthquake,reviewed
+2026-06-05T10:00:00Z,1,2,3,2.0,,,,,,hv,f,,F,earthquake,reviewed
+`
+	quakes, err := parseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("parseCSV returned error: %v", err)
+	}
+	if quakes[0].MagType != "mww" || quakes[3].MagType != "ml" || quakes[5].MagType != "" {
+		t.Fatalf("unexpected magnitude types %q, %q, %q", quakes[0].MagType, quakes[3].MagType, quakes[5].MagType)
+	}
+
+	store := openTestStore(t)
+	stats := groupByWeek(quakes)["2026-W23"]
+	if report := buildReport(store, "2026-W23", stats); report.MagTypes != nil {
+		t.Fatalf("expected no breakdown by default, got %v", report.MagTypes)
+	}
+
+	t.Setenv("MAG_TYPE_BREAKDOWN", "true")
+	report := buildReport(store, "2026-W23", stats)
+	want := "By magnitude type: ML 2, Md 1, Mw 1, mb 1, unknown 1"
+	if text := renderText(report); !strings.Contains(text, want) {
+		t.Fatalf("expected %q in the report, got:\n%s", want, text)
+	}
+	if got := groupByMagType([]Earthquake{{MagType: "mwr"}, {MagType: "mh"}}); got[0].Label != "Mw" || got[1].Label != "mh" {
+		t.Fatalf("unexpected families %v", got)
+	}
+}
@@ -1,217 +1,512 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
-	"github.com/bluesky-social/indigo/api/atproto"
-	"github.com/bluesky-social/indigo/api/bsky"
-	"github.com/bluesky-social/indigo/lex/util"
-	"github.com/bluesky-social/indigo/xrpc"
-	"github.com/cockroachdb/pebble"
 	"github.com/joho/godotenv"
 )
 
 type Earthquake struct {
-	Time      time.Time
-	Magnitude float64
-	Place     string
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Magnitude float64   `json:"magnitude"`
+	Place     string    `json:"place"`
+	Status    string    `json:"status"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Depth     float64   `json:"depth"`
+	Network   string    `json:"network"`
+	MagType   string    `json:"magType"`
+	// "Did You Feel It?" reports and intensities, only in GeoJSON feeds
+	Felt int     `json:"felt,omitempty"`
+	CDI  float64 `json:"cdi,omitempty"`
+	MMI  float64 `json:"mmi,omitempty"`
 }
 
-type WeekStats struct {
-	StartDate time.Time
-	EndDate   time.Time
-	Year      int
-	WeekNum   int
-	Counts    [7]int
+// Missing coordinates and depths are NaN in memory and null in JSON, which has no NaN
+func (e Earthquake) MarshalJSON() ([]byte, error) {
+	type alias Earthquake
+	return json.Marshal(struct {
+		alias
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		Depth     *float64 `json:"depth"`
+	}{alias(e), nullable(e.Latitude), nullable(e.Longitude), nullable(e.Depth)})
+}
+
+func (e *Earthquake) UnmarshalJSON(data []byte) error {
+	type alias Earthquake
+	var decoded struct {
+		alias
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		Depth     *float64 `json:"depth"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = Earthquake(decoded.alias)
+	e.Latitude = fromNullable(decoded.Latitude)
+	e.Longitude = fromNullable(decoded.Longitude)
+	e.Depth = fromNullable(decoded.Depth)
+	return nil
+}
+
+// HasLocation reports whether both coordinates were present in the feed
+func (e Earthquake) HasLocation() bool {
+	return !math.IsNaN(e.Latitude) && !math.IsNaN(e.Longitude)
 }
 
-type BlueskyConfig struct {
-	Identifier string
-	Password   string
+func nullable(v float64) *float64 {
+	if math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
+
+func fromNullable(v *float64) float64 {
+	if v == nil {
+		return math.NaN()
+	}
+	return *v
 }
 
-var db *pebble.DB
+type WeekStats struct {
+	StartDate    time.Time
+	EndDate      time.Time
+	Year         int
+	WeekNum      int
+	Counts       [7]int
+	MagnitudeSum float64
+	Largest      Earthquake
+	DepthCounts  [4]int
+	// Events per hour of the day in UTC
+	HourCounts [24]int
+	// Time of the latest event, zero for weeks stored before it was tracked
+	Latest     time.Time
+	Historical bool         `json:",omitempty"`
+	Events     []Earthquake `json:"-"`
+}
+
+const defaultFeedURL = "https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_month.csv"
+
+const defaultMinWeeklyEvents = 100
+
+// now returns the current time. Tests override it to pin "now" to a fixed instant.
+var now = time.Now
 
 func main() {
+	format := flag.String("format", "text", "report format: text, compact, detailed or json")
+	mapFile := flag.String("map-file", "", "write a PNG map of the reported week's epicenters to this file")
+	markdownDir := flag.String("markdown-dir", "", "write the report as markdown to <dir>/<year>/<week>.md")
+	markdownCommit := flag.Bool("markdown-commit", false, "commit the markdown report with git, requires -markdown-dir")
+	archiveCSV := flag.String("archive-csv", "", "append the weekly totals as a row to this CSV file")
+	regenWeek := flag.String("regen-week", "", "print the report of a stored week (e.g. 2026-W23) without posting")
+	draft := flag.Bool("draft", false, "save the report as a draft instead of posting it")
+	publishWeek := flag.String("publish-draft", "", "post the saved draft of a week (e.g. 2026-W23)")
+	unmarkWeek := flag.String("unmark-week", "", "reset the posted status of a week (e.g. 2026-W23) so the next run posts it again, requires -force")
+	force := flag.Bool("force", false, "confirm -unmark-week")
+	backfillStart := flag.String("backfill", "", "store the stats of past weeks from the FDSN archive, starting at this date (e.g. 2025-01-01), followed by an optional end date")
+	lint := flag.Bool("lint-report", false, "print the post lengths of the latest stored week's report, or of the week given as argument, with the current configuration")
+	smoke := flag.Bool("smoke-test", false, "create a real post and delete it right away to check the credentials and the connection; the post is briefly public")
+	dump := flag.Bool("dump-events", false, "write the parsed events of the feeds as newline-delimited JSON to stdout, then exit")
+	engageFlag := flag.Bool("engage", false, "follow back the accounts that followed the bot, then exit")
+	dbStatsFlag := flag.Bool("db-stats", false, "print the key counts, the stored weeks and the disk size of the database, or of the Pebble database given as argument, then exit")
+	repairDB := flag.Bool("repair-db", false, "move a corrupt database aside and start with a new one holding the entries that can still be read")
+	heartbeatAge := flag.Duration("check-heartbeat", 0, "exit with status 1 when the last successful run is older than this (e.g. 26h)")
+	flag.Parse()
+
 	err := godotenv.Load()
 	if err != nil && !os.IsNotExist(err) {
 		log.Fatal("Error loading .env file")
 	}
 
+	if safeMode() {
+		printSafeModeBanner()
+	}
+
+	// Validate the optional report template before doing any work
+	reportTemplate, err := loadTemplate(os.Getenv("TEMPLATE_FILE"))
+	if err != nil {
+		fmt.Printf("Error loading report template: %v\n", err)
+		return
+	}
+	zones, err := reportZones()
+	if err != nil {
+		fmt.Printf("Error loading report time zones: %v\n", err)
+		return
+	}
+	thresholds, err := networkThresholds()
+	if err != nil {
+		fmt.Printf("Error loading network thresholds: %v\n", err)
+		return
+	}
+	if weekLocation, err = weekZone(); err != nil {
+		fmt.Printf("Error loading week time zone: %v\n", err)
+		return
+	}
+
+	if magnitudePalette, err = loadPalette(); err != nil {
+		fmt.Printf("Error loading magnitude palette: %v\n", err)
+		return
+	}
+	if _, err := replyPolicy(); err != nil {
+		fmt.Printf("Error loading reply policy: %v\n", err)
+		return
+	}
+	if err := checkSQLite(); err != nil {
+		fmt.Printf("Error checking SQLite output: %v\n", err)
+		return
+	}
+	postAt, err := loadPostSchedule()
+	if err != nil {
+		fmt.Printf("Error loading post schedule: %v\n", err)
+		return
+	}
+	timeout, err := runTimeout()
+	if err != nil {
+		fmt.Printf("Error loading run timeout: %v\n", err)
+		return
+	}
+
+	if *dump {
+		if _, err := dumpEvents(context.Background(), os.Stdout, configuredFeedURLs()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error dumping events: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize Pebble database
 	dbPath := filepath.Join(os.TempDir(), "earthquakestats-pebble")
-	db, err = pebble.Open(dbPath, &pebble.Options{})
+	if *dbStatsFlag {
+		if flag.Arg(0) != "" {
+			dbPath = flag.Arg(0)
+		}
+		if err := printDBStats(os.Stdout, dbPath); err != nil {
+			fmt.Printf("Error reading database stats: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	store, err := openStore(dbPath, *repairDB)
 	if err != nil {
 		fmt.Printf("Error opening Pebble database: %v\n", err)
 		return
 	}
-	defer db.Close()
+	defer store.Close()
 
-	// Download CSV file
-	url := "https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_month.csv"
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		fmt.Printf("Error downloading file: %v\n", err)
+	if *regenWeek != "" {
+		if err := regenerateWeek(store, os.Stdout, *regenWeek, *format, reportTemplate); err != nil {
+			fmt.Printf("Error regenerating report: %v\n", err)
+		}
 		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Unexpected status code downloading file: %d\n", resp.StatusCode)
+
+	if *heartbeatAge > 0 {
+		if err := checkHeartbeat(store, *heartbeatAge); err != nil {
+			fmt.Printf("Heartbeat check failed: %v\n", err)
+			store.Close()
+			os.Exit(1)
+		}
+		fmt.Println("Heartbeat OK")
 		return
 	}
 
-	// Parse CSV data
-	earthquakes, err := parseCSV(resp.Body)
-	if err != nil {
-		fmt.Printf("Error parsing CSV: %v\n", err)
+	if *smoke {
+		if err := smokeTest(context.Background(), store); err != nil {
+			fmt.Printf("Smoke test failed: %v\n", err)
+			store.Close()
+			os.Exit(1)
+		}
 		return
 	}
 
-	// Group earthquakes by week
-	weeklyStats := groupByWeek(earthquakes)
+	if *engageFlag {
+		followed, err := engage(context.Background(), store)
+		fmt.Printf("Followed back %d accounts\n", followed)
+		if err != nil {
+			fmt.Printf("Error engaging: %v\n", err)
+			store.Close()
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Get full weeks only
-	fullWeeks := getFullWeeks(weeklyStats)
+	if *lint {
+		fits, err := lintReport(store, os.Stdout, flag.Arg(0), *format, reportTemplate, zones)
+		if err != nil {
+			fmt.Printf("Error checking report: %v\n", err)
+		}
+		if err != nil || !fits {
+			store.Close()
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Generate reports
-	if len(fullWeeks) > 0 {
-		reportData := generateReports(fullWeeks)
+	if addr := os.Getenv("SERVE_ADDR"); addr != "" {
+		if err := serve(store, addr); err != nil {
+			fmt.Printf("Error serving reports: %v\n", err)
+		}
+		return
+	}
 
-		// Post to Bluesky if new report is available
-		if reportData.ShouldPost {
-			err := postToBluesky(reportData.ReportText)
-			if err != nil {
-				fmt.Printf("Error posting to Bluesky: %v\n", err)
-			} else {
-				// Mark as posted in Pebble
-				markWeekAsPosted(reportData.WeekKey)
-			}
-		} else {
-			fmt.Println("Report for this week already posted")
+	if *unmarkWeek != "" {
+		if !*force {
+			fmt.Printf("This makes the next run post week %s again, rerun with -force to confirm\n", *unmarkWeek)
+			return
+		}
+		if err := store.UnmarkWeekPosted(*unmarkWeek); err != nil {
+			fmt.Printf("Error unmarking week: %v\n", err)
+			return
 		}
-	} else {
-		fmt.Println("No complete weeks of earthquake data available")
+		fmt.Printf("Week %s is no longer marked as posted\n", *unmarkWeek)
+		return
+	}
+
+	if *publishWeek != "" {
+		if err := publishDraft(context.Background(), store, *publishWeek); err != nil {
+			fmt.Printf("Error publishing draft: %v\n", err)
+		}
+		return
+	}
+
+	if *backfillStart != "" {
+		start, end, err := parseBackfillRange(*backfillStart, flag.Arg(0))
+		if err != nil {
+			fmt.Printf("Error parsing backfill range: %v\n", err)
+			return
+		}
+		stored, err := newBackfiller(thresholds).backfill(store, start, end)
+		if err != nil {
+			fmt.Printf("Error backfilling: %v\n", err)
+		}
+		fmt.Printf("Backfilled %d weeks\n", stored)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	run(ctx, store, runConfig{
+		format:         *format,
+		tmpl:           reportTemplate,
+		zones:          zones,
+		thresholds:     thresholds,
+		draft:          *draft,
+		mapFile:        *mapFile,
+		markdownDir:    *markdownDir,
+		markdownCommit: *markdownCommit,
+		archiveCSV:     *archiveCSV,
+		postAt:         postAt,
+	})
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		fmt.Printf("Error: run did not finish within %s\n", timeout)
+		cancel()
+		store.Close()
+		os.Exit(1)
 	}
 }
 
 // ReportData contains data for the generated report
 type ReportData struct {
 	WeekKey    string
+	Report     Report
 	ReportText string
+	Thread     []string
 	ShouldPost bool
+	// When the feeds were downloaded and the time span they cover, for the footer
+	FetchedAt  time.Time
+	FeedWindow string
 }
 
-// Check if a week has already been posted
-func wasWeekPosted(weekKey string) bool {
-	_, closer, err := db.Get([]byte(weekKey))
-	if errors.Is(err, pebble.ErrNotFound) {
-		return false
+// Texts to post, the report followed by its thread replies
+func (r ReportData) Posts() []string {
+	return append([]string{r.ReportText}, r.Thread...)
+}
+
+// Download and parse every feed and merge the results. A failing feed is
+// logged and skipped, only when all feeds fail an error is returned. The data
+// quality covers the feeds that were fetched.
+func fetchFeeds(ctx context.Context, urls []string) ([]Earthquake, DataQuality, error) {
+	quality := newDataQuality()
+	var feeds [][]Earthquake
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		earthquakes, feedQuality, err := fetchEarthquakes(ctx, url)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, quality, fmt.Errorf("fetching %s: %w", url, ctxErr)
+		}
+		if err != nil {
+			fmt.Printf("Error fetching %s: %v\n", url, err)
+			continue
+		}
+		feeds = append(feeds, earthquakes)
+		quality.merge(feedQuality)
+	}
+
+	if len(feeds) == 0 {
+		return nil, quality, fmt.Errorf("no feed could be fetched")
 	}
+	merged := mergeEarthquakes(feeds...)
+	quality.DuplicateIDs = quality.RowsParsed - len(merged)
+	quality.DataGaps = findDataGaps(eventTimes(merged))
+	printDataGaps(os.Stdout, quality.DataGaps)
+	return merged, quality, nil
+}
+
+func fetchEarthquakes(ctx context.Context, url string) ([]Earthquake, DataQuality, error) {
+	body, err := openFeed(ctx, url)
 	if err != nil {
-		fmt.Printf("Error checking if week was posted: %v\n", err)
-		return false
+		return nil, DataQuality{}, err
 	}
-	defer closer.Close()
-	return true
-}
+	defer body.Close()
 
-// Mark a week as posted
-func markWeekAsPosted(weekKey string) {
-	err := db.Set([]byte(weekKey), []byte("posted"), pebble.Sync)
+	quality := newDataQuality()
+	var earthquakes []Earthquake
+	err = scanFeed(url, body, &quality, func(eq Earthquake) error {
+		earthquakes = append(earthquakes, eq)
+		return nil
+	})
 	if err != nil {
-		fmt.Printf("Error marking week as posted: %v\n", err)
+		return nil, quality, err
 	}
+	return earthquakes, quality, nil
 }
 
-// Post the earthquake report to Bluesky
-func postToBluesky(reportText string) error {
-	// Get Bluesky credentials from environment variables
-	bskyConfig := BlueskyConfig{
-		Identifier: os.Getenv("BLUESKY_IDENTIFIER"),
-		Password:   os.Getenv("BLUESKY_PASSWORD"),
+// Open a feed for reading, from disk for local paths and over HTTP otherwise
+func openFeed(ctx context.Context, url string) (io.ReadCloser, error) {
+	if path, ok := feedFilePath(url); ok {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open CSV: %w", err)
+		}
+		return file, nil
 	}
 
-	if bskyConfig.Identifier == "" || bskyConfig.Password == "" {
-		return fmt.Errorf("missing Bluesky credentials in environment variables")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-
-	// Create a Bluesky client
-	host := os.Getenv("BLUESKY_HOST")
-	if host == "" {
-		host = "https://me.rasc.ch"
+	client := newHTTPClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download CSV: %w", err)
 	}
 
-	client := &xrpc.Client{
-		Host: host,
-		Auth: &xrpc.AuthInfo{},
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	// Log in to Bluesky
-	ctx := context.Background()
-	auth, err := atproto.ServerCreateSession(ctx, client, &atproto.ServerCreateSession_Input{
-		Identifier: bskyConfig.Identifier,
-		Password:   bskyConfig.Password,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to authenticate with Bluesky: %w", err)
+	// USGS serves maintenance pages as HTML with status 200
+	if contentType := resp.Header.Get("Content-Type"); strings.Contains(contentType, "html") {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected content type %q, the feed may be down", contentType)
 	}
+	return resp.Body, nil
+}
 
-	// Set auth info
-	client.Auth.AccessJwt = auth.AccessJwt
-	client.Auth.RefreshJwt = auth.RefreshJwt
-	client.Auth.Handle = auth.Handle
-	client.Auth.Did = auth.Did
+// Path of a feed on disk, given as a file:// URL or a plain path. Other URLs
+// are downloaded.
+func feedFilePath(url string) (string, bool) {
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		return path, true
+	}
+	return url, !strings.Contains(url, "://")
+}
 
-	// Create post
-	post := &bsky.FeedPost{
-		Text:      reportText,
-		CreatedAt: time.Now().Format(time.RFC3339),
+// Merge earthquakes from several feeds, keeping the first occurrence of each ID
+func mergeEarthquakes(feeds ...[]Earthquake) []Earthquake {
+	seen := make(map[string]bool)
+	var merged []Earthquake
+	for _, earthquakes := range feeds {
+		for _, eq := range earthquakes {
+			if eq.ID != "" {
+				if seen[eq.ID] {
+					continue
+				}
+				seen[eq.ID] = true
+			}
+			merged = append(merged, eq)
+		}
 	}
+	return merged
+}
+
+// Parse a USGS CSV feed. Mirrors that re-serialize the feed sometimes quote
+// sloppily, so bare quotes inside fields are accepted and the delimiter can be
+// changed with CSV_DELIMITER.
+func parseCSV(r io.Reader) ([]Earthquake, error) {
+	earthquakes, _, err := parseCSVQuality(r)
+	return earthquakes, err
+}
 
-	// Submit post
-	_, err = atproto.RepoCreateRecord(ctx, client, &atproto.RepoCreateRecord_Input{
-		Repo:       client.Auth.Did,
-		Collection: "app.bsky.feed.post",
-		Record:     &util.LexiconTypeDecoder{Val: post},
+// Parse a USGS CSV feed like parseCSV and describe the quality of its rows
+func parseCSVQuality(r io.Reader) ([]Earthquake, DataQuality, error) {
+	quality := newDataQuality()
+	var earthquakes []Earthquake
+	err := scanCSV(r, &quality, func(eq Earthquake) error {
+		earthquakes = append(earthquakes, eq)
+		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create post: %w", err)
+		return nil, quality, err
 	}
-
-	fmt.Println("Successfully posted earthquake report to Bluesky!")
-	return nil
+	return earthquakes, quality, nil
 }
 
-func parseCSV(r io.Reader) ([]Earthquake, error) {
-	reader := csv.NewReader(r)
+// Parse a USGS CSV feed row by row and call fn for every event, so large
+// feeds can be processed without holding all events. Rows are counted in
+// quality.
+func scanCSV(r io.Reader, quality *DataQuality, fn func(Earthquake) error) error {
+	reader := csv.NewReader(skipBOM(r))
 	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.Comma = csvDelimiter()
 
 	headers, err := reader.Read()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	// Some mirrors pad the header names
+	for i, h := range headers {
+		headers[i] = strings.TrimSpace(h)
+	}
+	if !slices.Contains(headers, "time") || !slices.Contains(headers, "mag") {
+		return fmt.Errorf("unexpected CSV header %q", strings.Join(headers, ","))
 	}
 
-	var earthquakes []Earthquake
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		quakeMap := make(map[string]string, len(headers))
@@ -221,28 +516,208 @@ func parseCSV(r io.Reader) ([]Earthquake, error) {
 			}
 		}
 
-		t, err := time.Parse(time.RFC3339Nano, quakeMap["time"])
+		t, layout, err := parseEventTime(quakeMap["time"])
 		if err != nil {
+			quality.RowsSkipped["invalidTime"]++
 			continue
 		}
+		if layout != time.RFC3339Nano && envBool("DEBUG") {
+			fmt.Printf("Parsed time %q of event %s with layout %q\n", quakeMap["time"], quakeMap["id"], layout)
+		}
 
 		mag, err := strconv.ParseFloat(quakeMap["mag"], 64)
 		if err != nil {
+			quality.RowsSkipped["invalidMagnitude"]++
 			continue
 		}
 
-		earthquakes = append(earthquakes, Earthquake{
+		eq := Earthquake{
+			ID:        quakeMap["id"],
 			Time:      t.UTC(),
 			Magnitude: mag,
 			Place:     quakeMap["place"],
-		})
+			Status:    quakeMap["status"],
+			Latitude:  parseOptionalFloat(quakeMap["latitude"]),
+			Longitude: parseOptionalFloat(quakeMap["longitude"]),
+			Depth:     parseOptionalFloat(quakeMap["depth"]),
+			Network:   quakeMap["net"],
+			MagType:   strings.ToLower(quakeMap["magType"]),
+		}
+		quality.add(eq)
+		if err := fn(eq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Layouts tried after RFC 3339 for event times of mirrors whose format drifted.
+// Times without a zone are UTC.
+var fallbackTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 MST",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05.999999999",
+}
+
+// Matches the seconds of a leap second such as "23:59:60" or "23:59:60.5"
+var leapSecondPattern = regexp.MustCompile(`([T ]\d{2}:\d{2}:)60(\D|$)`)
+
+// Parse an event time and return the layout that matched. A leap second,
+// 23:59:60, is read as the start of the next minute since Go rejects it.
+// Plain integers are milliseconds since the epoch, as in the GeoJSON feeds.
+func parseEventTime(value string) (time.Time, string, error) {
+	value = strings.TrimSpace(value)
+	leapSecond := leapSecondPattern.MatchString(value)
+	if leapSecond {
+		value = leapSecondPattern.ReplaceAllString(value, "${1}59${2}")
+	}
+
+	for _, layout := range append([]string{time.RFC3339Nano}, fallbackTimeLayouts...) {
+		if t, err := time.Parse(layout, value); err == nil {
+			if leapSecond {
+				t = t.Add(time.Second)
+			}
+			return t, layout, nil
+		}
+	}
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis).UTC(), "unix milliseconds", nil
+	}
+	return time.Time{}, "", fmt.Errorf("unrecognized time %q", value)
+}
+
+// Skip the UTF-8 byte order mark that some mirrors write before the header
+func skipBOM(r io.Reader) io.Reader {
+	buffered := bufio.NewReader(r)
+	if bom, err := buffered.Peek(3); err == nil && string(bom) == "\uFEFF" {
+		buffered.Discard(3)
+	}
+	return buffered
+}
+
+// Read the field delimiter from CSV_DELIMITER, a single character or "tab".
+// Unset or invalid means a comma.
+func csvDelimiter() rune {
+	value := os.Getenv("CSV_DELIMITER")
+	if value == "tab" {
+		return '\t'
 	}
-	return earthquakes, nil
+	delimiter, size := utf8.DecodeRuneInString(value)
+	if value == "" || size != len(value) || delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError {
+		if value != "" {
+			fmt.Printf("Ignoring invalid CSV_DELIMITER %q\n", value)
+		}
+		return ','
+	}
+	return delimiter
+}
+
+// Parse an optional numeric column, returning NaN when it is empty or invalid
+func parseOptionalFloat(value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
 }
 
+// Keep only events with status "reviewed". Automatic events still carry
+// preliminary magnitudes that USGS may revise, but a week's recent events are
+// mostly automatic, so filtering lowers the totals of the report.
+func filterReviewed(earthquakes []Earthquake) []Earthquake {
+	var reviewed []Earthquake
+	for _, eq := range earthquakes {
+		if eq.Status == "reviewed" {
+			reviewed = append(reviewed, eq)
+		}
+	}
+	return reviewed
+}
+
+// Parse NETWORK_MIN_MAG, a comma-separated list of network:magnitude pairs
+// such as "ak:2.5,ci:1.5". Unset means no per-network filtering.
+func networkThresholds() (map[string]float64, error) {
+	thresholds := make(map[string]float64)
+	for pair := range strings.SplitSeq(os.Getenv("NETWORK_MIN_MAG"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		network, magStr, found := strings.Cut(pair, ":")
+		mag, err := strconv.ParseFloat(strings.TrimSpace(magStr), 64)
+		if !found || err != nil {
+			return nil, fmt.Errorf("invalid network threshold %q", pair)
+		}
+		thresholds[strings.ToLower(strings.TrimSpace(network))] = mag
+	}
+	return thresholds, nil
+}
+
+// Drop events below the minimum magnitude of their network. Local networks
+// record many small events that would otherwise dominate the global counts.
+// Networks without a threshold are kept as they are.
+func filterByNetwork(earthquakes []Earthquake, thresholds map[string]float64) []Earthquake {
+	if len(thresholds) == 0 {
+		return earthquakes
+	}
+	var kept []Earthquake
+	for _, eq := range earthquakes {
+		if minMag, ok := thresholds[strings.ToLower(eq.Network)]; ok && eq.Magnitude < minMag {
+			continue
+		}
+		kept = append(kept, eq)
+	}
+	return kept
+}
+
+// Read a boolean environment variable, treating unset or invalid values as false
+func envBool(name string) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && value
+}
+
+// Read an integer environment variable, falling back to def when unset or invalid
+func envInt(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// A complete week worldwide has well over a thousand events, so a total below
+// MIN_WEEKLY_EVENTS means the feed was truncated rather than the week was quiet
+func checkEventFloor(report Report) error {
+	minEvents := envInt("MIN_WEEKLY_EVENTS", defaultMinWeeklyEvents)
+	if report.Total < minEvents {
+		return fmt.Errorf("week %s has only %d events, expected at least %d", report.WeekKey, report.Total, minEvents)
+	}
+	return nil
+}
+
+// Location of the week boundaries from WEEK_TZ. Weeks run from Monday 00:00
+// to Sunday 24:00 in this location; UTC by default.
+var weekLocation = time.UTC
+
+// Load WEEK_TZ, an IANA time zone such as "America/Los_Angeles"
+func weekZone() (*time.Location, error) {
+	name := strings.TrimSpace(os.Getenv("WEEK_TZ"))
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// Boundaries, ISO year and ISO week of the week containing t. The boundaries
+// are computed in weekLocation and returned in UTC for storage.
 func getWeekBoundaries(t time.Time) (time.Time, time.Time, int, int) {
-	// Convert to UTC
-	t = t.UTC()
+	t = t.In(weekLocation)
 
 	// Adjust to Monday-start week (1=Monday, 0=Sunday)
 	weekday := int(t.Weekday())
@@ -250,17 +725,26 @@ func getWeekBoundaries(t time.Time) (time.Time, time.Time, int, int) {
 		weekday = 7
 	}
 
-	// Calculate the start of the week (Monday 00:00:00 UTC)
-	startOfDay := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	// Calculate the start of the week (Monday 00:00:00). The start is
+	// inclusive, an event at exactly Monday 00:00:00 begins the new week.
+	startOfDay := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, weekLocation)
 	start := startOfDay.AddDate(0, 0, -(weekday - 1))
 
-	// End of week is Sunday 23:59:59 UTC
-	end := start.AddDate(0, 0, 6).Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	// End of week is Sunday 23:59:59, the last whole second of the week.
+	// Events up to the next Monday 00:00:00, exclusive, still belong to it.
+	// Adding days in the week's location keeps DST weeks at 167 or 169 hours.
+	end := start.AddDate(0, 0, 7).Add(-time.Second)
 
 	// Get ISO year and week number based on Thursday
 	year, week := start.AddDate(0, 0, 3).ISOWeek()
 
-	return start, end, year, week
+	return start.UTC(), end.UTC(), year, week
+}
+
+// Start of the week after the week containing t
+func nextWeekStart(t time.Time) time.Time {
+	_, end, _, _ := getWeekBoundaries(t)
+	return end.Add(time.Second)
 }
 
 func categorizeMagnitude(mag float64) int {
@@ -282,12 +766,42 @@ func categorizeMagnitude(mag float64) int {
 	}
 }
 
+// Depth bands: shallow < 70 km, intermediate 70 - 300 km, deep > 300 km,
+// and unknown for events without a depth
+func categorizeDepth(depth float64) int {
+	switch {
+	case math.IsNaN(depth):
+		return 3
+	case depth < 70:
+		return 0
+	case depth <= 300:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Count an event in the stats
+func (s *WeekStats) add(eq Earthquake) {
+	s.Counts[categorizeMagnitude(eq.Magnitude)]++
+	s.MagnitudeSum += eq.Magnitude
+	s.DepthCounts[categorizeDepth(eq.Depth)]++
+	s.HourCounts[eq.Time.UTC().Hour()]++
+	s.Events = append(s.Events, eq)
+	if eq.Time.After(s.Latest) {
+		s.Latest = eq.Time
+	}
+	if eq.Magnitude > s.Largest.Magnitude || s.Largest.Time.IsZero() {
+		s.Largest = eq
+	}
+}
+
 func groupByWeek(earthquakes []Earthquake) map[string]WeekStats {
 	weeklyStats := make(map[string]WeekStats)
 
 	for _, eq := range earthquakes {
 		start, end, year, weekNum := getWeekBoundaries(eq.Time)
-		weekKey := fmt.Sprintf("%d-W%02d", year, weekNum)
+		weekKey := formatWeekKey(year, weekNum)
 
 		stats, exists := weeklyStats[weekKey]
 		if !exists {
@@ -299,22 +813,131 @@ func groupByWeek(earthquakes []Earthquake) map[string]WeekStats {
 			}
 		}
 
-		category := categorizeMagnitude(eq.Magnitude)
-		stats.Counts[category]++
-
+		stats.add(eq)
 		weeklyStats[weekKey] = stats
 	}
 
 	return weeklyStats
 }
 
+// Build the report of a week, including comparisons with stored history
+func buildReport(store *Store, weekKey string, stats WeekStats) Report {
+	report := newReport(weekKey, stats)
+	if yearAgo, ok := store.LoadWeekStats(yearAgoWeekKey(stats.Year, stats.WeekNum)); ok {
+		yearAgoTotal := yearAgo.ReportedTotal()
+		report.YearAgoTotal = &yearAgoTotal
+	}
+	if previous, ok := store.LoadWeekStats(previousWeekKey(stats.StartDate)); ok && previous.Total() > 0 {
+		previousLargest := previous.Largest.Magnitude
+		report.PreviousLargest = &previousLargest
+	}
+	addEventSections(&report, stats)
+	report.Milestone = findMilestone(store, stats)
+	return report
+}
+
+// Add the sections computed from the events themselves
+func addEventSections(report *Report, stats WeekStats) {
+	if envBool("DEPTH_BREAKDOWN") {
+		report.DepthBands = newDepthBands(stats.DepthCounts)
+	}
+	if envBool("CONTINENT_BREAKDOWN") {
+		report.Continents = groupByContinent(stats.Events)
+	}
+	if envBool("HOUR_BREAKDOWN") {
+		report.HourCounts = hourCounts(stats)
+	}
+	if envBool("MAG_TYPE_BREAKDOWN") {
+		report.MagTypes = groupByMagType(stats.Events)
+	}
+	report.WidelyFelt = widelyFelt(stats.Events, envInt("FELT_THRESHOLD", defaultFeltThreshold))
+	if lat, lon, ok := homeLocation(); ok {
+		report.Closest = closestEvent(stats.Events, lat, lon)
+	}
+	if ranking := groupByRegion(stats.Events, stats.EndDate, regionHalfLife()); len(ranking) > 0 {
+		report.MostActive = &ranking[0]
+	}
+	report.Percentiles = magnitudePercentiles(stats.Events)
+	report.InterEvent = interEventStats(eventTimes(stats.Events))
+	report.Histogram = magnitudeHistogram(stats.Events)
+	report.BValue = weeklyBValue(stats.Events)
+	report.DepthCorrelation = depthCorrelation(stats.Events)
+	report.Aftershocks = weeklyDeclustering(stats.Events)
+}
+
+// Print the report of a stored week without posting it
+func regenerateWeek(store *Store, w io.Writer, weekKey string, format string, tmpl *template.Template) error {
+	stats, ok := store.LoadWeekStats(weekKey)
+	if !ok {
+		return fmt.Errorf("no stored stats for week %s", weekKey)
+	}
+
+	report := buildReport(store, weekKey, stats)
+	reportText, err := renderPostText(report, format, tmpl)
+	if err != nil {
+		return err
+	}
+	return printReport(w, ReportData{WeekKey: weekKey, Report: report, ReportText: reportText}, format)
+}
+
+// Render the epicenter map to a PNG file and print its alt text
+func writeEpicenterMap(path string, events []Earthquake) error {
+	data, err := renderEpicenterMap(events)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write map: %w", err)
+	}
+	fmt.Printf("Map written to %s: %s\n", path, epicenterMapAlt(events))
+	return nil
+}
+
+// Total number of earthquakes in the week
+func (s WeekStats) Total() int {
+	var total int
+	for _, count := range s.Counts {
+		total += count
+	}
+	return total
+}
+
+// Total shown in the report. With EXCLUDE_MICRO, events below M2.0 are left
+// out, they are still part of Counts for other calculations.
+func (s WeekStats) ReportedTotal() int {
+	if envBool("EXCLUDE_MICRO") {
+		return s.Total() - s.Counts[0]
+	}
+	return s.Total()
+}
+
+// Key of the week before the week starting at start
+func previousWeekKey(start time.Time) string {
+	// A day before the start is in the previous week whatever its length
+	_, _, year, week := getWeekBoundaries(start.Add(-24 * time.Hour))
+	return formatWeekKey(year, week)
+}
+
+// Key of the same ISO week in the previous year. Week 53 maps to week 52
+// when the previous year has only 52 ISO weeks.
+func yearAgoWeekKey(year, week int) string {
+	prevYear := year - 1
+	_, weeksInPrevYear := time.Date(prevYear, 12, 28, 0, 0, 0, 0, time.UTC).ISOWeek()
+	if week > weeksInPrevYear {
+		week = weeksInPrevYear
+	}
+	return formatWeekKey(prevYear, week)
+}
+
 func getFullWeeks(weekStats map[string]WeekStats) map[string]WeekStats {
-	now := time.Now().UTC()
+	current := now().UTC()
 	fullWeeks := make(map[string]WeekStats)
 
 	for key, stats := range weekStats {
-		// Only include weeks that have already ended
-		if stats.EndDate.Before(now) {
+		// Only include weeks that have already ended. EndDate is a whole
+		// second, so compare with the exclusive end to keep events in the
+		// last second of Sunday out of a week reported as complete.
+		if !current.Before(stats.EndDate.Add(time.Second)) {
 			fullWeeks[key] = stats
 		}
 	}
@@ -322,13 +945,13 @@ func getFullWeeks(weekStats map[string]WeekStats) map[string]WeekStats {
 	return fullWeeks
 }
 
-func generateReports(weeklyStats map[string]WeekStats) ReportData {
+func generateReports(store *Store, weeklyStats map[string]WeekStats) ReportData {
 	// Sort weeks chronologically
 	var weeks []string
 	for week := range weeklyStats {
 		weeks = append(weeks, week)
 	}
-	sort.Strings(weeks)
+	sortWeekKeys(weeks)
 
 	// Only report the last week
 	if len(weeks) == 0 {
@@ -339,44 +962,19 @@ func generateReports(weeklyStats map[string]WeekStats) ReportData {
 	stats := weeklyStats[lastWeek]
 
 	// Check if this week was already posted
-	if wasWeekPosted(lastWeek) {
+	if store.WasWeekPosted(lastWeek) {
 		return ReportData{
 			WeekKey:    lastWeek,
 			ShouldPost: false,
 		}
 	}
 
-	startTimeStr := stats.StartDate.Format(time.RFC3339)[:19] + "Z"
-	endTimeStr := stats.EndDate.Format(time.RFC3339)[:19] + "Z"
-
-	categories := []string{
-		"Micro < 2.0",
-		"Minor 2.0 - 3.9",
-		"Light 4.0 - 4.9",
-		"Moderate 5.0 - 5.9",
-		"Strong 6.0 - 6.9",
-		"Major 7.0 - 7.9",
-		"Great >= 8.0",
-	}
-
-	// Build report text
-	var reportText strings.Builder
-	reportText.WriteString("Weekly Earthquake Report\n")
-	reportText.WriteString(fmt.Sprintf("%s (%s - %s)\n\n", lastWeek, startTimeStr, endTimeStr))
-
-	var total int
-	for i, count := range stats.Counts {
-		reportText.WriteString(fmt.Sprintf("%s: %d\n", categories[i], count))
-		total += count
-	}
-	reportText.WriteString(fmt.Sprintf("\nTotal: %d", total))
-
-	// Print report to console as well
-	fmt.Println(reportText.String())
+	report := buildReport(store, lastWeek, stats)
 
 	return ReportData{
 		WeekKey:    lastWeek,
-		ReportText: reportText.String(),
+		Report:     report,
+		ReportText: renderText(report),
 		ShouldPost: true,
 	}
 }
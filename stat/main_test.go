@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
 )
 
 func TestParseCSVUsesHeadersAndSkipsShortRows(t *testing.T) {
@@ -30,3 +39,557 @@ func TestParseCSVUsesHeadersAndSkipsShortRows(t *testing.T) {
 		t.Fatalf("expected UTC time, got %v", quakes[0].Time.Location())
 	}
 }
+
+func TestFilterReviewedDropsAutomaticEvents(t *testing.T) {
+	csv := `time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status
+2026-06-08T10:11:12Z,1,2,3,4.4,ml,,,,,us,us1,,Reviewed Place,earthquake,reviewed
+2026-06-08T11:11:12Z,1,2,3,2.1,ml,,,,,us,us2,,Automatic Place,earthquake,automatic
+2026-06-08T12:11:12Z,1,2,3,5.0,mb,,,,,us,us3,,Other Reviewed Place,earthquake,reviewed
+`
+
+	quakes, err := parseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("parseCSV returned error: %v", err)
+	}
+	if quakes[1].Status != "automatic" {
+		t.Fatalf("expected status automatic, got %q", quakes[1].Status)
+	}
+
+	reviewed := filterReviewed(quakes)
+	if len(reviewed) != 2 {
+		t.Fatalf("expected 2 reviewed earthquakes, got %d", len(reviewed))
+	}
+	for _, q := range reviewed {
+		if q.Status != "reviewed" {
+			t.Fatalf("expected only reviewed events, got %q", q.Status)
+		}
+	}
+}
+
+func setNow(t *testing.T, instant time.Time) {
+	t.Helper()
+	original := now
+	now = func() time.Time { return instant }
+	t.Cleanup(func() { now = original })
+}
+
+func TestGetFullWeeksUsesInjectedClock(t *testing.T) {
+	quakes := []Earthquake{
+		{Time: time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC), Magnitude: 3.1},
+		{Time: time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC), Magnitude: 4.2},
+	}
+	weeks := groupByWeek(quakes)
+
+	setNow(t, time.Date(2026, 6, 10, 0, 0, 0, 0, time.UTC))
+	fullWeeks := getFullWeeks(weeks)
+
+	if len(fullWeeks) != 1 {
+		t.Fatalf("expected 1 full week, got %d", len(fullWeeks))
+	}
+	if _, ok := fullWeeks["2026-W23"]; !ok {
+		t.Fatalf("expected 2026-W23 to be complete, got %v", fullWeeks)
+	}
+}
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := openStore(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestYearAgoWeekKeyHandlesWeek53(t *testing.T) {
+	tests := []struct {
+		year, week int
+		want       string
+	}{
+		{2027, 1, "2026-W01"},
+		{2026, 53, "2025-W52"},
+		{2027, 52, "2026-W52"},
+		{2021, 53, "2020-W53"},
+	}
+	for _, tt := range tests {
+		if got := yearAgoWeekKey(tt.year, tt.week); got != tt.want {
+			t.Errorf("yearAgoWeekKey(%d, %d) = %q, want %q", tt.year, tt.week, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateReportsComparesToSameWeekLastYear(t *testing.T) {
+	store := openTestStore(t)
+	setNow(t, time.Date(2027, 1, 5, 0, 0, 0, 0, time.UTC))
+
+	// 2026-W53 runs from Monday 2026-12-28 to Sunday 2027-01-03
+	weeks := groupByWeek([]Earthquake{
+		{Time: time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC), Magnitude: 4.5},
+		{Time: time.Date(2027, 1, 2, 12, 0, 0, 0, time.UTC), Magnitude: 2.5},
+		{Time: time.Date(2027, 1, 3, 12, 0, 0, 0, time.UTC), Magnitude: 3.5},
+	})
+	if _, ok := weeks["2026-W53"]; !ok {
+		t.Fatalf("expected events to be grouped into 2026-W53, got %v", weeks)
+	}
+
+	reportData := generateReports(store, getFullWeeks(weeks))
+	if strings.Contains(reportData.ReportText, "Same week last year") {
+		t.Fatalf("expected no year-ago line without history, got:\n%s", reportData.ReportText)
+	}
+
+	store.StoreWeekStats("2025-W52", WeekStats{Counts: [7]int{0, 4, 1}})
+
+	reportData = generateReports(store, getFullWeeks(weeks))
+	if !strings.Contains(reportData.ReportText, "Same week last year: 5 (-2)") {
+		t.Fatalf("expected year-ago comparison line, got:\n%s", reportData.ReportText)
+	}
+}
+
+func TestRegenerateWeekFromStoredStats(t *testing.T) {
+	store := openTestStore(t)
+
+	var out strings.Builder
+	if err := regenerateWeek(store, &out, "2026-W23", "text", nil); err == nil {
+		t.Fatal("expected an error for a week without stored stats")
+	}
+
+	weeks := groupByWeek([]Earthquake{
+		{Time: time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC), Magnitude: 3.1, Place: "Test"},
+	})
+	store.StoreWeekStats("2026-W23", weeks["2026-W23"])
+
+	if err := regenerateWeek(store, &out, "2026-W23", "text", nil); err != nil {
+		t.Fatalf("regenerateWeek returned error: %v", err)
+	}
+	want := renderText(newReport("2026-W23", weeks["2026-W23"])) + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected regenerated report:\nwant %q\ngot  %q", want, out.String())
+	}
+}
+
+func TestFetchFeedsMergesAndDeduplicatesByID(t *testing.T) {
+	const header = "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n"
+	feeds := map[string]string{
+		"/world.csv": header +
+			"2026-06-08T10:00:00Z,1,2,3,4.4,mb,,,,,us,us1,,World One,earthquake,reviewed\n" +
+			"2026-06-08T11:00:00Z,1,2,3,5.1,mb,,,,,us,us2,,World Two,earthquake,reviewed\n",
+		"/regional.csv": header +
+			"2026-06-08T11:00:00Z,1,2,3,5.1,mb,,,,,us,us2,,Regional Two,earthquake,reviewed\n" +
+			"2026-06-08T12:00:00Z,1,2,3,1.2,ml,,,,,ci,ci3,,Regional Three,earthquake,automatic\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := feeds[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	quakes, _, err := fetchFeeds(context.Background(), []string{server.URL + "/world.csv", " " + server.URL + "/regional.csv", server.URL + "/missing.csv"})
+	if err != nil {
+		t.Fatalf("fetchFeeds returned error: %v", err)
+	}
+
+	if len(quakes) != 3 {
+		t.Fatalf("expected 3 merged earthquakes, got %d", len(quakes))
+	}
+	if quakes[1].ID != "us2" || quakes[1].Place != "World Two" {
+		t.Fatalf("expected first occurrence of us2 to be kept, got %+v", quakes[1])
+	}
+
+	if _, _, err := fetchFeeds(context.Background(), []string{server.URL + "/missing.csv"}); err == nil {
+		t.Fatal("expected an error when no feed can be fetched")
+	}
+}
+
+func TestCategorizeDepthBoundaries(t *testing.T) {
+	tests := []struct {
+		depth float64
+		want  int
+	}{
+		{0, 0},
+		{69.99, 0},
+		{70, 1},
+		{300, 1},
+		{300.01, 2},
+		{650, 2},
+		{math.NaN(), 3},
+	}
+	for _, tt := range tests {
+		if got := categorizeDepth(tt.depth); got != tt.want {
+			t.Errorf("categorizeDepth(%v) = %d, want %d", tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestDepthBandsShowUnknownOnlyWhenPresent(t *testing.T) {
+	if bands := newDepthBands([4]int{5, 2, 1, 0}); len(bands) != 3 {
+		t.Fatalf("expected unknown band to be hidden, got %v", bands)
+	}
+	bands := newDepthBands([4]int{5, 2, 1, 4})
+	if len(bands) != 4 || bands[3].Label != "Unknown depth" || bands[3].Count != 4 {
+		t.Fatalf("expected unknown band with 4 events, got %v", bands)
+	}
+}
+
+func TestFetchEarthquakesRejectsHTMLMaintenancePage(t *testing.T) {
+	const page = "<!DOCTYPE html>\n<html><body>Scheduled maintenance</body></html>\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/typed.csv" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "text/csv")
+		}
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	if _, _, err := fetchEarthquakes(context.Background(), server.URL+"/typed.csv"); err == nil || !strings.Contains(err.Error(), "content type") {
+		t.Fatalf("expected a content type error, got %v", err)
+	}
+	if _, _, err := fetchEarthquakes(context.Background(), server.URL+"/mislabeled.csv"); err == nil || !strings.Contains(err.Error(), "unexpected CSV header") {
+		t.Fatalf("expected a header error, got %v", err)
+	}
+}
+
+func TestCheckEventFloorRejectsSuspiciouslySmallWeeks(t *testing.T) {
+	report := Report{WeekKey: "2026-W23", Total: 12}
+	if err := checkEventFloor(report); err == nil {
+		t.Fatal("expected the default floor to reject a week with 12 events")
+	}
+
+	report.Total = defaultMinWeeklyEvents
+	if err := checkEventFloor(report); err != nil {
+		t.Fatalf("expected a week at the floor to pass, got %v", err)
+	}
+
+	t.Setenv("MIN_WEEKLY_EVENTS", "10")
+	report.Total = 12
+	if err := checkEventFloor(report); err != nil {
+		t.Fatalf("expected a configured floor of 10 to accept 12 events, got %v", err)
+	}
+}
+
+func TestFilterByNetworkAppliesPerNetworkThresholds(t *testing.T) {
+	t.Setenv("NETWORK_MIN_MAG", "ak:2.5, CI:1.5")
+	thresholds, err := networkThresholds()
+	if err != nil {
+		t.Fatalf("networkThresholds returned error: %v", err)
+	}
+
+	header := "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n"
+	earthquakes, err := parseCSV(strings.NewReader(header +
+		"2026-06-02T10:00:00Z,1,2,3,1.8,ml,,,,,ak,ak1,,Alaska,earthquake,reviewed\n" +
+		"2026-06-02T11:00:00Z,1,2,3,2.7,ml,,,,,ak,ak2,,Alaska,earthquake,reviewed\n" +
+		"2026-06-02T12:00:00Z,1,2,3,1.2,ml,,,,,ci,ci1,,California,earthquake,reviewed\n" +
+		"2026-06-02T13:00:00Z,1,2,3,1.6,ml,,,,,ci,ci2,,California,earthquake,reviewed\n" +
+		"2026-06-02T14:00:00Z,1,2,3,0.9,ml,,,,,nc,nc1,,California,earthquake,reviewed\n"))
+	if err != nil {
+		t.Fatalf("parseCSV returned error: %v", err)
+	}
+
+	var ids []string
+	for _, eq := range filterByNetwork(earthquakes, thresholds) {
+		ids = append(ids, eq.ID)
+	}
+	if strings.Join(ids, ",") != "ak2,ci2,nc1" {
+		t.Fatalf("expected ak2,ci2,nc1 to pass the thresholds, got %v", ids)
+	}
+
+	t.Setenv("NETWORK_MIN_MAG", "ak")
+	if _, err := networkThresholds(); err == nil {
+		t.Fatal("expected an error for a threshold without magnitude")
+	}
+}
+
+func TestParseCSVToleratesLazyQuotesAndCustomDelimiter(t *testing.T) {
+	header := "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n"
+	earthquakes, err := parseCSV(strings.NewReader(header +
+		`2026-06-02T10:00:00Z,1,2,3,4.1,mb,,,,,us,us1,,"10 km "E" of Foo, Chile",earthquake,reviewed` + "\n" +
+		`2026-06-02T11:00:00Z,1,2,3,2.2,ml,,,,,ak,ak1,,5 km N of "Old" Town,earthquake,reviewed` + "\n"))
+	if err != nil {
+		t.Fatalf("parseCSV returned error: %v", err)
+	}
+	if len(earthquakes) != 2 {
+		t.Fatalf("expected 2 earthquakes, got %d", len(earthquakes))
+	}
+	if earthquakes[0].Place != `10 km "E" of Foo, Chile` || earthquakes[0].Status != "reviewed" {
+		t.Fatalf("unexpected lazily quoted row %+v", earthquakes[0])
+	}
+	if earthquakes[1].Place != `5 km N of "Old" Town` {
+		t.Fatalf("unexpected bare quotes %+v", earthquakes[1])
+	}
+
+	t.Setenv("CSV_DELIMITER", ";")
+	earthquakes, err = parseCSV(strings.NewReader(strings.ReplaceAll(header, ",", ";") +
+		"2026-06-02T12:00:00Z;1;2;3;3.3;ml;;;;;nc;nc1;;8 km W of Cobb, CA;earthquake;reviewed\n"))
+	if err != nil {
+		t.Fatalf("parseCSV returned error: %v", err)
+	}
+	if len(earthquakes) != 1 || earthquakes[0].Place != "8 km W of Cobb, CA" || earthquakes[0].Magnitude != 3.3 {
+		t.Fatalf("unexpected semicolon-delimited rows %+v", earthquakes)
+	}
+}
+
+func TestParseCSVStripsBOMAndPaddedHeaders(t *testing.T) {
+	earthquakes, err := parseCSV(strings.NewReader("\uFEFF time , latitude,longitude,depth, mag ,magType,net, id ,place,status\n" +
+		"2026-06-02T10:00:00Z,1,2,3,4.1,mb,us,us1,\"10 km E of Foo, Chile\",reviewed\n"))
+	if err != nil {
+		t.Fatalf("parseCSV returned error: %v", err)
+	}
+	if len(earthquakes) != 1 {
+		t.Fatalf("expected 1 earthquake, got %d", len(earthquakes))
+	}
+	eq := earthquakes[0]
+	if eq.ID != "us1" || eq.Magnitude != 4.1 || eq.Place != "10 km E of Foo, Chile" || eq.Time.Hour() != 10 {
+		t.Fatalf("unexpected earthquake %+v", eq)
+	}
+}
+
+func TestUnmarkWeekAsPostedDeletesDedupeKey(t *testing.T) {
+	store := openTestStore(t)
+
+	if err := store.UnmarkWeekPosted("2026-W23"); err == nil {
+		t.Fatal("expected an error for a week that was never posted")
+	}
+
+	for _, weekKey := range []string{"2026-W23", "2026-W24"} {
+		store.MarkWeekPosted(weekKey)
+		if err := store.SavePostedWeek(weekKey, PostedWeek{}); err != nil {
+			t.Fatalf("SavePostedWeek returned error: %v", err)
+		}
+		if err := store.SaveThreadProgress(weekKey, []*atproto.RepoStrongRef{{Uri: "at://post", Cid: "cid"}}); err != nil {
+			t.Fatalf("SaveThreadProgress returned error: %v", err)
+		}
+		if err := store.QueueOutputRetry("markdown", ReportData{WeekKey: weekKey}); err != nil {
+			t.Fatalf("QueueOutputRetry returned error: %v", err)
+		}
+	}
+	if err := store.UnmarkWeekPosted("2026-W23"); err != nil {
+		t.Fatalf("UnmarkWeekPosted returned error: %v", err)
+	}
+	if store.WasWeekPosted("2026-W23") {
+		t.Fatal("expected 2026-W23 to be unmarked")
+	}
+	if _, ok, _ := store.LoadPostedWeek("2026-W23"); ok {
+		t.Fatal("expected the posted report of 2026-W23 to be deleted")
+	}
+	if posted, _ := store.LoadThreadProgress("2026-W23"); posted != nil {
+		t.Fatal("expected the thread progress of 2026-W23 to be deleted")
+	}
+	queued, err := store.QueuedOutputRetries()
+	if err != nil || len(queued["markdown"]) != 1 || queued["markdown"][0].WeekKey != "2026-W24" {
+		t.Fatalf("expected only the retry of 2026-W24 to stay queued, got %v (err %v)", queued, err)
+	}
+
+	if !store.WasWeekPosted("2026-W24") {
+		t.Fatal("expected other weeks to stay marked")
+	}
+	if _, ok, _ := store.LoadPostedWeek("2026-W24"); !ok {
+		t.Fatal("expected the posted report of other weeks to stay")
+	}
+}
+
+// Compute week boundaries in the named zone for the rest of the test
+func setWeekZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	t.Setenv("WEEK_TZ", name)
+	loc, err := weekZone()
+	if err != nil {
+		t.Fatalf("weekZone returned error: %v", err)
+	}
+	weekLocation = loc
+	t.Cleanup(func() { weekLocation = time.UTC })
+	return loc
+}
+
+func TestWeekZoneBucketsAcrossDSTChange(t *testing.T) {
+	zurich := setWeekZone(t, "Europe/Zurich")
+
+	// Clocks in Zurich go forward on Sunday 2026-03-29, so week 13 has 167 hours
+	quakes := []Earthquake{
+		{ID: "a", Time: time.Date(2026, 3, 22, 23, 30, 0, 0, time.UTC), Magnitude: 2.1}, // Monday 00:30 CET
+		{ID: "b", Time: time.Date(2026, 3, 29, 21, 30, 0, 0, time.UTC), Magnitude: 3.1}, // Sunday 23:30 CEST
+		{ID: "c", Time: time.Date(2026, 3, 29, 22, 30, 0, 0, time.UTC), Magnitude: 4.1}, // Monday 00:30 CEST
+	}
+	weeks := groupByWeek(quakes)
+
+	w13, w14 := weeks["2026-W13"], weeks["2026-W14"]
+	if w13.Total() != 2 || w14.Total() != 1 {
+		t.Fatalf("expected 2 events in W13 and 1 in W14, got %v", weeks)
+	}
+	if !w13.StartDate.Equal(time.Date(2026, 3, 23, 0, 0, 0, 0, zurich)) || w13.StartDate.Location() != time.UTC {
+		t.Fatalf("expected W13 to start Monday 00:00 CET, stored in UTC, got %v", w13.StartDate)
+	}
+	if length := w13.EndDate.Add(time.Second).Sub(w13.StartDate); length != 167*time.Hour {
+		t.Fatalf("expected a 167 hour week, got %v", length)
+	}
+	if !w14.StartDate.Equal(time.Date(2026, 3, 30, 0, 0, 0, 0, zurich)) {
+		t.Fatalf("expected W14 to start Monday 00:00 CEST, got %v", w14.StartDate)
+	}
+
+	if got := previousWeekKey(w14.StartDate); got != "2026-W13" {
+		t.Fatalf("expected W13 before W14, got %s", got)
+	}
+	if got := weekStart("2026-W14"); !got.Equal(w14.StartDate) {
+		t.Fatalf("expected weekStart to match the boundaries, got %v", got)
+	}
+	if got := nextWeekStart(w13.StartDate); !got.Equal(w14.StartDate) {
+		t.Fatalf("expected the week after W13 to start at %v, got %v", w14.StartDate, got)
+	}
+
+	setNow(t, w14.StartDate.Add(-time.Second))
+	if _, ok := getFullWeeks(weeks)["2026-W13"]; ok {
+		t.Fatal("expected W13 to be incomplete before Monday 00:00 local time")
+	}
+	setNow(t, w14.StartDate)
+	if _, ok := getFullWeeks(weeks)["2026-W13"]; !ok {
+		t.Fatal("expected W13 to be complete at Monday 00:00 local time")
+	}
+
+	// Without REPORT_TZ the week is displayed in its own zone
+	texts, err := renderZoneVariants(newReport("2026-W13", w13), nil, "text", nil)
+	if err != nil {
+		t.Fatalf("renderZoneVariants returned error: %v", err)
+	}
+	if !strings.Contains(texts[0], "(Europe/Zurich)\n2026-W13 (2026-03-23 00:00 CET - 2026-03-29 23:59 CEST)") {
+		t.Fatalf("expected the week in local time, got %q", texts[0])
+	}
+
+	t.Setenv("WEEK_TZ", "Mars/Olympus_Mons")
+	if _, err := weekZone(); err == nil {
+		t.Fatal("expected an error for an unknown time zone")
+	}
+}
+
+func TestWeekBoundariesAtMondayMidnightAndSundayEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		instant time.Time
+		weekKey string
+	}{
+		{"Monday midnight starts the week", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), "2026-W23"},
+		{"last nanosecond before Monday", time.Date(2026, 5, 31, 23, 59, 59, 999999999, time.UTC), "2026-W22"},
+		{"Sunday 23:59:59 ends the week", time.Date(2026, 6, 7, 23, 59, 59, 0, time.UTC), "2026-W23"},
+		{"next Monday midnight", time.Date(2026, 6, 8, 0, 0, 0, 0, time.UTC), "2026-W24"},
+		{"Monday midnight in another zone", time.Date(2026, 6, 8, 2, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), "2026-W24"},
+	}
+	for _, tt := range tests {
+		start, end, year, week := getWeekBoundaries(tt.instant)
+		if got := fmt.Sprintf("%d-W%02d", year, week); got != tt.weekKey {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.weekKey, got)
+		}
+		if tt.instant.Before(start) || !tt.instant.Before(start.AddDate(0, 0, 7)) {
+			t.Errorf("%s: %v outside week %v - %v", tt.name, tt.instant, start, end)
+		}
+		if start.Weekday() != time.Monday || start.Hour() != 0 || end.Sub(start) != 7*24*time.Hour-time.Second {
+			t.Errorf("%s: unexpected boundaries %v - %v", tt.name, start, end)
+		}
+	}
+}
+
+func TestGetFullWeeksWaitsForTheLastSecondOfSunday(t *testing.T) {
+	weeks := groupByWeek([]Earthquake{
+		{Time: time.Date(2026, 6, 7, 23, 59, 59, 500000000, time.UTC), Magnitude: 3.1},
+	})
+
+	setNow(t, time.Date(2026, 6, 7, 23, 59, 59, 700000000, time.UTC))
+	if fullWeeks := getFullWeeks(weeks); len(fullWeeks) != 0 {
+		t.Fatalf("expected 2026-W23 to be incomplete until Monday, got %v", fullWeeks)
+	}
+
+	setNow(t, time.Date(2026, 6, 8, 0, 0, 0, 0, time.UTC))
+	if _, ok := getFullWeeks(weeks)["2026-W23"]; !ok {
+		t.Fatal("expected 2026-W23 to be complete at Monday midnight")
+	}
+}
+
+func TestCategorizeMagnitudeBoundaries(t *testing.T) {
+	tests := []struct {
+		mag      float64
+		category int
+	}{
+		{-0.5, 0}, {1.99, 0}, {2.0, 1}, {3.99, 1}, {4.0, 2}, {4.99, 2},
+		{5.0, 3}, {5.99, 3}, {6.0, 4}, {6.99, 4}, {7.0, 5}, {7.99, 5}, {8.0, 6}, {9.5, 6},
+	}
+	for _, tt := range tests {
+		if got := categorizeMagnitude(tt.mag); got != tt.category {
+			t.Errorf("categorizeMagnitude(%v) = %d (%s), want %d (%s)", tt.mag, got, categories[got], tt.category, categories[tt.category])
+		}
+	}
+}
+
+// A month of the worldwide feed has tens of thousands of events, mostly
+// below M2.0. The switch takes a few nanoseconds per event; a binary search
+// over a boundary table measured about twice as slow, so the switch stays.
+func BenchmarkCategorizeMagnitude(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	mags := make([]float64, 40000)
+	for i := range mags {
+		mags[i] = rng.ExpFloat64() + 0.5
+	}
+
+	var counts [7]int
+	for b.Loop() {
+		for _, mag := range mags {
+			counts[categorizeMagnitude(mag)]++
+		}
+	}
+}
+
+func TestParseCSVAcceptsAlternativeTimeFormats(t *testing.T) {
+	csv := `time,latitude,longitude,depth,mag,id,place
+2026-06-08 10:11:12.5+00:00,1,2,3,2.1,a,Space separator
+2026-06-08 10:11:12 UTC,1,2,3,2.2,b,Zone name
+2026-06-08T10:11:12,1,2,3,2.3,c,No zone
+1780913472000,1,2,3,2.4,d,Epoch milliseconds
+2016-12-31T23:59:60.250Z,1,2,3,2.5,e,Leap second
+08.06.2026 10:11,1,2,3,2.6,f,Unknown format
+`
+	quakes, err := parseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("parseCSV returned error: %v", err)
+	}
+
+	want := map[string]time.Time{
+		"a": time.Date(2026, 6, 8, 10, 11, 12, 500000000, time.UTC),
+		"b": time.Date(2026, 6, 8, 10, 11, 12, 0, time.UTC),
+		"c": time.Date(2026, 6, 8, 10, 11, 12, 0, time.UTC),
+		"d": time.Date(2026, 6, 8, 10, 11, 12, 0, time.UTC),
+		"e": time.Date(2017, 1, 1, 0, 0, 0, 250000000, time.UTC),
+	}
+	if len(quakes) != len(want) {
+		t.Fatalf("expected %d events with the unknown format skipped, got %d", len(want), len(quakes))
+	}
+	for _, q := range quakes {
+		if !q.Time.Equal(want[q.ID]) {
+			t.Errorf("%s (%s): expected %v, got %v", q.ID, q.Place, want[q.ID], q.Time)
+		}
+	}
+}
+
+func TestFetchFeedsReadsLocalFiles(t *testing.T) {
+	path, err := filepath.Abs(filepath.Join("testdata", "all_week.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, feed := range []string{filepath.Join("testdata", "all_week.csv"), "file://" + path} {
+		quakes, _, err := fetchFeeds(context.Background(), []string{feed})
+		if err != nil {
+			t.Fatalf("fetchFeeds(%q) returned error: %v", feed, err)
+		}
+		if len(quakes) != 3 || quakes[0].ID != "us7000a1b2" || quakes[0].Place != "76 km E of Ishinomaki, Japan" {
+			t.Fatalf("unexpected quakes from %q: %+v", feed, quakes)
+		}
+	}
+
+	if _, _, err := fetchEarthquakes(context.Background(), filepath.Join("testdata", "missing.csv")); err == nil || !strings.Contains(err.Error(), "failed to open CSV") {
+		t.Fatalf("expected an error for a missing file, got %v", err)
+	}
+	if got := feedWindow([]string{"file://" + path}); got != "7-day" {
+		t.Fatalf("expected the window of a local feed, got %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+)
+
+const (
+	mapWidth  = 720
+	mapHeight = 360
+)
+
+var (
+	mapBackground = color.RGBA{R: 0x1d, G: 0x3b, B: 0x53, A: 0xff}
+	mapGraticule  = color.RGBA{R: 0x3e, G: 0x5f, B: 0x7a, A: 0xff}
+)
+
+// Opacity of the markers, so overlapping epicenters stay visible
+const mapMarkerAlpha = 0xb0
+
+// Render the epicenters on an equirectangular world map with a 30° graticule.
+// Markers grow with magnitude, are colored by category from magnitudePalette
+// and larger events are drawn on top.
+func renderEpicenterMap(events []Earthquake) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, mapWidth, mapHeight))
+	for y := range mapHeight {
+		for x := range mapWidth {
+			img.SetRGBA(x, y, mapBackground)
+		}
+	}
+
+	for lon := -180; lon <= 180; lon += 30 {
+		x, _ := project(0, float64(lon))
+		for y := range mapHeight {
+			img.SetRGBA(min(x, mapWidth-1), y, mapGraticule)
+		}
+	}
+	for lat := -90; lat <= 90; lat += 30 {
+		_, y := project(float64(lat), 0)
+		for x := range mapWidth {
+			img.SetRGBA(x, min(y, mapHeight-1), mapGraticule)
+		}
+	}
+
+	located := make([]Earthquake, 0, len(events))
+	for _, eq := range events {
+		if eq.HasLocation() {
+			located = append(located, eq)
+		}
+	}
+	sort.Slice(located, func(i, j int) bool {
+		return located[i].Magnitude < located[j].Magnitude
+	})
+
+	for _, eq := range located {
+		x, y := project(eq.Latitude, eq.Longitude)
+		marker := magnitudePalette.color(eq.Magnitude)
+		marker.A = mapMarkerAlpha
+		drawMarker(img, x, y, markerRadius(eq.Magnitude), marker)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode map: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Describe the map for screen readers
+func epicenterMapAlt(events []Earthquake) string {
+	var largest *Earthquake
+	located := 0
+	for i, eq := range events {
+		if !eq.HasLocation() {
+			continue
+		}
+		located++
+		if largest == nil || eq.Magnitude > largest.Magnitude {
+			largest = &events[i]
+		}
+	}
+
+	alt := fmt.Sprintf("World map showing the epicenters of %s earthquakes", formatThousands(located))
+	if largest != nil {
+		alt += fmt.Sprintf(", the largest M%s near %s", formatMag(largest.Magnitude), shortPlace(largest.Place))
+	}
+	return alt
+}
+
+// Map latitude and longitude to pixel coordinates
+func project(lat, lon float64) (int, int) {
+	x := int((lon + 180) / 360 * mapWidth)
+	y := int((90 - lat) / 180 * mapHeight)
+	return x, y
+}
+
+func markerRadius(mag float64) int {
+	return max(1, int(math.Round(mag*mag/6)))
+}
+
+func drawMarker(img *image.RGBA, cx, cy, radius int, c color.RGBA) {
+	bounds := img.Bounds()
+	for y := cy - radius; y <= cy+radius; y++ {
+		for x := cx - radius; x <= cx+radius; x++ {
+			dx, dy := x-cx, y-cy
+			if dx*dx+dy*dy > radius*radius || !(image.Point{X: x, Y: y}).In(bounds) {
+				continue
+			}
+			img.SetRGBA(x, y, blend(img.RGBAAt(x, y), c))
+		}
+	}
+}
+
+// Blend a translucent color over an opaque background
+func blend(bg, fg color.RGBA) color.RGBA {
+	a := float64(fg.A) / 0xff
+	mix := func(b, f uint8) uint8 {
+		return uint8(math.Round(float64(f)*a + float64(b)*(1-a)))
+	}
+	return color.RGBA{R: mix(bg.R, fg.R), G: mix(bg.G, fg.G), B: mix(bg.B, fg.B), A: 0xff}
+}
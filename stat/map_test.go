@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderEpicenterMapProducesDecodablePNG(t *testing.T) {
+	events := []Earthquake{
+		{Time: time.Now(), Magnitude: 6.8, Place: "Fiji", Latitude: -17.8, Longitude: 178},
+		{Time: time.Now(), Magnitude: 2.1, Place: "Alaska", Latitude: 61.2, Longitude: -149.9},
+		{Time: time.Now(), Magnitude: 3.0, Place: "Nowhere", Latitude: math.NaN(), Longitude: math.NaN()},
+	}
+
+	data, err := renderEpicenterMap(events)
+	if err != nil {
+		t.Fatalf("renderEpicenterMap returned error: %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode map PNG: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != mapWidth || bounds.Dy() != mapHeight {
+		t.Fatalf("expected %dx%d map, got %dx%d", mapWidth, mapHeight, bounds.Dx(), bounds.Dy())
+	}
+
+	x, y := project(-17.8, 178)
+	if r, _, _, _ := img.At(x, y).RGBA(); r>>8 == uint32(mapBackground.R) {
+		t.Fatal("expected a marker at the Fiji epicenter")
+	}
+
+	alt := epicenterMapAlt(events)
+	if !strings.Contains(alt, "2 earthquakes") || !strings.Contains(alt, "M6.8 near Fiji") {
+		t.Fatalf("unexpected alt text %q", alt)
+	}
+}
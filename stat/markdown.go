@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Render the report as a markdown page for a static site archive
+func renderMarkdown(report Report) string {
+	var md strings.Builder
+	if report.Milestone != nil {
+		md.WriteString(report.Milestone.Headline() + "\n\n")
+	}
+	md.WriteString(fmt.Sprintf("# Weekly Earthquake Report %s\n\n", report.WeekKey))
+	md.WriteString(fmt.Sprintf("%s - %s (UTC)\n\n",
+		report.StartDate.UTC().Format("2006-01-02 15:04"), report.EndDate.UTC().Format("2006-01-02 15:04")))
+
+	md.WriteString("| Magnitude | Earthquakes |\n")
+	md.WriteString("| --- | ---: |\n")
+	for _, category := range report.Categories {
+		md.WriteString(fmt.Sprintf("| %s | %s |\n", markdownCell(category.Label), formatThousands(category.Count)))
+	}
+	md.WriteString(fmt.Sprintf("| **Total** | **%s** |\n", formatThousands(report.Total)))
+
+	if report.Largest != nil {
+		md.WriteString(fmt.Sprintf("\nLargest: M%s near %s, %s\n",
+			formatMag(report.Largest.Magnitude), report.Largest.Place, report.Largest.Time.UTC().Format("2006-01-02 15:04 UTC")))
+		md.WriteString(fmt.Sprintf("\nAverage magnitude: %.2f\n", report.AverageMagnitude))
+	}
+	if report.YearAgoTotal != nil {
+		md.WriteString(fmt.Sprintf("\nSame week last year: %s (%s)\n", formatCount(*report.YearAgoTotal), formatDelta(report.Total-*report.YearAgoTotal)))
+	}
+	return md.String()
+}
+
+// Escape the characters that would end a table cell
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// Write the markdown report to <dir>/<year>/<week key>.md, creating the
+// directories as needed, and return the file path
+func writeMarkdownReport(dir string, report Report) (string, error) {
+	year, _, ok := strings.Cut(report.WeekKey, "-")
+	if !ok {
+		return "", fmt.Errorf("invalid week key %q", report.WeekKey)
+	}
+
+	path := filepath.Join(dir, year, report.WeekKey+".md")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(renderMarkdown(report)), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write markdown report: %w", err)
+	}
+	return path, nil
+}
+
+// Commit the markdown report in the git repository that contains it
+func commitMarkdownReport(path string, weekKey string) error {
+	dir, file := filepath.Split(path)
+	for _, args := range [][]string{
+		{"add", "--", file},
+		{"commit", "-m", fmt.Sprintf("Add earthquake report %s", weekKey), "--", file},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
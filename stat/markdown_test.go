@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdownTable(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := newReport("2026-W23", WeekStats{
+		StartDate:    start,
+		EndDate:      start.AddDate(0, 0, 7),
+		Counts:       [7]int{1200, 800, 40, 10, 2, 1, 0},
+		MagnitudeSum: 4106,
+		Largest:      Earthquake{Magnitude: 7.1, Place: "South of the Fiji Islands", Time: start.Add(50 * time.Hour)},
+	})
+
+	want := `# Weekly Earthquake Report 2026-W23
+
+2026-06-01 00:00 - 2026-06-08 00:00 (UTC)
+
+| Magnitude | Earthquakes |
+| --- | ---: |
+| Micro < 2.0 | 1,200 |
+| Minor 2.0 - 3.9 | 800 |
+| Light 4.0 - 4.9 | 40 |
+| Moderate 5.0 - 5.9 | 10 |
+| Strong 6.0 - 6.9 | 2 |
+| Major 7.0 - 7.9 | 1 |
+| Great >= 8.0 | 0 |
+| **Total** | **2,053** |
+
+Largest: M7.1 near South of the Fiji Islands, 2026-06-03 02:00 UTC
+
+Average magnitude: 2.00
+`
+	if got := renderMarkdown(report); got != want {
+		t.Fatalf("unexpected markdown:\nwant %q\ngot  %q", want, got)
+	}
+
+	yearAgo := 853
+	report.YearAgoTotal = &yearAgo
+	for groupDigits, want := range map[string]string{"true": "Same week last year: 853 (+1,200)", "false": "Same week last year: 853 (+1200)"} {
+		t.Setenv("GROUP_DIGITS", groupDigits)
+		if got := renderMarkdown(report); !strings.Contains(got, want) {
+			t.Fatalf("expected %q with GROUP_DIGITS=%s, got:\n%s", want, groupDigits, got)
+		}
+	}
+
+	if got := markdownCell("a | b"); got != `a \| b` {
+		t.Fatalf("expected pipes to be escaped, got %q", got)
+	}
+}
+
+func TestWriteMarkdownReportCreatesDirectories(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+
+	path, err := writeMarkdownReport(dir, Report{WeekKey: "2026-W23", Categories: []CategoryCount{{Label: "Micro < 2.0", Count: 3}}, Total: 3})
+	if err != nil {
+		t.Fatalf("writeMarkdownReport returned error: %v", err)
+	}
+	if path != filepath.Join(dir, "2026", "2026-W23.md") {
+		t.Fatalf("unexpected path %q", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "| Micro < 2.0 | 3 |") {
+		t.Fatalf("expected the table in the file, got %q (err %v)", data, err)
+	}
+}
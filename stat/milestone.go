@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Milestone marks a week whose largest earthquake beats every stored week of the lookback
+type Milestone struct {
+	Years int `json:"years"`
+}
+
+// Headline prepended to the post
+func (m Milestone) Headline() string {
+	if m.Years == 1 {
+		return "🏆 Largest earthquake in a year"
+	}
+	return fmt.Sprintf("🏆 Largest earthquake in %d years", m.Years)
+}
+
+// Check whether the largest event of the week is larger than the largest
+// event of every stored week before it. MILESTONE_YEARS limits the comparison
+// to that many years, otherwise the whole stored history counts. Returns nil
+// when the stored history covers less than one year (or less than
+// MILESTONE_YEARS), since a record over a few weeks is no headline.
+func findMilestone(store *Store, stats WeekStats) *Milestone {
+	if stats.Total() == 0 {
+		return nil
+	}
+	history, err := store.AllWeekStats()
+	if err != nil {
+		fmt.Printf("Error loading stored weeks: %v\n", err)
+		return nil
+	}
+
+	lookbackYears := 0
+	if years, err := strconv.Atoi(os.Getenv("MILESTONE_YEARS")); err == nil && years > 0 {
+		lookbackYears = years
+	}
+	var since time.Time
+	if lookbackYears > 0 {
+		since = stats.StartDate.AddDate(-lookbackYears, 0, 0)
+	}
+
+	earliest := stats.StartDate
+	for _, week := range history {
+		if !week.StartDate.Before(stats.StartDate) || week.StartDate.Before(since) {
+			continue
+		}
+		if week.Total() > 0 && week.Largest.Magnitude >= stats.Largest.Magnitude {
+			return nil
+		}
+		if week.StartDate.Before(earliest) {
+			earliest = week.StartDate
+		}
+	}
+
+	// Whole years covered by the history. Week starts drift by a day or two
+	// per year, so a week of slack is allowed.
+	years := 0
+	for !earliest.After(stats.StartDate.AddDate(-(years + 1), 0, 7)) {
+		years++
+	}
+	if lookbackYears > 0 {
+		if years < lookbackYears {
+			return nil
+		}
+		years = lookbackYears
+	}
+	if years < 1 {
+		return nil
+	}
+	return &Milestone{Years: years}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Store a week of stats whose largest event has the given magnitude
+func storeWeekWithLargest(store *Store, start time.Time, mag float64) {
+	_, end, year, week := getWeekBoundaries(start)
+	store.StoreWeekStats(fmt.Sprintf("%d-W%02d", year, week), WeekStats{
+		StartDate: start, EndDate: end, Year: year, WeekNum: week,
+		Counts: [7]int{0, 0, 0, 1}, MagnitudeSum: mag, Largest: Earthquake{Magnitude: mag},
+	})
+}
+
+func TestFindMilestone(t *testing.T) {
+	store := openTestStore(t)
+	week := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	stats := WeekStats{StartDate: week, Counts: [7]int{0, 0, 0, 0, 0, 1}, Largest: Earthquake{Magnitude: 7.4}}
+
+	// Half a year of history is not enough
+	for w := 1; w <= 26; w++ {
+		storeWeekWithLargest(store, week.AddDate(0, 0, -7*w), 6.0+float64(w%10)/10)
+	}
+	if m := findMilestone(store, stats); m != nil {
+		t.Fatalf("expected no milestone with half a year of history, got %+v", m)
+	}
+
+	// With two years of smaller events the week is a record
+	for w := 27; w <= 106; w++ {
+		storeWeekWithLargest(store, week.AddDate(0, 0, -7*w), 7.0)
+	}
+	m := findMilestone(store, stats)
+	if m == nil || m.Years != 2 {
+		t.Fatalf("expected the largest earthquake in 2 years, got %+v", m)
+	}
+	if text := renderText(Report{Categories: newReport("", WeekStats{}).Categories, StartDate: week, EndDate: week, Milestone: m}); !strings.HasPrefix(text, "🏆 Largest earthquake in 2 years\nWeekly Earthquake Report") {
+		t.Fatalf("expected milestone headline, got:\n%s", text)
+	}
+	if text := renderCompact(Report{Categories: newReport("", WeekStats{}).Categories, Milestone: m}); text != "🏆 Largest earthquake in 2 years · This week: 0 quakes" {
+		t.Fatalf("expected the milestone on the compact line, got %q", text)
+	}
+
+	// A larger event 18 months ago breaks the record, unless the lookback excludes it
+	storeWeekWithLargest(store, week.AddDate(0, -18, 0), 7.8)
+	if m := findMilestone(store, stats); m != nil {
+		t.Fatalf("expected no milestone below the historical maximum, got %+v", m)
+	}
+	t.Setenv("MILESTONE_YEARS", "1")
+	if m := findMilestone(store, stats); m == nil || m.Years != 1 || m.Headline() != "🏆 Largest earthquake in a year" {
+		t.Fatalf("expected the largest earthquake in a year, got %+v", m)
+	}
+	t.Setenv("MILESTONE_YEARS", "5")
+	if m := findMilestone(store, stats); m != nil {
+		t.Fatalf("expected no milestone when the history is shorter than the lookback, got %+v", m)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Default number of consecutive runs without data before an outage is escalated
+const defaultOutageRuns = 3
+
+// errNoEvents is returned when the feeds were fetched but held no events,
+// which means the feed is broken rather than the week quiet
+var errNoEvents = errors.New("the feeds returned no events, the feed may be down")
+
+// feedOutage counts the consecutive runs that got no data
+type feedOutage struct {
+	Runs      int
+	Since     time.Time
+	Escalated bool
+}
+
+// Record a run without data and return the outage so far
+func (s *Store) RecordEmptyRun(at time.Time) (feedOutage, error) {
+	var outage feedOutage
+	value, closer, err := s.db.Get([]byte(outageKey))
+	switch {
+	case err == nil:
+		err = json.Unmarshal(value, &outage)
+		closer.Close()
+		if err != nil {
+			return outage, fmt.Errorf("failed to decode outage: %w", err)
+		}
+	case !errors.Is(err, pebble.ErrNotFound):
+		return outage, err
+	}
+
+	if outage.Runs == 0 {
+		outage.Since = at
+	}
+	outage.Runs++
+	return outage, s.saveOutage(outage)
+}
+
+func (s *Store) saveOutage(outage feedOutage) error {
+	data, err := json.Marshal(outage)
+	if err != nil {
+		return fmt.Errorf("failed to encode outage: %w", err)
+	}
+	return s.db.Set([]byte(outageKey), data, pebble.Sync)
+}
+
+// Forget the outage after a run with data
+func (s *Store) ClearOutage() error {
+	return s.db.Delete([]byte(outageKey), pebble.Sync)
+}
+
+// Number of consecutive runs without data from FEED_OUTAGE_RUNS before the
+// outage is escalated
+func outageRuns() int {
+	if value := os.Getenv("FEED_OUTAGE_RUNS"); value != "" {
+		if runs, err := strconv.Atoi(value); err == nil && runs > 0 {
+			return runs
+		}
+		fmt.Printf("Warning: ignoring invalid FEED_OUTAGE_RUNS %q\n", value)
+	}
+	return defaultOutageRuns
+}
+
+// Record a run that got no data and escalate once the outage lasts for
+// outageRuns runs. The escalation is sent once per outage to
+// OUTAGE_WEBHOOK_URL, or only printed when no webhook is configured.
+func handleEmptyRun(ctx context.Context, store *Store) error {
+	outage, err := store.RecordEmptyRun(now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("No earthquake data for %d consecutive runs since %s\n", outage.Runs, outage.Since.UTC().Format(time.RFC3339))
+	if outage.Runs < outageRuns() || outage.Escalated {
+		return nil
+	}
+
+	message := fmt.Sprintf("Earthquake feed outage: no data for %d consecutive runs since %s", outage.Runs, outage.Since.UTC().Format("2006-01-02 15:04 UTC"))
+	fmt.Println(message)
+	if url := os.Getenv("OUTAGE_WEBHOOK_URL"); url != "" {
+		if err := sendWebhook(ctx, url, message); err != nil {
+			return fmt.Errorf("failed to send outage alert: %w", err)
+		}
+	}
+	outage.Escalated = true
+	return store.saveOutage(outage)
+}
+
+// Post a message as {"text": "..."}, which Slack, Mattermost and most chat
+// webhooks accept
+func sendWebhook(ctx context.Context, url, message string) error {
+	body, err := json.Marshal(map[string]string{"text": message})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := newHTTPClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
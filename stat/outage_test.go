@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsecutiveEmptyFeedsEscalateOnce(t *testing.T) {
+	store := openTestStore(t)
+	var alerts []string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		alerts = append(alerts, payload["text"])
+	}))
+	t.Cleanup(webhook.Close)
+
+	empty := true
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n")
+		if !empty {
+			fmt.Fprint(w, "2026-06-09T10:00:00Z,1,2,10,2.5,ml,,,,,us,a,,Place A,earthquake,reviewed\n")
+		}
+	}))
+	t.Cleanup(feed.Close)
+	t.Setenv("USGS_FEED_URL", feed.URL)
+	t.Setenv("OUTAGE_WEBHOOK_URL", webhook.URL)
+	t.Setenv("FEED_OUTAGE_RUNS", "3")
+	setNow(t, time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	for i := 1; i <= 4; i++ {
+		summary := run(context.Background(), store, runConfig{format: "text"})
+		if len(summary.Errors) != 1 || !strings.Contains(summary.Errors[0], "no events") {
+			t.Fatalf("run %d: expected an empty feed error, got %v", i, summary.Errors)
+		}
+		if want := min(max(i-2, 0), 1); len(alerts) != want {
+			t.Fatalf("run %d: expected %d alerts, got %q", i, want, alerts)
+		}
+	}
+	if !strings.Contains(alerts[0], "no data for 3 consecutive runs since 2026-06-10 12:00 UTC") {
+		t.Fatalf("unexpected alert %q", alerts[0])
+	}
+
+	// A run with data ends the outage, the next one starts counting anew
+	empty = false
+	if summary := run(context.Background(), store, runConfig{format: "text"}); len(summary.Errors) != 0 {
+		t.Fatalf("expected a run with data to succeed, got %v", summary.Errors)
+	}
+	empty = true
+	if outage, _ := store.RecordEmptyRun(now()); outage.Runs != 1 || outage.Escalated {
+		t.Fatalf("expected a new outage after data, got %+v", outage)
+	}
+}
+
+func TestFailedDownloadIsNoOutage(t *testing.T) {
+	store := openTestStore(t)
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(feed.Close)
+	t.Setenv("USGS_FEED_URL", feed.URL)
+	setNow(t, time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	if summary := run(context.Background(), store, runConfig{format: "text"}); len(summary.Errors) != 1 {
+		t.Fatalf("expected the download error, got %v", summary.Errors)
+	}
+	if outage, _ := store.RecordEmptyRun(now()); outage.Runs != 1 {
+		t.Fatalf("expected the failed download not to count as an empty run, got %+v", outage)
+	}
+}
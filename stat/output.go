@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+)
+
+// output is a destination of the weekly report
+type output interface {
+	name() string
+	publish(ctx context.Context, reportData ReportData) error
+}
+
+// blueskyOutput posts the report as a thread, or as an image card with
+// REPORT_CARD
+type blueskyOutput struct {
+	store *Store
+}
+
+func (o blueskyOutput) name() string { return "bluesky" }
+
+func (o blueskyOutput) publish(ctx context.Context, reportData ReportData) error {
+	var root *atproto.RepoStrongRef
+	var err error
+	if envBool("REPORT_CARD") {
+		root, err = postReportCard(ctx, o.store, reportData)
+	} else {
+		root, err = postThread(ctx, o.store, reportData.WeekKey, reportData.Posts())
+	}
+	if err != nil {
+		return err
+	}
+
+	// Keep what was posted for corrections, a failure must not fail the post
+	posted := PostedWeek{Root: root, Categories: reportData.Report.Categories}
+	if err := o.store.SavePostedWeek(reportData.WeekKey, posted); err != nil {
+		fmt.Printf("Error saving posted week: %v\n", err)
+	}
+	return nil
+}
+
+// markdownOutput writes the report to the markdown archive
+type markdownOutput struct {
+	dir    string
+	commit bool
+}
+
+func (o markdownOutput) name() string { return "markdown" }
+
+func (o markdownOutput) publish(ctx context.Context, reportData ReportData) error {
+	path, err := writeMarkdownReport(o.dir, reportData.Report)
+	if err != nil {
+		return err
+	}
+	if o.commit {
+		return commitMarkdownReport(path, reportData.WeekKey)
+	}
+	return nil
+}
+
+// The configured outputs and the name of the primary one from PRIMARY_OUTPUT,
+// "bluesky" by default
+func configuredOutputs(store *Store, cfg runConfig) ([]output, string) {
+	outputs := []output{blueskyOutput{store: store}}
+	if cfg.markdownDir != "" {
+		outputs = append(outputs, markdownOutput{dir: cfg.markdownDir, commit: cfg.markdownCommit})
+	}
+	if cfg.archiveCSV != "" {
+		outputs = append(outputs, archiveOutput{path: cfg.archiveCSV})
+	}
+	primary := strings.TrimSpace(os.Getenv("PRIMARY_OUTPUT"))
+	if primary == "" {
+		primary = "bluesky"
+	}
+	return outputs, primary
+}
+
+// Publish the report to every output. The week is marked as posted once the
+// primary output succeeds; when it fails, nothing else is published so the
+// next run starts over. A failed secondary output does not undo the post, its
+// error is returned and the report is queued for retryOutputs.
+func publishOutputs(ctx context.Context, store *Store, outputs []output, primary string, reportData ReportData) (posted bool, errs []error) {
+	first := -1
+	for i, o := range outputs {
+		if o.name() == primary {
+			first = i
+		}
+	}
+	if first == -1 {
+		return false, []error{fmt.Errorf("primary output %q is not configured", primary)}
+	}
+
+	if err := outputs[first].publish(ctx, reportData); err != nil {
+		return false, []error{fmt.Errorf("%s: %w", primary, err)}
+	}
+	store.MarkWeekPosted(reportData.WeekKey)
+
+	for i, o := range outputs {
+		if i == first {
+			continue
+		}
+		if err := o.publish(ctx, reportData); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", o.name(), err))
+			if err := store.QueueOutputRetry(o.name(), reportData); err != nil {
+				errs = append(errs, fmt.Errorf("failed to queue %s for retry: %w", o.name(), err))
+			}
+		}
+	}
+	return true, errs
+}
+
+// Publish the queued reports again. Reports of outputs that are no longer
+// configured stay queued.
+func retryOutputs(ctx context.Context, store *Store, outputs []output) []error {
+	queued, err := store.QueuedOutputRetries()
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, o := range outputs {
+		for _, reportData := range queued[o.name()] {
+			if err := o.publish(ctx, reportData); err != nil {
+				errs = append(errs, fmt.Errorf("retrying %s for week %s: %w", o.name(), reportData.WeekKey, err))
+				continue
+			}
+			if err := store.DeleteOutputRetry(o.name(), reportData.WeekKey); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeOutput records the published weeks and fails while err is set
+type fakeOutput struct {
+	outputName string
+	err        error
+	published  []string
+}
+
+func (o *fakeOutput) name() string { return o.outputName }
+
+func (o *fakeOutput) publish(ctx context.Context, reportData ReportData) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.published = append(o.published, reportData.WeekKey)
+	return nil
+}
+
+func TestPublishOutputsMarksWeekWhenSecondaryFails(t *testing.T) {
+	pds := newMockPDS(t)
+	store := openTestStore(t)
+	webhook := &fakeOutput{outputName: "webhook", err: errors.New("connection refused")}
+	outputs := []output{blueskyOutput{store: store}, webhook}
+	reportData := ReportData{WeekKey: "2026-W23", ReportText: "Weekly Earthquake Report"}
+
+	posted, errs := publishOutputs(context.Background(), store, outputs, "bluesky", reportData)
+	if !posted || !store.WasWeekPosted("2026-W23") {
+		t.Fatal("expected the week to be marked as posted after the primary output succeeded")
+	}
+	if len(errs) != 1 || errs[0].Error() != "webhook: connection refused" {
+		t.Fatalf("expected the secondary failure to be reported, got %v", errs)
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 1 {
+		t.Fatalf("expected one post, got %d", len(created))
+	}
+
+	// The next run publishes the queued report to the secondary output only
+	webhook.err = nil
+	if errs := retryOutputs(context.Background(), store, outputs); len(errs) != 0 {
+		t.Fatalf("retryOutputs returned errors: %v", errs)
+	}
+	if len(webhook.published) != 1 || webhook.published[0] != "2026-W23" {
+		t.Fatalf("expected the queued week to be retried, got %v", webhook.published)
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 1 {
+		t.Fatalf("expected no second post, got %d", len(created))
+	}
+	if queued, _ := store.QueuedOutputRetries(); len(queued) != 0 {
+		t.Fatalf("expected the retry queue to be empty, got %v", queued)
+	}
+}
+
+func TestPublishOutputsStopsWhenPrimaryFails(t *testing.T) {
+	store := openTestStore(t)
+	archive := &fakeOutput{outputName: "markdown", err: errors.New("disk full")}
+	bluesky := &fakeOutput{outputName: "bluesky"}
+	reportData := ReportData{WeekKey: "2026-W23"}
+
+	posted, errs := publishOutputs(context.Background(), store, []output{bluesky, archive}, "markdown", reportData)
+	if posted || store.WasWeekPosted("2026-W23") || len(errs) != 1 {
+		t.Fatalf("expected a failed primary to leave the week unposted, got %v %v", posted, errs)
+	}
+	if len(bluesky.published) != 0 {
+		t.Fatal("expected no secondary output after the primary failed")
+	}
+
+	if _, errs := publishOutputs(context.Background(), store, []output{bluesky}, "webhook", reportData); len(errs) != 1 {
+		t.Fatalf("expected an error for an unconfigured primary, got %v", errs)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// palette is the color of each magnitude category, indexed like categories
+type palette [7]color.RGBA
+
+// Okabe-Ito colors, distinguishable with the common forms of color blindness.
+// Great earthquakes are white so they stand out on the dark map background.
+var defaultPalette = palette{
+	{R: 0x56, G: 0xb4, B: 0xe9, A: 0xff}, // sky blue
+	{R: 0x00, G: 0x9e, B: 0x73, A: 0xff}, // bluish green
+	{R: 0xf0, G: 0xe4, B: 0x42, A: 0xff}, // yellow
+	{R: 0xe6, G: 0x9f, B: 0x00, A: 0xff}, // orange
+	{R: 0xd5, G: 0x5e, B: 0x00, A: 0xff}, // vermillion
+	{R: 0xcc, G: 0x79, B: 0xa7, A: 0xff}, // reddish purple
+	{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, // white
+}
+
+// Palette used by maps and charts, set from MAG_PALETTE in main
+var magnitudePalette = defaultPalette
+
+// Color of the category of a magnitude
+func (p palette) color(mag float64) color.RGBA {
+	return p[categorizeMagnitude(mag)]
+}
+
+// Read MAG_PALETTE, a comma-separated list of hex colors from the micro to the
+// great category. Empty or missing entries keep the default color.
+func loadPalette() (palette, error) {
+	p := defaultPalette
+	value := strings.TrimSpace(os.Getenv("MAG_PALETTE"))
+	if value == "" {
+		return p, nil
+	}
+
+	entries := strings.Split(value, ",")
+	if len(entries) > len(p) {
+		return p, fmt.Errorf("MAG_PALETTE has %d colors, expected at most %d", len(entries), len(p))
+	}
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		c, err := parseHexColor(entry)
+		if err != nil {
+			return p, fmt.Errorf("invalid MAG_PALETTE color for %s: %w", categories[i], err)
+		}
+		p[i] = c
+	}
+	return p, nil
+}
+
+// Parse an opaque color written as #rgb or #rrggbb
+func parseHexColor(s string) (color.RGBA, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok {
+		return color.RGBA{}, fmt.Errorf("%q does not start with #", s)
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("%q is not #rgb or #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("%q is not a hex color", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestDefaultPaletteCoversEveryCategory(t *testing.T) {
+	if len(defaultPalette) != len(categories) {
+		t.Fatalf("palette has %d colors for %d categories", len(defaultPalette), len(categories))
+	}
+	seen := make(map[color.RGBA]string)
+	for i, c := range defaultPalette {
+		if c.A != 0xff {
+			t.Errorf("color of %s is not opaque: %v", categories[i], c)
+		}
+		if other, ok := seen[c]; ok {
+			t.Errorf("%s and %s share the color %v", other, categories[i], c)
+		}
+		seen[c] = categories[i]
+	}
+	if got := defaultPalette.color(6.3); got != defaultPalette[4] {
+		t.Errorf("color(6.3) = %v, want the strong category color %v", got, defaultPalette[4])
+	}
+}
+
+func TestLoadPaletteOverrides(t *testing.T) {
+	t.Setenv("MAG_PALETTE", "#000, ,#12aBeF")
+	p, err := loadPalette()
+	if err != nil {
+		t.Fatalf("loadPalette returned error: %v", err)
+	}
+	if want := (color.RGBA{A: 0xff}); p[0] != want {
+		t.Errorf("micro color = %v, want %v", p[0], want)
+	}
+	if p[1] != defaultPalette[1] {
+		t.Errorf("expected an empty entry to keep the default, got %v", p[1])
+	}
+	if want := (color.RGBA{R: 0x12, G: 0xab, B: 0xef, A: 0xff}); p[2] != want {
+		t.Errorf("light color = %v, want %v", p[2], want)
+	}
+	if p[6] != defaultPalette[6] {
+		t.Errorf("expected missing entries to keep the default, got %v", p[6])
+	}
+
+	for _, value := range []string{"red", "#12345", "#gggggg", "#1,#2,#3,#4,#5,#6,#7,#8"} {
+		t.Setenv("MAG_PALETTE", value)
+		if _, err := loadPalette(); err == nil {
+			t.Errorf("expected an error for MAG_PALETTE %q", value)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// Directory serving the DID documents of did:plc identities
+var plcDirectory = "https://plc.directory"
+
+// How long a discovered PDS endpoint is used before it is resolved again, so a
+// migrated account is picked up within a day
+const pdsCacheTTL = 24 * time.Hour
+
+// Find the PDS that hosts the account. The handle is resolved to a DID
+// through host and the PDS is read from the DID document. The result is
+// cached in the store, which may be nil. When discovery fails, or the
+// identifier is an email address, host itself is used.
+func pdsHost(ctx context.Context, store *Store, host, identifier string) string {
+	if strings.Contains(identifier, "@") {
+		return host
+	}
+
+	if store != nil {
+		cached, found, err := store.LoadPDSEndpoint(identifier)
+		if err != nil {
+			fmt.Printf("Error loading cached PDS endpoint: %v\n", err)
+		} else if found && now().Sub(cached.ResolvedAt) < pdsCacheTTL {
+			return cached.URL
+		}
+	}
+
+	endpoint, err := resolvePDS(ctx, host, identifier)
+	if err != nil {
+		fmt.Printf("Error discovering the PDS of %s, using %s: %v\n", identifier, host, err)
+		return host
+	}
+
+	if store != nil {
+		if err := store.SavePDSEndpoint(identifier, pdsEndpoint{URL: endpoint, ResolvedAt: now()}); err != nil {
+			fmt.Printf("Error caching PDS endpoint: %v\n", err)
+		}
+	}
+	return endpoint
+}
+
+// Resolve a handle or DID to the service endpoint of its PDS
+func resolvePDS(ctx context.Context, host, identifier string) (string, error) {
+	did := identifier
+	if !strings.HasPrefix(identifier, "did:") {
+		agent := userAgent()
+		out, err := atproto.IdentityResolveHandle(ctx, &xrpc.Client{Host: host, UserAgent: &agent}, identifier)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve handle: %w", err)
+		}
+		did = out.Did
+	}
+
+	docURL, err := didDocumentURL(did)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		return "", err
+	}
+	client := newHTTPClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download DID document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code for DID document: %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Service []struct {
+			ID              string `json:"id"`
+			Type            string `json:"type"`
+			ServiceEndpoint string `json:"serviceEndpoint"`
+		} `json:"service"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to decode DID document: %w", err)
+	}
+
+	for _, service := range doc.Service {
+		if strings.HasSuffix(service.ID, "#atproto_pds") && service.Type == "AtprotoPersonalDataServer" && service.ServiceEndpoint != "" {
+			return strings.TrimSuffix(service.ServiceEndpoint, "/"), nil
+		}
+	}
+	return "", fmt.Errorf("DID document of %s names no PDS", did)
+}
+
+// URL of the DID document of a did:plc or did:web identity
+func didDocumentURL(did string) (string, error) {
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		return plcDirectory + "/" + did, nil
+	case strings.HasPrefix(did, "did:web:"):
+		return "https://" + strings.TrimPrefix(did, "did:web:") + "/.well-known/did.json", nil
+	default:
+		return "", fmt.Errorf("unsupported DID %q", did)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPDSHostDiscoversAndCachesEndpoint(t *testing.T) {
+	pds := newMockPDS(t)
+	pds.pdsEndpoint = "https://pds.example.net/"
+	store := openTestStore(t)
+	instant := time.Date(2026, 6, 8, 6, 0, 0, 0, time.UTC)
+	setNow(t, instant)
+
+	if host := pdsHost(context.Background(), store, pds.URL, "bot.example.com"); host != "https://pds.example.net" {
+		t.Fatalf("expected the PDS from the DID document, got %q", host)
+	}
+
+	// Within a day the cached endpoint is used without resolving again
+	setNow(t, instant.Add(23*time.Hour))
+	if host := pdsHost(context.Background(), store, pds.URL, "bot.example.com"); host != "https://pds.example.net" {
+		t.Fatalf("expected the cached PDS, got %q", host)
+	}
+	if resolved := len(pds.calls("/plc/did:plc:bot")); resolved != 1 {
+		t.Fatalf("expected one DID document lookup, got %d", resolved)
+	}
+
+	setNow(t, instant.Add(25*time.Hour))
+	pdsHost(context.Background(), store, pds.URL, "bot.example.com")
+	if resolved := len(pds.calls("/plc/did:plc:bot")); resolved != 2 {
+		t.Fatalf("expected an expired cache entry to be resolved again, got %d lookups", resolved)
+	}
+}
+
+func TestPDSHostFallsBackToConfiguredHost(t *testing.T) {
+	pds := newMockPDS(t)
+
+	for _, identifier := range []string{"unknown.example.com", "did:key:z6Mk", "bot@example.com"} {
+		if host := pdsHost(context.Background(), nil, pds.URL, identifier); host != pds.URL {
+			t.Errorf("expected the configured host for %s, got %q", identifier, host)
+		}
+	}
+}
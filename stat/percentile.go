@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Percentiles of the weekly magnitudes shown in the detailed report
+var reportedPercentiles = []float64{50, 90, 99}
+
+// MagnitudePercentile is the magnitude below which the given percentage of events fall
+type MagnitudePercentile struct {
+	Percentile float64 `json:"percentile"`
+	Magnitude  float64 `json:"magnitude"`
+}
+
+// Percentile of sorted values with linear interpolation between the two
+// closest ranks: the rank is p/100*(n-1), counted from zero, and a fractional
+// rank interpolates between its neighbors. This is the default method of
+// NumPy and spreadsheet PERCENTILE functions.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// Magnitude percentiles of the events. A percentile is only meaningful when
+// at least one event lies above it, so p is omitted with fewer than
+// 100/(100-p) events: p50 needs 2 events, p90 needs 10 and p99 needs 100.
+func magnitudePercentiles(events []Earthquake) []MagnitudePercentile {
+	magnitudes := make([]float64, len(events))
+	for i, eq := range events {
+		magnitudes[i] = eq.Magnitude
+	}
+	sort.Float64s(magnitudes)
+
+	var percentiles []MagnitudePercentile
+	for _, p := range reportedPercentiles {
+		if float64(len(magnitudes)) < 100/(100-p) {
+			continue
+		}
+		percentiles = append(percentiles, MagnitudePercentile{Percentile: p, Magnitude: percentile(magnitudes, p)})
+	}
+	return percentiles
+}
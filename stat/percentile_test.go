@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPercentileInterpolatesBetweenRanks(t *testing.T) {
+	// 1.0, 1.1, ..., 10.9, 11.0: 101 evenly spaced magnitudes
+	var events []Earthquake
+	for i := 0; i <= 100; i++ {
+		events = append(events, Earthquake{Magnitude: 1 + float64(i)/10})
+	}
+
+	percentiles := magnitudePercentiles(events)
+	want := []float64{6.0, 10.0, 10.9}
+	if len(percentiles) != len(want) {
+		t.Fatalf("expected %d percentiles, got %+v", len(want), percentiles)
+	}
+	for i, p := range percentiles {
+		if math.Abs(p.Magnitude-want[i]) > 1e-9 {
+			t.Fatalf("expected p%.0f = %.1f, got %v", p.Percentile, want[i], p.Magnitude)
+		}
+	}
+
+	if got := percentile([]float64{2, 4}, 25); got != 2.5 {
+		t.Fatalf("expected interpolated 2.5, got %v", got)
+	}
+}
+
+func TestMagnitudePercentilesOmitsTailForSmallWeeks(t *testing.T) {
+	events := make([]Earthquake, 12)
+	for i := range events {
+		events[i].Magnitude = float64(i)
+	}
+
+	percentiles := magnitudePercentiles(events)
+	if len(percentiles) != 2 || percentiles[1].Percentile != 90 {
+		t.Fatalf("expected only p50 and p90 with 12 events, got %+v", percentiles)
+	}
+	if got := magnitudePercentiles(events[:1]); len(got) != 0 {
+		t.Fatalf("expected no percentiles for a single event, got %+v", got)
+	}
+
+	text := renderDetailed(Report{Categories: newReport("", WeekStats{}).Categories, Percentiles: percentiles})
+	if !strings.HasSuffix(text, "Magnitude percentiles: p50 M5.5, p90 M9.9") {
+		t.Fatalf("expected percentile line, got:\n%s", text)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import "fmt"
+
+// regionPopulation is the approximate population and land area of a region,
+// rounded figures from around 2023 that only serve as context
+type regionPopulation struct {
+	Millions float64
+	AreaKm2  float64
+}
+
+// Density is people per km²
+func (p regionPopulation) density() float64 {
+	return p.Millions * 1e6 / p.AreaKm2
+}
+
+// Population of the seismically active regions that most often top the
+// weekly ranking, keyed like regionOf. Regions that are not listed, such as
+// ocean ridges, get no population context.
+var regionPopulations = map[string]regionPopulation{
+	"Afghanistan":      {41, 653_000},
+	"Alaska":           {0.73, 1_723_000},
+	"Argentina":        {46, 2_780_000},
+	"Burma (Myanmar)":  {54, 677_000},
+	"California":       {39, 424_000},
+	"Canada":           {40, 9_985_000},
+	"Chile":            {19.6, 756_000},
+	"China":            {1410, 9_597_000},
+	"Colombia":         {52, 1_142_000},
+	"Ecuador":          {18, 256_000},
+	"El Salvador":      {6.3, 21_000},
+	"Fiji":             {0.93, 18_300},
+	"Greece":           {10.4, 132_000},
+	"Guatemala":        {18, 109_000},
+	"Hawaii":           {1.44, 28_300},
+	"Iceland":          {0.39, 103_000},
+	"Idaho":            {2, 216_000},
+	"India":            {1430, 3_287_000},
+	"Indonesia":        {278, 1_905_000},
+	"Iran":             {89, 1_648_000},
+	"Italy":            {59, 302_000},
+	"Japan":            {124, 378_000},
+	"Mexico":           {129, 1_964_000},
+	"Montana":          {1.1, 381_000},
+	"Nepal":            {30.9, 147_000},
+	"Nevada":           {3.2, 286_000},
+	"New Mexico":       {2.1, 315_000},
+	"New Zealand":      {5.2, 268_000},
+	"Nicaragua":        {6.9, 130_000},
+	"Oklahoma":         {4, 181_000},
+	"Oregon":           {4.2, 255_000},
+	"Pakistan":         {240, 882_000},
+	"Papua New Guinea": {10.3, 463_000},
+	"Peru":             {34, 1_285_000},
+	"Philippines":      {115, 300_000},
+	"Puerto Rico":      {3.2, 9_100},
+	"Russia":           {144, 17_098_000},
+	"Solomon Islands":  {0.72, 28_900},
+	"Taiwan":           {23.4, 36_000},
+	"Tajikistan":       {10, 143_000},
+	"Texas":            {30.5, 696_000},
+	"Tonga":            {0.1, 750},
+	"Turkey":           {85, 784_000},
+	"Utah":             {3.4, 220_000},
+	"Vanuatu":          {0.33, 12_200},
+	"Washington":       {7.8, 185_000},
+	"Wyoming":          {0.58, 253_000},
+}
+
+// Other names USGS uses for the regions above
+var regionAliases = map[string]string{
+	"AK":      "Alaska",
+	"CA":      "California",
+	"HI":      "Hawaii",
+	"ID":      "Idaho",
+	"MT":      "Montana",
+	"NM":      "New Mexico",
+	"NV":      "Nevada",
+	"OK":      "Oklahoma",
+	"OR":      "Oregon",
+	"TX":      "Texas",
+	"UT":      "Utah",
+	"WA":      "Washington",
+	"WY":      "Wyoming",
+	"Burma":   "Burma (Myanmar)",
+	"Myanmar": "Burma (Myanmar)",
+	"Türkiye": "Turkey",
+}
+
+// Regions with fewer people per km² are noted as sparsely populated, with
+// more as densely populated
+const (
+	sparseDensity = 25
+	denseDensity  = 250
+)
+
+// Look up the population of a region as returned by regionOf
+func lookupPopulation(region string) (regionPopulation, bool) {
+	if name, ok := regionAliases[region]; ok {
+		region = name
+	}
+	population, ok := regionPopulations[region]
+	return population, ok
+}
+
+// Describe the events of a region relative to its population, e.g.
+// "≈79 per million people, sparsely populated". Empty for regions without a
+// population entry or without POPULATION_CONTEXT.
+func populationContext(region string, count int) string {
+	if !envBool("POPULATION_CONTEXT") {
+		return ""
+	}
+	population, ok := lookupPopulation(region)
+	if !ok {
+		return ""
+	}
+
+	perMillion := float64(count) / population.Millions
+	text := fmt.Sprintf("≈%.1f per million people", perMillion)
+	if perMillion >= 10 {
+		text = fmt.Sprintf("≈%s per million people", formatCount(int(perMillion+0.5)))
+	}
+	switch density := population.density(); {
+	case density < sparseDensity:
+		text += ", sparsely populated"
+	case density > denseDensity:
+		text += ", densely populated"
+	}
+	return text
+}
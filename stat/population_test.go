@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookupPopulation(t *testing.T) {
+	alaska, ok := lookupPopulation("AK")
+	if !ok || alaska != regionPopulations["Alaska"] {
+		t.Fatalf("expected AK to resolve to Alaska, got %+v (%v)", alaska, ok)
+	}
+	if _, ok := lookupPopulation("Mid-Atlantic Ridge"); ok {
+		t.Fatal("expected no population for an ocean ridge")
+	}
+}
+
+func TestPopulationContext(t *testing.T) {
+	if text := populationContext("Alaska", 58); text != "" {
+		t.Fatalf("expected no context without POPULATION_CONTEXT, got %q", text)
+	}
+
+	t.Setenv("POPULATION_CONTEXT", "true")
+	tests := []struct {
+		region string
+		count  int
+		want   string
+	}{
+		{"Alaska", 58, "≈79 per million people, sparsely populated"},
+		{"Japan", 62, "≈0.5 per million people, densely populated"},
+		{"CA", 120, "≈3.1 per million people"},
+		{"South Sandwich Islands region", 12, ""},
+	}
+	for _, tt := range tests {
+		if got := populationContext(tt.region, tt.count); got != tt.want {
+			t.Errorf("populationContext(%q, %d) = %q, want %q", tt.region, tt.count, got, tt.want)
+		}
+	}
+
+	week := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := Report{Categories: newReport("", WeekStats{}).Categories, StartDate: week, EndDate: week}
+	report.MostActive = &RegionActivity{Region: "Alaska", Count: 58}
+	if text := renderText(report); !strings.Contains(text, "\nMost active region: Alaska (58, ≈79 per million people, sparsely populated)") {
+		t.Fatalf("expected the population context in the report, got:\n%s", text)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Time spans of the USGS summary feeds, keyed by the suffix of the feed name
+var feedWindows = map[string]string{
+	"hour":  "1-hour",
+	"day":   "1-day",
+	"week":  "7-day",
+	"month": "30-day",
+}
+
+// Describe the time span of the USGS summary feeds, e.g. "30-day" for
+// all_month.csv. Returns an empty string for other URLs or mixed spans.
+func feedWindow(urls []string) string {
+	window := ""
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(url), ".csv")
+		_, span, _ := strings.Cut(name, "_")
+		w, ok := feedWindows[span]
+		if !ok || (window != "" && window != w) {
+			return ""
+		}
+		window = w
+	}
+	return window
+}
+
+// Append the data source footer, e.g. "Data: USGS 30-day feed, fetched
+// 2026-06-08 06:00 UTC". Longer forms are tried first, the footer is
+// shortened or left out to keep the post within maxPostLength.
+func withProvenance(text string, fetchedAt time.Time, window string) string {
+	fetched := fetchedAt.UTC()
+	var footers []string
+	if window != "" {
+		footers = append(footers, fmt.Sprintf("Data: USGS %s feed, fetched %s UTC", window, fetched.Format("2006-01-02 15:04")))
+	}
+	footers = append(footers,
+		fmt.Sprintf("Data: USGS, fetched %s UTC", fetched.Format("2006-01-02 15:04")),
+		fmt.Sprintf("Data: USGS %sZ", fetched.Format("01-02 15:04")),
+		"Data: USGS",
+	)
+
+	for _, footer := range footers {
+		if withFooter := text + "\n\n" + footer; postLength(withFooter) <= maxPostLength {
+			return withFooter
+		}
+	}
+	return text
+}
+
+// Add the footer to the last post of a thread only, so it is read once after
+// the whole report. The compact format gets no footer.
+func withThreadProvenance(texts []string, format string, fetchedAt time.Time, window string) []string {
+	if format == "compact" || len(texts) == 0 {
+		return texts
+	}
+	last := len(texts) - 1
+	texts[last] = withProvenance(texts[last], fetchedAt, window)
+	return texts
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedWindowFromSummaryFeedNames(t *testing.T) {
+	tests := map[string]string{
+		defaultFeedURL: "30-day",
+		"https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_week.csv, https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/4.5_week.csv": "7-day",
+		"https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_day.csv,https://earthquake.usgs.gov/earthquakes/feed/v1.0/summary/all_week.csv":   "",
+		"https://mirror.example.com/quakes.csv": "",
+	}
+	for urls, want := range tests {
+		if got := feedWindow(strings.Split(urls, ",")); got != want {
+			t.Errorf("feedWindow(%q) = %q, want %q", urls, got, want)
+		}
+	}
+}
+
+func TestWithProvenanceUsesFetchTimeAndFitsBudget(t *testing.T) {
+	instant := time.Date(2026, 6, 8, 6, 5, 0, 0, time.FixedZone("CEST", 2*60*60))
+	setNow(t, instant)
+
+	text := withProvenance("Weekly Earthquake Report", now(), "30-day")
+	if !strings.HasSuffix(text, "\n\nData: USGS 30-day feed, fetched 2026-06-08 04:05 UTC") {
+		t.Fatalf("expected provenance footer, got:\n%s", text)
+	}
+
+	long := strings.Repeat("x", maxPostLength-30)
+	text = withProvenance(long, now(), "30-day")
+	if postLength(text) > maxPostLength || !strings.HasSuffix(text, "Data: USGS 06-08 04:05Z") {
+		t.Fatalf("expected the abbreviated footer within the budget, got %d chars: %q", postLength(text), text[len(long):])
+	}
+
+	full := strings.Repeat("x", maxPostLength)
+	if text := withProvenance(full, now(), "30-day"); text != full {
+		t.Fatal("expected no footer when the post is already full")
+	}
+}
+
+func TestThreadProvenanceOnlyOnLastPost(t *testing.T) {
+	setNow(t, time.Date(2026, 6, 8, 4, 5, 0, 0, time.UTC))
+
+	texts := withThreadProvenance([]string{"Report (Europe/Zurich)", "Report (America/New_York)"}, "text", now(), "30-day")
+	if strings.Contains(texts[0], "Data: USGS") || !strings.HasSuffix(texts[1], "Data: USGS 30-day feed, fetched 2026-06-08 04:05 UTC") {
+		t.Fatalf("expected the footer on the last post only, got %q", texts)
+	}
+	if texts := withThreadProvenance([]string{"This week: 1,234 quakes"}, "compact", now(), "30-day"); texts[0] != "This week: 1,234 quakes" {
+		t.Fatalf("expected no footer in the compact format, got %q", texts[0])
+	}
+}
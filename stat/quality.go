@@ -0,0 +1,70 @@
+package main
+
+import "math"
+
+// DataQuality describes the rows of the fetched feeds, for diagnosing feed
+// problems from the run summary. It is never posted.
+type DataQuality struct {
+	RowsParsed int `json:"rowsParsed"`
+	// Rows left out, by reason: "invalidTime" or "invalidMagnitude"
+	RowsSkipped          map[string]int `json:"rowsSkipped"`
+	MissingDepthPercent  float64        `json:"missingDepthPercent"`
+	MissingCoordsPercent float64        `json:"missingCoordsPercent"`
+	// Events dropped when merging because their ID was seen before
+	DuplicateIDs int      `json:"duplicateIds"`
+	MagnitudeMin *float64 `json:"magnitudeMin"`
+	MagnitudeMax *float64 `json:"magnitudeMax"`
+	// Stretches without events that point to feed downtime
+	DataGaps []DataGap `json:"dataGaps,omitempty"`
+
+	missingDepth  int
+	missingCoords int
+}
+
+func newDataQuality() DataQuality {
+	return DataQuality{RowsSkipped: make(map[string]int)}
+}
+
+// Count a parsed row
+func (q *DataQuality) add(eq Earthquake) {
+	q.RowsParsed++
+	if math.IsNaN(eq.Depth) {
+		q.missingDepth++
+	}
+	if math.IsNaN(eq.Latitude) || math.IsNaN(eq.Longitude) {
+		q.missingCoords++
+	}
+	if q.MagnitudeMin == nil || eq.Magnitude < *q.MagnitudeMin {
+		q.MagnitudeMin = &eq.Magnitude
+	}
+	if q.MagnitudeMax == nil || eq.Magnitude > *q.MagnitudeMax {
+		q.MagnitudeMax = &eq.Magnitude
+	}
+	q.updatePercents()
+}
+
+// Add the rows of another feed
+func (q *DataQuality) merge(other DataQuality) {
+	q.RowsParsed += other.RowsParsed
+	for reason, n := range other.RowsSkipped {
+		q.RowsSkipped[reason] += n
+	}
+	q.DuplicateIDs += other.DuplicateIDs
+	q.missingDepth += other.missingDepth
+	q.missingCoords += other.missingCoords
+	if other.MagnitudeMin != nil && (q.MagnitudeMin == nil || *other.MagnitudeMin < *q.MagnitudeMin) {
+		q.MagnitudeMin = other.MagnitudeMin
+	}
+	if other.MagnitudeMax != nil && (q.MagnitudeMax == nil || *other.MagnitudeMax > *q.MagnitudeMax) {
+		q.MagnitudeMax = other.MagnitudeMax
+	}
+	q.updatePercents()
+}
+
+func (q *DataQuality) updatePercents() {
+	if q.RowsParsed == 0 {
+		return
+	}
+	q.MissingDepthPercent = math.Round(float64(q.missingDepth)*1000/float64(q.RowsParsed)) / 10
+	q.MissingCoordsPercent = math.Round(float64(q.missingCoords)*1000/float64(q.RowsParsed)) / 10
+}
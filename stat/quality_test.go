@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDataQualityOfFixtureWithDefects(t *testing.T) {
+	quakes, quality, err := fetchFeeds(context.Background(), []string{filepath.Join("testdata", "defects.csv")})
+	if err != nil {
+		t.Fatalf("fetchFeeds returned error: %v", err)
+	}
+	if len(quakes) != 3 {
+		t.Fatalf("expected 3 events after dropping the duplicate, got %d", len(quakes))
+	}
+
+	if quality.RowsParsed != 4 || quality.DuplicateIDs != 1 {
+		t.Fatalf("expected 4 parsed rows and 1 duplicate, got %+v", quality)
+	}
+	if want := map[string]int{"invalidTime": 1, "invalidMagnitude": 1}; !reflect.DeepEqual(quality.RowsSkipped, want) {
+		t.Fatalf("unexpected skipped rows %v", quality.RowsSkipped)
+	}
+	if quality.MissingDepthPercent != 25 || quality.MissingCoordsPercent != 25 {
+		t.Fatalf("expected 25%% missing depth and coordinates, got %v and %v", quality.MissingDepthPercent, quality.MissingCoordsPercent)
+	}
+	if *quality.MagnitudeMin != -0.4 || *quality.MagnitudeMax != 5.8 {
+		t.Fatalf("unexpected magnitude range %v - %v", *quality.MagnitudeMin, *quality.MagnitudeMax)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RegionActivity is the weighted number of earthquakes in one region
+type RegionActivity struct {
+	Region string  `json:"region"`
+	Count  int     `json:"count"`
+	Score  float64 `json:"score"`
+}
+
+// Region of a USGS place, the part after the last comma.
+// "45 km SSW of Tobelo, Indonesia" becomes "Indonesia", "Southern Alaska" stays as is.
+func regionOf(place string) string {
+	place = shortPlace(place)
+	if i := strings.LastIndex(place, ","); i >= 0 {
+		place = place[i+1:]
+	}
+	return strings.TrimSpace(place)
+}
+
+// Rank regions by activity, most active first. With a positive halfLife each
+// event is weighted by 0.5^(age/halfLife), where age is measured back from
+// end, so late-week activity counts more. A zero halfLife weights all events
+// equally and the score is the event count.
+func groupByRegion(events []Earthquake, end time.Time, halfLife time.Duration) []RegionActivity {
+	byRegion := make(map[string]*RegionActivity)
+	for _, eq := range events {
+		region := regionOf(eq.Place)
+		if region == "" {
+			continue
+		}
+
+		weight := 1.0
+		if halfLife > 0 {
+			weight = math.Pow(0.5, float64(end.Sub(eq.Time))/float64(halfLife))
+		}
+
+		activity, ok := byRegion[region]
+		if !ok {
+			activity = &RegionActivity{Region: region}
+			byRegion[region] = activity
+		}
+		activity.Count++
+		activity.Score += weight
+	}
+
+	ranking := make([]RegionActivity, 0, len(byRegion))
+	for _, activity := range byRegion {
+		ranking = append(ranking, *activity)
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].Score != ranking[j].Score {
+			return ranking[i].Score > ranking[j].Score
+		}
+		return ranking[i].Region < ranking[j].Region
+	})
+	return ranking
+}
+
+// Read the decay half-life of the region ranking from REGION_HALF_LIFE, e.g. "48h".
+// Unset or invalid means uniform weighting.
+func regionHalfLife() time.Duration {
+	value := os.Getenv("REGION_HALF_LIFE")
+	if value == "" {
+		return 0
+	}
+	halfLife, err := time.ParseDuration(value)
+	if err != nil || halfLife < 0 {
+		fmt.Printf("Ignoring invalid REGION_HALF_LIFE %q\n", value)
+		return 0
+	}
+	return halfLife
+}
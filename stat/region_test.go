@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupByRegionDecayFavorsLateWeekActivity(t *testing.T) {
+	end := time.Date(2026, 6, 8, 0, 0, 0, 0, time.UTC)
+	events := []Earthquake{
+		{Time: time.Date(2026, 6, 1, 2, 0, 0, 0, time.UTC), Place: "10 km N of Early, Chile"},
+		{Time: time.Date(2026, 6, 1, 3, 0, 0, 0, time.UTC), Place: "20 km S of Early, Chile"},
+		{Time: time.Date(2026, 6, 7, 20, 0, 0, 0, time.UTC), Place: "5 km E of Late, Japan"},
+		{Time: time.Date(2026, 6, 7, 21, 0, 0, 0, time.UTC), Place: "8 km W of Late, Japan"},
+	}
+
+	uniform := groupByRegion(events, end, 0)
+	if uniform[0].Region != "Chile" || uniform[0].Score != 2 || uniform[1].Score != 2 {
+		t.Fatalf("expected equal scores ranked by name without decay, got %+v", uniform)
+	}
+
+	decayed := groupByRegion(events, end, 48*time.Hour)
+	if decayed[0].Region != "Japan" || decayed[0].Count != 2 {
+		t.Fatalf("expected the late-week region first with decay, got %+v", decayed)
+	}
+	if decayed[0].Score <= decayed[1].Score {
+		t.Fatalf("expected a higher score for late-week activity, got %+v", decayed)
+	}
+}
@@ -0,0 +1,581 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+var depthBands = []string{
+	"Shallow < 70 km",
+	"Intermediate 70 - 300 km",
+	"Deep > 300 km",
+	"Unknown depth",
+}
+
+var categories = []string{
+	"Micro < 2.0",
+	"Minor 2.0 - 3.9",
+	"Light 4.0 - 4.9",
+	"Moderate 5.0 - 5.9",
+	"Strong 6.0 - 6.9",
+	"Major 7.0 - 7.9",
+	"Great >= 8.0",
+}
+
+// Report is the structured form of a weekly summary
+type Report struct {
+	WeekKey          string                `json:"weekKey"`
+	StartDate        time.Time             `json:"startDate"`
+	EndDate          time.Time             `json:"endDate"`
+	Categories       []CategoryCount       `json:"categories"`
+	Total            int                   `json:"total"`
+	Largest          *Earthquake           `json:"largest,omitempty"`
+	AverageMagnitude float64               `json:"averageMagnitude"`
+	YearAgoTotal     *int                  `json:"yearAgoTotal,omitempty"`
+	Closest          *NearbyEvent          `json:"closest,omitempty"`
+	DepthBands       []CategoryCount       `json:"depthBands,omitempty"`
+	Continents       []CategoryCount       `json:"continents,omitempty"`
+	MagTypes         []CategoryCount       `json:"magTypes,omitempty"`
+	HourCounts       []int                 `json:"hourCounts,omitempty"`
+	WidelyFelt       []FeltEvent           `json:"widelyFelt,omitempty"`
+	MostActive       *RegionActivity       `json:"mostActive,omitempty"`
+	Percentiles      []MagnitudePercentile `json:"percentiles,omitempty"`
+	InterEvent       *InterEventTime       `json:"interEvent,omitempty"`
+	Histogram        []HistogramBin        `json:"histogram,omitempty"`
+	BValue           *BValue               `json:"bValue,omitempty"`
+	DepthCorrelation *DepthCorrelation     `json:"depthCorrelation,omitempty"`
+	Aftershocks      *Declustering         `json:"aftershocks,omitempty"`
+	Milestone        *Milestone            `json:"milestone,omitempty"`
+	PreviousLargest  *float64              `json:"previousLargest,omitempty"`
+	// Title of reports that do not cover a week, the weekly title when empty
+	Title string `json:"title,omitempty"`
+	// Latest event time when it is well before the end of the week
+	DataThrough *time.Time `json:"dataThrough,omitempty"`
+}
+
+// CategoryCount is the number of earthquakes in one magnitude category
+type CategoryCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+func newReport(weekKey string, stats WeekStats) Report {
+	report := Report{
+		WeekKey:   weekKey,
+		StartDate: stats.StartDate,
+		EndDate:   stats.EndDate,
+	}
+
+	excludeMicro := envBool("EXCLUDE_MICRO")
+	for i, count := range stats.Counts {
+		if i == 0 && excludeMicro {
+			continue
+		}
+		report.Categories = append(report.Categories, CategoryCount{Label: categories[i], Count: count})
+	}
+	report.Total = stats.ReportedTotal()
+
+	// The largest event and the average include micro events either way
+	if total := stats.Total(); total > 0 {
+		largest := stats.Largest
+		report.Largest = &largest
+		report.AverageMagnitude = stats.MagnitudeSum / float64(total)
+	}
+
+	if !stats.Latest.IsZero() && stats.EndDate.Sub(stats.Latest) > dataThroughGap {
+		latest := stats.Latest
+		report.DataThrough = &latest
+	}
+
+	return report
+}
+
+// A week whose latest event is more than this before its end is marked as
+// covered only up to that event, the feed window may end before the week does
+const dataThroughGap = 6 * time.Hour
+
+// Depth band counts of a week. The unknown band is only included when it has events.
+func newDepthBands(counts [4]int) []CategoryCount {
+	var bands []CategoryCount
+	for i, count := range counts {
+		if i == 3 && count == 0 {
+			continue
+		}
+		bands = append(bands, CategoryCount{Label: depthBands[i], Count: count})
+	}
+	return bands
+}
+
+// Layout of week boundaries in reports rendered for a time zone
+const zonedTimeLayout = "2006-01-02 15:04 MST"
+
+// Parse REPORT_TZ, a comma-separated list of IANA time zones such as
+// "Europe/Zurich,America/New_York". Unset means a single UTC report.
+func reportZones() ([]*time.Location, error) {
+	var zones []*time.Location
+	for name := range strings.SplitSeq(os.Getenv("REPORT_TZ"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+		}
+		zones = append(zones, loc)
+	}
+	return zones, nil
+}
+
+// Display the week boundaries in loc. Aggregation stays in UTC.
+func (r Report) In(loc *time.Location) Report {
+	r.StartDate = r.StartDate.In(loc)
+	r.EndDate = r.EndDate.In(loc)
+	if r.DataThrough != nil {
+		through := r.DataThrough.In(loc)
+		r.DataThrough = &through
+	}
+	return r
+}
+
+// Render the posts of a report: one per time zone, followed in the built-in
+// detailed format by the magnitude histogram as its own reply
+func renderReportPosts(report Report, zones []*time.Location, format string, tmpl *template.Template) ([]string, error) {
+	texts, err := renderZoneVariants(report, zones, format, tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if format == "detailed" && tmpl == nil {
+		texts = append(texts, histogramPosts(report.Histogram)...)
+	}
+	return texts, nil
+}
+
+// Render one post text per time zone, or a single text in the time zone of
+// the week boundaries without zones
+func renderZoneVariants(report Report, zones []*time.Location, format string, tmpl *template.Template) ([]string, error) {
+	if len(zones) == 0 {
+		zones = []*time.Location{weekLocation}
+	}
+
+	var texts []string
+	for _, loc := range zones {
+		text, err := renderPostText(report.In(loc), format, tmpl)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
+
+// Render the report as the text posted to Bluesky
+func renderText(report Report) string {
+	startTimeStr := report.StartDate.Format(time.RFC3339)[:19] + "Z"
+	endTimeStr := report.EndDate.Format(time.RFC3339)[:19] + "Z"
+	title := "Weekly Earthquake Report"
+	if report.Title != "" {
+		title = report.Title
+	}
+	if loc := report.StartDate.Location(); loc != time.UTC {
+		startTimeStr = report.StartDate.Format(zonedTimeLayout)
+		endTimeStr = report.EndDate.Format(zonedTimeLayout)
+		title += " (" + loc.String() + ")"
+	}
+
+	var reportText strings.Builder
+	if report.Milestone != nil {
+		reportText.WriteString(report.Milestone.Headline() + "\n")
+	}
+	reportText.WriteString(title + "\n")
+	reportText.WriteString(fmt.Sprintf("%s (%s - %s)", report.WeekKey, startTimeStr, endTimeStr))
+	if report.DataThrough != nil {
+		throughStr := report.DataThrough.Format(time.RFC3339)[:19] + "Z"
+		if report.DataThrough.Location() != time.UTC {
+			throughStr = report.DataThrough.Format(zonedTimeLayout)
+		}
+		reportText.WriteString(fmt.Sprintf(" (data through %s)", throughStr))
+	}
+	reportText.WriteString("\n\n")
+
+	showPercent := envBool("CATEGORY_PERCENT")
+	for _, category := range report.Categories {
+		count := formatCount(category.Count)
+		if showPercent {
+			count += formatPercent(category.Count, report.Total)
+		}
+		reportText.WriteString(fmt.Sprintf("%s: %s\n", category.Label, count))
+	}
+	reportText.WriteString(fmt.Sprintf("\nTotal: %s", formatCount(report.Total)))
+	if report.YearAgoTotal != nil {
+		reportText.WriteString(fmt.Sprintf("\nSame week last year: %s (%s)", formatCount(*report.YearAgoTotal), formatDelta(report.Total-*report.YearAgoTotal)))
+	}
+	if line := peakMagnitudeLine(report); line != "" {
+		reportText.WriteString("\n" + line)
+	}
+	if report.Closest != nil {
+		reportText.WriteString(fmt.Sprintf("\nClosest to you: M%s, %.0f km away near %s",
+			formatMag(report.Closest.Event.Magnitude), report.Closest.DistanceKm, shortPlace(report.Closest.Event.Place)))
+	}
+	if report.MostActive != nil {
+		count := formatCount(report.MostActive.Count)
+		if context := populationContext(report.MostActive.Region, report.MostActive.Count); context != "" {
+			count += ", " + context
+		}
+		reportText.WriteString(fmt.Sprintf("\nMost active region: %s (%s)", regionLabel(report.MostActive.Region), count))
+	}
+	if len(report.DepthBands) > 0 {
+		reportText.WriteString("\n")
+		for _, band := range report.DepthBands {
+			reportText.WriteString(fmt.Sprintf("\n%s: %s", band.Label, formatCount(band.Count)))
+		}
+	}
+	if len(report.Continents) > 0 {
+		parts := make([]string, len(report.Continents))
+		for i, continent := range report.Continents {
+			parts[i] = fmt.Sprintf("%s %s", continent.Label, formatCount(continent.Count))
+		}
+		reportText.WriteString("\n\nBy continent: " + strings.Join(parts, ", "))
+	}
+	if len(report.MagTypes) > 0 {
+		parts := make([]string, len(report.MagTypes))
+		for i, magType := range report.MagTypes {
+			parts[i] = fmt.Sprintf("%s %s", magType.Label, formatCount(magType.Count))
+		}
+		reportText.WriteString("\n\nBy magnitude type: " + strings.Join(parts, ", "))
+	}
+	if line := feltLine(report.WidelyFelt); line != "" {
+		reportText.WriteString("\n\n" + line)
+	}
+	if line := hourLine(report.HourCounts); line != "" {
+		reportText.WriteString("\n\n" + line)
+	}
+
+	return reportText.String()
+}
+
+// Percentages of small weeks jump by whole categories and tell little
+const minPercentTotal = 20
+
+// Share of count in total as a suffix such as " (66%)", " (<1%)" for a small
+// nonzero share. Empty below minPercentTotal events. Whole percentages that
+// do not add up to 100 exactly are fine for a glance.
+func formatPercent(count, total int) string {
+	if total < minPercentTotal {
+		return ""
+	}
+	percent := math.Round(float64(count) * 100 / float64(total))
+	if percent == 0 && count > 0 {
+		return " (<1%)"
+	}
+	return fmt.Sprintf(" (%.0f%%)", percent)
+}
+
+// Compare the largest magnitude with the previous week, e.g.
+// "Peak magnitude: M6.8 (last week M5.9, ▲ +0.9)". Empty without a previous week.
+func peakMagnitudeLine(report Report) string {
+	if report.Largest == nil || report.PreviousLargest == nil {
+		return ""
+	}
+
+	current, previous := report.Largest.Magnitude, *report.PreviousLargest
+	// Compare the displayed tenths so the delta matches the shown magnitudes
+	tenths := int(math.Round(current*10) - math.Round(previous*10))
+	change := "unchanged"
+	switch {
+	case tenths > 0:
+		change = fmt.Sprintf("▲ +%d.%d", tenths/10, tenths%10)
+	case tenths < 0:
+		change = fmt.Sprintf("▼ -%d.%d", -tenths/10, -tenths%10)
+	}
+	return fmt.Sprintf("Peak magnitude: M%s (last week M%s, %s)", formatMag(current), formatMag(previous), change)
+}
+
+// Render the report as a single line for crowded feeds, e.g.
+// "This week: 1,234 quakes (3 strong, 1 major) · largest M6.8 near Tobelo, Indonesia"
+func renderCompact(report Report) string {
+	lead := "This week"
+	if report.Milestone != nil {
+		// Keep the compact format on a single line
+		lead = report.Milestone.Headline() + " · " + lead
+	}
+	return renderSummaryLine(lead, report)
+}
+
+// Render the one-line summary after the given lead, e.g. "This week"
+func renderSummaryLine(lead string, report Report) string {
+	var notable []string
+	strongAndAbove := report.Categories[len(report.Categories)-3:]
+	for i, name := range []string{"strong", "major", "great"} {
+		if count := strongAndAbove[i].Count; count > 0 {
+			notable = append(notable, fmt.Sprintf("%d %s", count, name))
+		}
+	}
+
+	line := fmt.Sprintf("%s: %s quakes", lead, formatThousands(report.Total))
+	if len(notable) > 0 {
+		line += " (" + strings.Join(notable, ", ") + ")"
+	}
+	if report.Largest == nil {
+		return line
+	}
+
+	line += " · largest M" + formatMag(report.Largest.Magnitude)
+	place := shortPlace(report.Largest.Place)
+	if place == "" {
+		return line
+	}
+
+	// Cut overly long place names so the line never needs to be split
+	if excess := postLength(line+" near "+place) - maxPostLength; excess > 0 {
+		runes := []rune(place)
+		place = string(runes[:max(len(runes)-excess-1, 0)]) + "…"
+	}
+	return line + " near " + place
+}
+
+// Format a magnitude with one decimal place, rounding halves away from zero
+// so that 5.25 becomes "5.3" instead of the binary-rounded "5.2"
+func formatMag(m float64) string {
+	return strconv.FormatFloat(math.Round(m*10)/10, 'f', 1, 64)
+}
+
+// Strip the distance and direction from a USGS place, "45 km SSW of Tobelo, Indonesia" becomes "Tobelo, Indonesia"
+func shortPlace(place string) string {
+	if _, after, found := strings.Cut(place, " of "); found {
+		return after
+	}
+	return place
+}
+
+// Format a count with thousands separators, "12,847" by default
+func formatThousands(n int) string {
+	if n < 0 {
+		return "-" + formatThousands(-n)
+	}
+	digits := strconv.Itoa(n)
+	separator := thousandsSeparator()
+
+	var out strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out.WriteString(separator)
+		}
+		out.WriteRune(d)
+	}
+	return out.String()
+}
+
+// Separator of digit groups from THOUSANDS_SEPARATOR, e.g. "." or "'". "space"
+// means a narrow no-break space so numbers are not wrapped. Defaults to ",".
+func thousandsSeparator() string {
+	switch separator := os.Getenv("THOUSANDS_SEPARATOR"); separator {
+	case "":
+		return ","
+	case "space":
+		return "\u202f"
+	default:
+		return separator
+	}
+}
+
+// Format a count of the text report, grouped with GROUP_DIGITS=true. The
+// compact line always groups digits.
+func formatCount(n int) string {
+	if envBool("GROUP_DIGITS") {
+		return formatThousands(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// Format a signed difference of counts such as "+1,204" or "-12"
+func formatDelta(n int) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	return "+" + formatCount(n)
+}
+
+// Maximum length of a Bluesky post in graphemes
+const maxPostLength = 300
+
+// Length of a post, approximating graphemes by runes
+func postLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// Functions available in report templates
+var templateFuncs = template.FuncMap{
+	"mag":       formatMag,
+	"thousands": formatThousands,
+}
+
+// Parse the report template at path. An empty path means the built-in format.
+func loadTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Render the built-in layout followed by the magnitude statistics. The
+// histogram is posted as a reply of its own, see renderReportPosts.
+func renderDetailed(report Report) string {
+	text := renderText(report)
+	interpretation := report.DepthCorrelation.Interpretation()
+	if len(report.Percentiles) == 0 && report.InterEvent == nil && report.BValue == nil && interpretation == "" && report.Aftershocks == nil {
+		return text
+	}
+
+	text += "\n"
+	if len(report.Percentiles) > 0 {
+		var parts []string
+		for _, p := range report.Percentiles {
+			parts = append(parts, fmt.Sprintf("p%.0f M%s", p.Percentile, formatMag(p.Magnitude)))
+		}
+		text += "\nMagnitude percentiles: " + strings.Join(parts, ", ")
+	}
+	if ie := report.InterEvent; ie != nil {
+		text += fmt.Sprintf("\nTime between events: mean %s, median %s", formatInterval(ie.MeanSeconds), formatInterval(ie.MedianSeconds))
+	}
+	if b := report.BValue; b != nil {
+		text += fmt.Sprintf("\nb-value: %.2f (M%s and above, %s events)", b.Value, formatMag(b.CompletenessMag), formatCount(b.Events))
+	}
+	if interpretation != "" {
+		text += fmt.Sprintf("\n%s (depth vs. magnitude r = %.2f)", interpretation, report.DepthCorrelation.R)
+	}
+	if line := aftershockLine(report.Aftershocks); line != "" {
+		text += "\n" + line
+	}
+	return text
+}
+
+// Render the text posted to Bluesky. The compact format takes precedence over
+// a custom template, which in turn replaces the built-in layouts. A post over
+// maxPostLength loses optional sections until it fits.
+func renderPostText(report Report, format string, tmpl *template.Template) (string, error) {
+	text, err := renderSections(report, format, tmpl)
+	if err != nil || postLength(text) <= maxPostLength {
+		return text, err
+	}
+
+	// Drop optional sections, lowest priority first, until the post fits
+	var dropped []string
+	for _, section := range optionalSections {
+		section.drop(&report)
+		shorter, err := renderSections(report, format, tmpl)
+		if err != nil {
+			return "", err
+		}
+		if shorter != text {
+			dropped = append(dropped, section.name)
+			text = shorter
+		}
+		if postLength(text) <= maxPostLength {
+			break
+		}
+	}
+	if len(dropped) > 0 {
+		fmt.Printf("Warning: report of week %s too long, dropped %s\n", report.WeekKey, strings.Join(dropped, ", "))
+	}
+
+	// Without optional sections left, cut the text rather than fail to post
+	if postLength(text) > maxPostLength {
+		fmt.Printf("Warning: report of week %s still too long, cutting it to %d graphemes\n", report.WeekKey, maxPostLength)
+		text = string([]rune(text)[:maxPostLength-1]) + "…"
+	}
+	return text, nil
+}
+
+// Optional report sections in the order they are dropped from a post that is
+// too long, lowest priority first. The footer is handled by withProvenance.
+// The histogram is only part of the post in custom templates.
+var optionalSections = []struct {
+	name string
+	drop func(*Report)
+}{
+	{"histogram", func(r *Report) { r.Histogram = nil }},
+	{"hours", func(r *Report) { r.HourCounts = nil }},
+	{"aftershocks", func(r *Report) { r.Aftershocks = nil }},
+	{"magnitude types", func(r *Report) { r.MagTypes = nil }},
+	{"continents", func(r *Report) { r.Continents = nil }},
+	{"widely felt", func(r *Report) { r.WidelyFelt = nil }},
+	{"depth correlation", func(r *Report) { r.DepthCorrelation = nil }},
+	{"b-value", func(r *Report) { r.BValue = nil }},
+	{"inter-event times", func(r *Report) { r.InterEvent = nil }},
+	{"percentiles", func(r *Report) { r.Percentiles = nil }},
+	{"depth bands", func(r *Report) { r.DepthBands = nil }},
+	{"most active region", func(r *Report) { r.MostActive = nil }},
+	{"closest event", func(r *Report) { r.Closest = nil }},
+	{"peak magnitude trend", func(r *Report) { r.PreviousLargest = nil }},
+	{"year-ago trend", func(r *Report) { r.YearAgoTotal = nil }},
+	{"milestone", func(r *Report) { r.Milestone = nil }},
+}
+
+// Render the report with all of its sections after the optional intro line.
+// The intro counts toward the post length and is never dropped.
+func renderSections(report Report, format string, tmpl *template.Template) (string, error) {
+	text, err := renderBody(report, format, tmpl)
+	if intro := introLine(report); intro != "" && err == nil {
+		text = intro + "\n" + text
+	}
+	return text, err
+}
+
+// Render the report in the given format or template
+func renderBody(report Report, format string, tmpl *template.Template) (string, error) {
+	if format == "compact" {
+		return renderCompact(report), nil
+	}
+	if tmpl == nil {
+		if format == "detailed" {
+			return renderDetailed(report), nil
+		}
+		return renderText(report), nil
+	}
+
+	var text strings.Builder
+	if err := tmpl.Execute(&text, report); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return text.String(), nil
+}
+
+func toJSON(report Report) ([]byte, error) {
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// Print the report to the console in the requested format
+func printReport(w io.Writer, reportData ReportData, format string) error {
+	switch format {
+	case "text", "compact", "detailed":
+		_, err := fmt.Fprintln(w, reportData.ReportText)
+		return err
+	case "json":
+		data, err := toJSON(reportData.Report)
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestReportJSONRoundTrip(t *testing.T) {
+	quakes := []Earthquake{
+		{Time: time.Date(2026, 6, 1, 1, 0, 0, 0, time.UTC), Magnitude: 1.5, Place: "Alaska"},
+		{Time: time.Date(2026, 6, 2, 2, 0, 0, 0, time.UTC), Magnitude: 6.5, Place: "Fiji"},
+		{Time: time.Date(2026, 6, 3, 3, 0, 0, 0, time.UTC), Magnitude: 4.0, Place: "Chile"},
+	}
+	weeks := groupByWeek(quakes)
+	report := newReport("2026-W23", weeks["2026-W23"])
+
+	data, err := toJSON(report)
+	if err != nil {
+		t.Fatalf("toJSON returned error: %v", err)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode report JSON: %v", err)
+	}
+	if !reflect.DeepEqual(report, decoded) {
+		t.Fatalf("round trip mismatch:\nwant %+v\ngot  %+v", report, decoded)
+	}
+
+	if decoded.Total != 3 {
+		t.Fatalf("expected total 3, got %d", decoded.Total)
+	}
+	if decoded.Largest == nil || decoded.Largest.Place != "Fiji" {
+		t.Fatalf("expected largest event in Fiji, got %+v", decoded.Largest)
+	}
+	if decoded.AverageMagnitude != 4.0 {
+		t.Fatalf("expected average magnitude 4.0, got %f", decoded.AverageMagnitude)
+	}
+}
+
+func TestRenderCompact(t *testing.T) {
+	report := Report{
+		Categories: []CategoryCount{
+			{Count: 400}, {Count: 800}, {Count: 30}, {Count: 0}, {Count: 3}, {Count: 1}, {Count: 0},
+		},
+		Total:   1234,
+		Largest: &Earthquake{Magnitude: 6.8, Place: "45 km SSW of Tobelo, Indonesia"},
+	}
+
+	got := renderCompact(report)
+	want := "This week: 1,234 quakes (3 strong, 1 major) · largest M6.8 near Tobelo, Indonesia"
+	if got != want {
+		t.Fatalf("unexpected compact report:\nwant %q\ngot  %q", want, got)
+	}
+
+	report.Largest.Place = "Somewhere " + strings.Repeat("very ", 80) + "far away"
+	if length := postLength(renderCompact(report)); length > maxPostLength {
+		t.Fatalf("expected compact report to fit in %d graphemes, got %d", maxPostLength, length)
+	}
+}
+
+func TestFormatMagRoundsHalvesUp(t *testing.T) {
+	tests := map[float64]string{
+		5.25:      "5.3",
+		6.75:      "6.8",
+		4.95:      "5.0",
+		5.2999999: "5.3",
+		5.24:      "5.2",
+		7:         "7.0",
+	}
+	for mag, want := range tests {
+		if got := formatMag(mag); got != want {
+			t.Errorf("formatMag(%v) = %q, want %q", mag, got, want)
+		}
+	}
+}
+
+func TestRenderPostTextWithCustomTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	content := "{{.WeekKey}}: {{thousands .Total}} quakes{{with .Largest}}, largest M{{mag .Magnitude}}{{end}}"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := loadTemplate(path)
+	if err != nil {
+		t.Fatalf("loadTemplate returned error: %v", err)
+	}
+
+	report := Report{WeekKey: "2026-W23", Total: 1234, Largest: &Earthquake{Magnitude: 6.75}}
+	text, err := renderPostText(report, "text", tmpl)
+	if err != nil {
+		t.Fatalf("renderPostText returned error: %v", err)
+	}
+	if want := "2026-W23: 1,234 quakes, largest M6.8"; text != want {
+		t.Fatalf("unexpected template output:\nwant %q\ngot  %q", want, text)
+	}
+
+	if err := os.WriteFile(path, []byte("{{.Total"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTemplate(path); err == nil {
+		t.Fatal("expected an error for an invalid template")
+	}
+}
+
+func TestRenderZoneVariantsFromOneWeek(t *testing.T) {
+	weeks := groupByWeek([]Earthquake{
+		{Time: time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC), Magnitude: 3.1},
+	})
+	report := newReport("2026-W23", weeks["2026-W23"])
+
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts, err := renderZoneVariants(report, []*time.Location{zurich, newYork}, "text", nil)
+	if err != nil {
+		t.Fatalf("renderZoneVariants returned error: %v", err)
+	}
+	if len(texts) != 2 {
+		t.Fatalf("expected 2 variants, got %d", len(texts))
+	}
+
+	if !strings.Contains(texts[0], "Weekly Earthquake Report (Europe/Zurich)") ||
+		!strings.Contains(texts[0], "2026-W23 (2026-06-01 02:00 CEST - 2026-06-08 01:59 CEST)") {
+		t.Fatalf("unexpected Zurich variant:\n%s", texts[0])
+	}
+	if !strings.Contains(texts[1], "2026-W23 (2026-05-31 20:00 EDT - 2026-06-07 19:59 EDT)") {
+		t.Fatalf("unexpected New York variant:\n%s", texts[1])
+	}
+	for _, text := range texts {
+		if !strings.Contains(text, "Minor 2.0 - 3.9: 1") {
+			t.Fatalf("expected counts to be identical across zones:\n%s", text)
+		}
+	}
+
+	texts, err = renderZoneVariants(report, nil, "text", nil)
+	if err != nil || len(texts) != 1 || texts[0] != renderText(report) {
+		t.Fatalf("expected a single UTC report without zones, got %q (err %v)", texts, err)
+	}
+}
+
+func TestExcludeMicroOmitsMicroEventsFromTotal(t *testing.T) {
+	stats := groupByWeek([]Earthquake{
+		{Time: time.Date(2026, 6, 1, 1, 0, 0, 0, time.UTC), Magnitude: 0.8},
+		{Time: time.Date(2026, 6, 1, 2, 0, 0, 0, time.UTC), Magnitude: 1.9},
+		{Time: time.Date(2026, 6, 1, 3, 0, 0, 0, time.UTC), Magnitude: 2.5},
+		{Time: time.Date(2026, 6, 1, 4, 0, 0, 0, time.UTC), Magnitude: 6.1, Place: "12 km N of Strong, Chile"},
+	})["2026-W23"]
+
+	if report := newReport("2026-W23", stats); report.Total != 4 || len(report.Categories) != 7 {
+		t.Fatalf("expected micro events to count by default, got total %d", report.Total)
+	}
+
+	t.Setenv("EXCLUDE_MICRO", "true")
+	report := newReport("2026-W23", stats)
+	if report.Total != 2 || len(report.Categories) != 6 || report.Categories[0].Label != categories[1] {
+		t.Fatalf("expected micro events to be left out, got total %d, categories %+v", report.Total, report.Categories)
+	}
+	if stats.Counts[0] != 2 || report.AverageMagnitude != 2.825 {
+		t.Fatalf("expected micro events to stay in the stats, got counts %v, average %v", stats.Counts, report.AverageMagnitude)
+	}
+	text := renderText(report)
+	if strings.Contains(text, "Micro") || !strings.Contains(text, "Total: 2") {
+		t.Fatalf("unexpected text:\n%s", text)
+	}
+	if got := renderCompact(report); got != "This week: 2 quakes (1 strong) · largest M6.1 near Strong, Chile" {
+		t.Fatalf("unexpected compact text %q", got)
+	}
+}
+
+func TestPeakMagnitudeComparedWithPreviousWeek(t *testing.T) {
+	store := openTestStore(t)
+	weeks := groupByWeek([]Earthquake{
+		{Time: time.Date(2026, 5, 26, 0, 0, 0, 0, time.UTC), Magnitude: 5.94},
+		{Time: time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC), Magnitude: 6.8},
+		{Time: time.Date(2026, 6, 9, 0, 0, 0, 0, time.UTC), Magnitude: 6.4},
+	})
+
+	if report := buildReport(store, "2026-W22", weeks["2026-W22"]); peakMagnitudeLine(report) != "" {
+		t.Fatalf("expected no peak line without a previous week, got %q", peakMagnitudeLine(report))
+	}
+
+	store.StoreWeekStats("2026-W22", weeks["2026-W22"])
+	store.StoreWeekStats("2026-W23", weeks["2026-W23"])
+	tests := map[string]string{
+		"2026-W23": "Peak magnitude: M6.8 (last week M5.9, ▲ +0.9)",
+		"2026-W24": "Peak magnitude: M6.4 (last week M6.8, ▼ -0.4)",
+	}
+	for weekKey, want := range tests {
+		text := renderText(buildReport(store, weekKey, weeks[weekKey]))
+		if !strings.Contains(text, "\n"+want+"\n") && !strings.HasSuffix(text, "\n"+want) {
+			t.Fatalf("expected %q in the %s report, got:\n%s", want, weekKey, text)
+		}
+	}
+}
+
+func TestRenderPostTextDropsSectionsUntilItFits(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := newReport("2026-W23", WeekStats{
+		StartDate: start,
+		EndDate:   start.AddDate(0, 0, 7).Add(-time.Second),
+		Counts:    [7]int{1200, 800, 40, 10, 2, 1, 0},
+		Largest:   Earthquake{Magnitude: 7.1, Place: "South of the Fiji Islands"},
+	})
+	yearAgo, previousLargest := 1900, 6.2
+	report.YearAgoTotal = &yearAgo
+	report.PreviousLargest = &previousLargest
+	report.MostActive = &RegionActivity{Region: "Northern Mid-Atlantic Ridge near the Charlie-Gibbs Fracture Zone", Count: 42}
+	report.DepthBands = newDepthBands([4]int{1900, 120, 33, 0})
+	for _, name := range []string{"Asia", "North America", "South America", "Oceania", "Europe", "Africa", "Pacific Ocean", "Atlantic Ocean"} {
+		report.Continents = append(report.Continents, CategoryCount{Label: name, Count: 100})
+	}
+
+	full, _ := renderSections(report, "text", nil)
+	if postLength(full) <= maxPostLength {
+		t.Fatalf("expected the test report to be too long, got %d graphemes", postLength(full))
+	}
+
+	text, err := renderPostText(report, "text", nil)
+	if err != nil {
+		t.Fatalf("renderPostText returned error: %v", err)
+	}
+	if postLength(text) > maxPostLength {
+		t.Fatalf("expected the post to fit, got %d graphemes", postLength(text))
+	}
+	if strings.Contains(text, "By continent") || strings.Contains(text, "Shallow") {
+		t.Fatalf("expected the lowest priority sections to be dropped, got %q", text)
+	}
+	for _, kept := range []string{"Total: 2053", "Same week last year"} {
+		if !strings.Contains(text, kept) {
+			t.Errorf("expected %q to be kept, got %q", kept, text)
+		}
+	}
+
+	// Without optional sections to drop, the text is cut
+	tmpl := template.Must(template.New("long").Parse(strings.Repeat("x", 400)))
+	if text, _ := renderPostText(report, "text", tmpl); postLength(text) != maxPostLength || !strings.HasSuffix(text, "…") {
+		t.Fatalf("expected the text to be cut to %d graphemes, got %d", maxPostLength, postLength(text))
+	}
+}
+
+func TestFormatCountGroupsDigits(t *testing.T) {
+	if got := formatCount(12847); got != "12847" {
+		t.Fatalf("expected plain digits by default, got %q", got)
+	}
+
+	t.Setenv("GROUP_DIGITS", "true")
+	tests := map[int]string{
+		0:       "0",
+		999:     "999",
+		1000:    "1,000",
+		12847:   "12,847",
+		1234567: "1,234,567",
+		-4321:   "-4,321",
+	}
+	for n, want := range tests {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+	if got := formatDelta(-1204); got != "-1,204" {
+		t.Errorf("formatDelta(-1204) = %q, want %q", got, "-1,204")
+	}
+
+	t.Setenv("THOUSANDS_SEPARATOR", ".")
+	if got := formatCount(1234567); got != "1.234.567" {
+		t.Errorf("expected dots, got %q", got)
+	}
+	t.Setenv("THOUSANDS_SEPARATOR", "space")
+	if got := formatCount(12847); got != "12 847" {
+		t.Errorf("expected a narrow no-break space, got %q", got)
+	}
+
+	report := Report{Categories: []CategoryCount{{Label: "Minor 2.0 - 3.9", Count: 12847}}, Total: 12847}
+	if text := renderText(report); !strings.Contains(text, "Minor 2.0 - 3.9: 12 847") || !strings.Contains(text, "Total: 12 847") {
+		t.Fatalf("expected grouped counts in the text report:\n%s", text)
+	}
+}
+
+func TestRenderTextShowsDataThrough(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	stats := WeekStats{StartDate: start, EndDate: start.AddDate(0, 0, 7).Add(-time.Second)}
+	stats.add(Earthquake{Time: time.Date(2026, 6, 3, 8, 0, 0, 0, time.UTC), Magnitude: 2.5})
+	stats.add(Earthquake{Time: time.Date(2026, 6, 7, 14, 30, 0, 0, time.UTC), Magnitude: 3.1})
+
+	text := renderText(newReport("2026-W23", stats))
+	want := "2026-W23 (2026-06-01T00:00:00Z - 2026-06-07T23:59:59Z) (data through 2026-06-07T14:30:00Z)\n"
+	if !strings.Contains(text, want) {
+		t.Fatalf("expected %q in the report, got:\n%s", want, text)
+	}
+
+	// An event in the last hours of the week covers it
+	stats.add(Earthquake{Time: time.Date(2026, 6, 7, 21, 0, 0, 0, time.UTC), Magnitude: 1.2})
+	if text := renderText(newReport("2026-W23", stats)); strings.Contains(text, "data through") {
+		t.Fatalf("expected no data through note for a covered week, got:\n%s", text)
+	}
+}
+
+func TestReportOrderingIsStableAcrossRuns(t *testing.T) {
+	store := openTestStore(t)
+	t.Setenv("CONTINENT_BREAKDOWN", "true")
+	t.Setenv("MAG_TYPE_BREAKDOWN", "true")
+	t.Setenv("HISTOGRAM_BIN_WIDTH", "0.5")
+
+	// Every breakdown has ties: two events each in Japan, Chile and Alaska,
+	// two per magnitude type and the same number of felt reports
+	base := time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC)
+	places := []string{"10 km N of Tokyo, Japan", "5 km S of Arica, Chile", "20 km W of Anchorage, Alaska"}
+	lats := []float64{35.7, -18.5, 61.2}
+	lons := []float64{139.7, -70.3, -149.9}
+	magTypes := []string{"mww", "mb", "ml"}
+	var events []Earthquake
+	for i := range 6 {
+		events = append(events, Earthquake{
+			ID:        fmt.Sprintf("us%d", i),
+			Time:      base.Add(time.Duration(i) * time.Hour),
+			Magnitude: 4.5,
+			Place:     places[i%3],
+			Latitude:  lats[i%3],
+			Longitude: lons[i%3],
+			Depth:     10,
+			MagType:   magTypes[i%3],
+			Felt:      500,
+		})
+	}
+
+	var first Report
+	var firstText string
+	for run := range 50 {
+		weeks := groupByWeek(events)
+		report := buildReport(store, "2026-W23", weeks["2026-W23"])
+		text := renderDetailed(report)
+		if run == 0 {
+			first, firstText = report, text
+			continue
+		}
+		if text != firstText {
+			t.Fatalf("run %d rendered a different report:\n%s\nfirst run:\n%s", run, text, firstText)
+		}
+	}
+
+	if first.MostActive == nil || first.MostActive.Region != "Alaska" {
+		t.Fatalf("expected ties in the region ranking to go to the first name, got %+v", first.MostActive)
+	}
+	if got := first.MagTypes; len(got) != 3 || got[0].Label != "ML" || got[1].Label != "Mw" || got[2].Label != "mb" {
+		t.Fatalf("expected tied magnitude types by name, got %+v", got)
+	}
+	if got := first.WidelyFelt; len(got) != 6 || got[0].Event.ID != "us2" || got[1].Event.ID != "us5" {
+		t.Fatalf("expected tied felt events by place and ID, got %+v", got)
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	tests := []struct {
+		count, total int
+		want         string
+	}{
+		{812, 1230, " (66%)"},
+		{1, 1230, " (<1%)"},
+		{0, 1230, " (0%)"},
+		{1230, 1230, " (100%)"},
+		{5, 20, " (25%)"},
+		{3, 19, ""},
+		{0, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := formatPercent(tt.count, tt.total); got != tt.want {
+			t.Errorf("formatPercent(%d, %d) = %q, want %q", tt.count, tt.total, got, tt.want)
+		}
+	}
+
+	t.Setenv("CATEGORY_PERCENT", "true")
+	t.Setenv("EXCLUDE_MICRO", "true")
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := newReport("2026-W23", WeekStats{
+		StartDate: start,
+		EndDate:   start.AddDate(0, 0, 7).Add(-time.Second),
+		Counts:    [7]int{5000, 812, 400, 18, 0, 0, 0},
+	})
+	text := renderText(report)
+	if !strings.Contains(text, "\nMinor 2.0 - 3.9: 812 (66%)\nLight 4.0 - 4.9: 400 (33%)\nModerate 5.0 - 5.9: 18 (1%)\nStrong 6.0 - 6.9: 0 (0%)\n") {
+		t.Fatalf("expected percentages of the total without micro events, got:\n%s", text)
+	}
+}
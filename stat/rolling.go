@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultRollingDays = 7
+
+// Report mode from REPORT_MODE: "weekly" for ISO weeks (default) or
+// "rolling" for the trailing ROLLING_DAYS days, posted once a day
+func reportMode() string {
+	if os.Getenv("REPORT_MODE") == "rolling" {
+		return "rolling"
+	}
+	return "weekly"
+}
+
+// Stats of the trailing days ending now. Unlike ISO weeks the window moves
+// with every run, so a daily post always covers the latest events.
+func rollingWindow(events []Earthquake, days int) WeekStats {
+	end := now().UTC()
+	stats := WeekStats{StartDate: end.AddDate(0, 0, -days), EndDate: end}
+	for _, eq := range events {
+		if !eq.Time.Before(stats.StartDate) && eq.Time.Before(end) {
+			stats.add(eq)
+		}
+	}
+	return stats
+}
+
+// Title of a rolling report, the weekly title for a window of seven days
+func rollingTitle(days int) string {
+	switch days {
+	case 1:
+		return "Earthquakes of the last day"
+	case 7:
+		return ""
+	default:
+		return fmt.Sprintf("Earthquakes of the last %d days", days)
+	}
+}
+
+// Post the rolling report at most once per UTC date
+func runRolling(ctx context.Context, store *Store, cfg runConfig, earthquakes []Earthquake, urls []string, fetchedAt time.Time, summary *RunSummary) {
+	date := now().UTC().Format("2006-01-02")
+	if store.WasRollingPosted(date) {
+		fmt.Printf("Rolling report for %s already posted\n", date)
+		return
+	}
+
+	days := envInt("ROLLING_DAYS", defaultRollingDays)
+	stats := rollingWindow(earthquakes, days)
+	report := newReport(fmt.Sprintf("Last %d days", days), stats)
+	report.Title = rollingTitle(days)
+	addEventSections(&report, stats)
+
+	texts, err := renderReportPosts(report, cfg.zones, cfg.format, cfg.tmpl)
+	if err != nil {
+		summary.addError("rendering report", err)
+		return
+	}
+	texts = withThreadProvenance(texts, cfg.format, fetchedAt, feedWindow(urls))
+	reportData := ReportData{WeekKey: date, Report: report, ReportText: texts[0], Thread: texts[1:]}
+	if err := printReport(os.Stdout, reportData, cfg.format); err != nil {
+		summary.addError("printing report", err)
+	}
+
+	if err := checkEventFloor(report); err != nil {
+		fmt.Printf("Warning: not posting report, the feed may be incomplete: %v\n", err)
+	} else if _, err := postThread(ctx, store, rollingKeyPrefix+date, reportData.Posts()); err != nil {
+		summary.addError("posting rolling report", err)
+	} else {
+		store.MarkRollingPosted(date)
+		summary.WeekPosted = &date
+	}
+}
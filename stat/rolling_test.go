@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRollingWindowOverTenDays(t *testing.T) {
+	setNow(t, time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC))
+
+	// One event per day at noon, June 1 to 10, magnitude 2.1 to 3.0
+	var events []Earthquake
+	for day := 1; day <= 10; day++ {
+		events = append(events, Earthquake{
+			ID:        string(rune('a' + day - 1)),
+			Time:      time.Date(2026, 6, day, 12, 0, 0, 0, time.UTC),
+			Magnitude: 2.0 + float64(day)/10,
+		})
+	}
+
+	stats := rollingWindow(events, 7)
+	if stats.Total() != 7 {
+		t.Fatalf("expected the events of June 3 to 9, got %d", stats.Total())
+	}
+	if !stats.StartDate.Equal(time.Date(2026, 6, 3, 6, 0, 0, 0, time.UTC)) || !stats.EndDate.Equal(now()) {
+		t.Fatalf("unexpected window %v - %v", stats.StartDate, stats.EndDate)
+	}
+	if stats.Largest.ID != "i" {
+		t.Fatalf("expected the June 9 event to be the largest, got %+v", stats.Largest)
+	}
+
+	if got := rollingWindow(events, 3).Total(); got != 3 {
+		t.Fatalf("expected 3 events in a 3 day window, got %d", got)
+	}
+}
+
+func TestRunRollingPostsOncePerDay(t *testing.T) {
+	pds := newMockPDS(t)
+	store := openTestStore(t)
+	t.Setenv("MIN_WEEKLY_EVENTS", "1")
+	setNow(t, time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC))
+	events := []Earthquake{{ID: "a", Time: time.Date(2026, 6, 9, 12, 0, 0, 0, time.UTC), Magnitude: 4.2}}
+
+	for range 2 {
+		var summary RunSummary
+		runRolling(context.Background(), store, runConfig{format: "text"}, events, nil, now(), &summary)
+		if len(summary.Errors) != 0 {
+			t.Fatalf("runRolling reported errors: %v", summary.Errors)
+		}
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 1 {
+		t.Fatalf("expected one post per day, got %d", len(created))
+	}
+
+	setNow(t, time.Date(2026, 6, 11, 6, 0, 0, 0, time.UTC))
+	var summary RunSummary
+	runRolling(context.Background(), store, runConfig{format: "text"}, events, nil, now(), &summary)
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 2 {
+		t.Fatalf("expected a new post on the next day, got %d", len(created))
+	}
+}
+
+func TestRollingTitleNamesTheWindow(t *testing.T) {
+	pds := newMockPDS(t)
+	store := openTestStore(t)
+	t.Setenv("MIN_WEEKLY_EVENTS", "1")
+	t.Setenv("ROLLING_DAYS", "10")
+	setNow(t, time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC))
+	events := []Earthquake{{ID: "a", Time: time.Date(2026, 6, 9, 12, 0, 0, 0, time.UTC), Magnitude: 4.2}}
+
+	var summary RunSummary
+	runRolling(context.Background(), store, runConfig{format: "text"}, events, nil, now(), &summary)
+	created := pds.calls("com.atproto.repo.createRecord")
+	if len(created) != 1 {
+		t.Fatalf("expected one post, got %d (errors %v)", len(created), summary.Errors)
+	}
+	if text := created[0]["record"].(map[string]any)["text"].(string); !strings.HasPrefix(text, "Earthquakes of the last 10 days\nLast 10 days (") {
+		t.Fatalf("expected the title to name the window, got %q", text)
+	}
+
+	if rollingTitle(7) != "" || rollingTitle(1) != "Earthquakes of the last day" {
+		t.Fatalf("unexpected titles %q and %q", rollingTitle(7), rollingTitle(1))
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// runConfig holds the options of a regular run
+type runConfig struct {
+	format     string
+	tmpl       *template.Template
+	zones      []*time.Location
+	thresholds map[string]float64
+	draft      bool
+	mapFile    string
+	// Directory of the markdown archive, empty to write none
+	markdownDir    string
+	markdownCommit bool
+	// CSV file the weekly totals are appended to, empty to write none
+	archiveCSV string
+	// Local time to post complete weeks at, nil to post right away
+	postAt *postSchedule
+}
+
+// Default overall deadline of a run
+const defaultRunTimeout = 5 * time.Minute
+
+// Deadline of a whole run from RUN_TIMEOUT, e.g. "10m". It caps downloads,
+// parsing and posting together, so a hung run does not overlap the next one.
+func runTimeout() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv("RUN_TIMEOUT"))
+	if value == "" {
+		return defaultRunTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid RUN_TIMEOUT %q", value)
+	}
+	return timeout, nil
+}
+
+// RunSummary is printed as a single JSON line at the end of every run, so
+// cron logs can be checked without reading the whole output
+type RunSummary struct {
+	QuakesParsed  int      `json:"quakesParsed"`
+	WeeksComplete int      `json:"weeksComplete"`
+	WeekPosted    *string  `json:"weekPosted"`
+	AlertsPosted  int      `json:"alertsPosted"`
+	InterimPosted bool     `json:"interimPosted"`
+	Errors        []string `json:"errors"`
+	// Quality of the fetched feed rows, null when no feed could be fetched
+	DataQuality *DataQuality `json:"dataQuality"`
+}
+
+// Print the error and record it in the summary
+func (s *RunSummary) addError(action string, err error) {
+	fmt.Printf("Error %s: %v\n", action, err)
+	s.Errors = append(s.Errors, action+": "+err.Error())
+}
+
+// Print the summary as one JSON line prefixed with "run summary: "
+func writeRunSummary(w io.Writer, summary RunSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "run summary: %s\n", data)
+	return err
+}
+
+// Fetch the feeds, store the complete weeks and post the latest report
+func run(ctx context.Context, store *Store, cfg runConfig) (summary RunSummary) {
+	summary.Errors = []string{}
+	defer func() {
+		if err := writeRunSummary(os.Stdout, summary); err != nil {
+			fmt.Printf("Error writing run summary: %v\n", err)
+		}
+		if len(summary.Errors) == 0 {
+			if err := store.SaveHeartbeat(now()); err != nil {
+				fmt.Printf("Error writing heartbeat: %v\n", err)
+			}
+		}
+	}()
+
+	// Secondary outputs that failed in earlier runs
+	outputs, primary := configuredOutputs(store, cfg)
+	if !cfg.draft {
+		for _, err := range retryOutputs(ctx, store, outputs) {
+			summary.addError("retrying output", err)
+		}
+	}
+
+	// Download and parse the CSV feeds
+	urls := configuredFeedURLs()
+	earthquakes, quality, err := fetchFeeds(ctx, urls)
+	if err == nil && len(earthquakes) == 0 {
+		err = errNoEvents
+	}
+	if err != nil {
+		summary.addError("fetching earthquakes", err)
+		// Only an empty feed counts toward an outage, a failed download is
+		// usually transient and is retried by the next run
+		if errors.Is(err, errNoEvents) {
+			summary.DataQuality = &quality
+			if err := handleEmptyRun(ctx, store); err != nil {
+				summary.addError("recording feed outage", err)
+			}
+		}
+		return summary
+	}
+	if err := store.ClearOutage(); err != nil {
+		summary.addError("clearing feed outage", err)
+	}
+	summary.DataQuality = &quality
+	fetchedAt := now()
+	summary.QuakesParsed = len(earthquakes)
+
+	// Optionally drop events whose magnitude has not been reviewed yet
+	if envBool("REVIEWED_ONLY") {
+		earthquakes = filterReviewed(earthquakes)
+	}
+	earthquakes = filterByNetwork(earthquakes, cfg.thresholds)
+
+	// Group earthquakes by week, optionally together with the events of earlier runs
+	feedWeeks := groupByWeek(earthquakes)
+	weeklyStats := feedWeeks
+	if envBool("INCREMENTAL_WEEKS") {
+		if weeklyStats, err = mergeIncremental(store, earthquakes); err != nil {
+			summary.addError("merging collected events", err)
+			return summary
+		}
+	}
+
+	// Get full weeks only
+	fullWeeks := getFullWeeks(weeklyStats)
+	summary.WeeksComplete = len(fullWeeks)
+
+	// Keep the stats of complete weeks for historical comparisons
+	for weekKey, stats := range fullWeeks {
+		store.StoreWeekStats(weekKey, stats)
+	}
+	if path := os.Getenv("SQLITE_PATH"); path != "" && len(fullWeeks) > 0 {
+		if err := writeSQLiteWeeks(ctx, path, fullWeeks); err != nil {
+			summary.addError("writing SQLite stats", err)
+		}
+	}
+
+	if reportMode() == "rolling" {
+		runRolling(ctx, store, cfg, earthquakes, urls, fetchedAt, &summary)
+		return summary
+	}
+
+	// Post early when the current week already has a notable event
+	if envBool("NOTABLE_TRIGGER") && !cfg.draft {
+		posted, err := postInterimReport(ctx, store, weeklyStats)
+		if err != nil {
+			summary.addError("posting interim report", err)
+		} else if posted {
+			summary.InterimPosted = true
+		}
+	}
+
+	if envBool("YEAR_REVIEW") && !cfg.draft {
+		if year, posted, err := postYearReview(ctx, store); err != nil {
+			summary.addError("posting year in review", err)
+		} else if posted {
+			fmt.Printf("Posted the %d year in review\n", year)
+		}
+	}
+
+	// Reply to posted weeks whose counts were revised since. The weeks of the
+	// feed are compared, as the incremental stats no longer hold posted weeks.
+	if envBool("POST_CORRECTIONS") && !cfg.draft {
+		if _, err := postCorrections(ctx, store, feedWeeks, earthquakes); err != nil {
+			summary.addError("posting corrections", err)
+		}
+	}
+
+	if len(fullWeeks) == 0 {
+		fmt.Println("No complete weeks of earthquake data available")
+		return summary
+	}
+
+	// Generate reports
+	reportData := generateReports(store, fullWeeks)
+	if !reportData.ShouldPost {
+		fmt.Println("Report for this week already posted")
+		return summary
+	}
+
+	// Hold the report until the scheduled time, the posted mark makes sure a
+	// later run posts it once
+	if cfg.postAt != nil && !cfg.draft {
+		weekEnd := fullWeeks[reportData.WeekKey].EndDate.Add(time.Second)
+		if at := cfg.postAt.after(weekEnd); now().Before(at) {
+			fmt.Printf("Report of week %s is scheduled for %s\n", reportData.WeekKey, at.Format("2006-01-02 15:04 MST"))
+			return summary
+		}
+	}
+
+	reportData.FetchedAt, reportData.FeedWindow = fetchedAt, feedWindow(urls)
+
+	texts, err := renderReportPosts(reportData.Report, cfg.zones, cfg.format, cfg.tmpl)
+	if err != nil {
+		summary.addError("rendering report", err)
+		return summary
+	}
+	texts = withThreadProvenance(texts, cfg.format, reportData.FetchedAt, reportData.FeedWindow)
+	reportData.ReportText, reportData.Thread = texts[0], texts[1:]
+
+	// Print report to console as well
+	if err := printReport(os.Stdout, reportData, cfg.format); err != nil {
+		summary.addError("printing report", err)
+	}
+
+	if cfg.mapFile != "" {
+		if err := writeEpicenterMap(cfg.mapFile, fullWeeks[reportData.WeekKey].Events); err != nil {
+			summary.addError("writing map", err)
+		}
+	}
+
+	// Post to Bluesky
+	if cfg.draft {
+		if err := store.SaveDraft(reportData.WeekKey, reportData.Posts()); err != nil {
+			summary.addError("saving draft", err)
+		} else {
+			fmt.Printf("Draft saved, publish it with -publish-draft %s\n", reportData.WeekKey)
+		}
+	} else if err := checkEventFloor(reportData.Report); err != nil {
+		fmt.Printf("Warning: not posting report, the feed may be incomplete: %v\n", err)
+	} else {
+		posted, errs := publishOutputs(ctx, store, outputs, primary, reportData)
+		for _, err := range errs {
+			summary.addError("publishing report", err)
+		}
+		if posted {
+			summary.WeekPosted = &reportData.WeekKey
+		}
+	}
+	return summary
+}
+
+// The feeds from USGS_FEED_URL, the default feed when unset
+func configuredFeedURLs() []string {
+	feedURLs := os.Getenv("USGS_FEED_URL")
+	if feedURLs == "" {
+		feedURLs = defaultFeedURL
+	}
+	return strings.Split(feedURLs, ",")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunReportsSummary(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	header := "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n"
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, header+
+			"2026-06-02T10:00:00Z,1,2,10,2.5,ml,,,,,us,a,,Place A,earthquake,reviewed\n"+
+			"2026-06-04T10:00:00Z,1,2,10,4.5,mb,,,,,us,b,,Place B,earthquake,reviewed\n"+
+			"2026-06-09T10:00:00Z,1,2,10,6.2,mww,,,,,us,c,,Place C,earthquake,reviewed\n")
+	}))
+	t.Cleanup(feed.Close)
+	t.Setenv("USGS_FEED_URL", feed.URL)
+	t.Setenv("MIN_WEEKLY_EVENTS", "1")
+	t.Setenv("NOTABLE_TRIGGER", "true")
+	setNow(t, time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	summary := run(context.Background(), store, runConfig{format: "text"})
+
+	if summary.QuakesParsed != 3 || summary.WeeksComplete != 1 || summary.AlertsPosted != 0 || !summary.InterimPosted {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if summary.WeekPosted == nil || *summary.WeekPosted != "2026-W23" {
+		t.Fatalf("expected 2026-W23 to be posted, got %v", summary.WeekPosted)
+	}
+	if len(summary.Errors) != 0 {
+		t.Fatalf("expected no errors, got %v", summary.Errors)
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 2 {
+		t.Fatalf("expected the interim and the weekly post, got %d posts", len(created))
+	}
+
+	if last, ok, _ := store.LoadHeartbeat(); !ok || !last.Equal(now()) {
+		t.Fatalf("expected a heartbeat at %s, got %s", now(), last)
+	}
+
+	// The second run has nothing to post and the feed fails
+	feed.Close()
+	setNow(t, time.Date(2026, 6, 10, 13, 0, 0, 0, time.UTC))
+	summary = run(context.Background(), store, runConfig{format: "text"})
+	if summary.WeekPosted != nil || len(summary.Errors) != 1 || !strings.HasPrefix(summary.Errors[0], "fetching earthquakes: ") {
+		t.Fatalf("unexpected summary of a failed run %+v", summary)
+	}
+	if last, _, _ := store.LoadHeartbeat(); last.Hour() != 12 {
+		t.Fatalf("expected a failed run to keep the heartbeat, got %s", last)
+	}
+
+	var out bytes.Buffer
+	if err := writeRunSummary(&out, summary); err != nil {
+		t.Fatalf("writeRunSummary returned error: %v", err)
+	}
+	line, ok := strings.CutPrefix(out.String(), "run summary: ")
+	if !ok || strings.Count(line, "\n") != 1 {
+		t.Fatalf("expected a single prefixed line, got %q", out.String())
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		t.Fatalf("summary is not JSON: %v", err)
+	}
+	for _, name := range []string{"quakesParsed", "weeksComplete", "weekPosted", "alertsPosted", "interimPosted", "errors"} {
+		if _, ok := fields[name]; !ok {
+			t.Fatalf("expected field %s in %s", name, line)
+		}
+	}
+}
+
+func TestRunStopsAtDeadline(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	t.Cleanup(feed.Close)
+	t.Setenv("USGS_FEED_URL", feed.URL)
+	setNow(t, time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	summary := run(ctx, store, runConfig{format: "text"})
+
+	if len(summary.Errors) != 1 || !strings.Contains(summary.Errors[0], "context deadline exceeded") {
+		t.Fatalf("expected the slow download to hit the deadline, got %v", summary.Errors)
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 0 {
+		t.Fatalf("expected nothing to be posted, got %d posts", len(created))
+	}
+	if _, ok, _ := store.LoadHeartbeat(); ok {
+		t.Fatal("expected no heartbeat after a run that timed out")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	if timeout, err := runTimeout(); timeout != defaultRunTimeout || err != nil {
+		t.Fatalf("expected the default timeout, got %s (err %v)", timeout, err)
+	}
+	t.Setenv("RUN_TIMEOUT", "90s")
+	if timeout, err := runTimeout(); timeout != 90*time.Second || err != nil {
+		t.Fatalf("expected 90s, got %s (err %v)", timeout, err)
+	}
+	t.Setenv("RUN_TIMEOUT", "soon")
+	if _, err := runTimeout(); err == nil {
+		t.Fatal("expected an error for an invalid timeout")
+	}
+}
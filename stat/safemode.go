@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errSafeMode is returned instead of logging in while SAFE_MODE is set
+var errSafeMode = errors.New("SAFE_MODE is set, nothing is written to Bluesky")
+
+// SAFE_MODE blocks every write to Bluesky, whatever else is configured. It
+// is checked in login, so no request reaches the PDS, not even the session.
+func safeMode() bool {
+	return envBool("SAFE_MODE")
+}
+
+// Print a banner that is hard to miss in the logs of a run in safe mode
+func printSafeModeBanner() {
+	fmt.Println("************************************************************")
+	fmt.Println("* SAFE_MODE is set: nothing is posted to Bluesky this run  *")
+	fmt.Println("************************************************************")
+}
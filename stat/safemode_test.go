@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSafeModeSendsNothingToBluesky(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	header := "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n"
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, header+
+			"2026-06-02T10:00:00Z,1,2,10,2.5,ml,,,,,us,a,,Place A,earthquake,reviewed\n"+
+			"2026-06-09T10:00:00Z,1,2,10,6.2,mww,,,,,us,c,,Place C,earthquake,reviewed\n")
+	}))
+	t.Cleanup(feed.Close)
+	t.Setenv("USGS_FEED_URL", feed.URL)
+	t.Setenv("MIN_WEEKLY_EVENTS", "1")
+	t.Setenv("NOTABLE_TRIGGER", "true")
+	t.Setenv("POST_CORRECTIONS", "true")
+	t.Setenv("SAFE_MODE", "true")
+	setNow(t, time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC))
+
+	summary := run(context.Background(), store, runConfig{format: "text"})
+	if summary.WeekPosted != nil || summary.AlertsPosted != 0 {
+		t.Fatalf("expected nothing to be posted, got %+v", summary)
+	}
+	if len(summary.Errors) == 0 || !strings.Contains(strings.Join(summary.Errors, "\n"), errSafeMode.Error()) {
+		t.Fatalf("expected the blocked posts to be reported, got %v", summary.Errors)
+	}
+	if store.WasWeekPosted("2026-W23") {
+		t.Fatal("expected the week to stay unposted for a run without safe mode")
+	}
+
+	if err := smokeTest(context.Background(), store); !errors.Is(err, errSafeMode) {
+		t.Fatalf("expected the smoke test to be blocked, got %v", err)
+	}
+	if _, err := engage(context.Background(), store); !errors.Is(err, errSafeMode) {
+		t.Fatalf("expected engage to be blocked, got %v", err)
+	}
+
+	pds.mu.Lock()
+	defer pds.mu.Unlock()
+	if len(pds.requests) != 0 {
+		t.Fatalf("expected no request to the PDS, got %v", pds.requests)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// postSchedule is the local weekday and time at which a complete week is
+// posted, from POST_AT
+type postSchedule struct {
+	weekday      time.Weekday
+	hour, minute int
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+}
+
+// Parse POST_AT such as "09:00" (Monday) or "Tue 18:30". Unset means a
+// complete week is posted right away.
+func loadPostSchedule() (*postSchedule, error) {
+	value := strings.TrimSpace(os.Getenv("POST_AT"))
+	if value == "" {
+		return nil, nil
+	}
+
+	schedule := &postSchedule{weekday: time.Monday}
+	clock := value
+	if day, rest, ok := strings.Cut(value, " "); ok {
+		weekday, known := weekdayNames[strings.ToLower(day)[:min(len(day), 3)]]
+		if !known {
+			return nil, fmt.Errorf("invalid POST_AT %q: unknown weekday %q", value, day)
+		}
+		schedule.weekday, clock = weekday, strings.TrimSpace(rest)
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid POST_AT %q, expected e.g. \"Mon 09:00\"", value)
+	}
+	schedule.hour, schedule.minute = t.Hour(), t.Minute()
+	return schedule, nil
+}
+
+// First scheduled time at or after the end of a week, in weekLocation. The
+// time is computed from the calendar date, so it stays at the same local
+// time across daylight saving changes.
+func (s postSchedule) after(weekEnd time.Time) time.Time {
+	local := weekEnd.In(weekLocation)
+	days := (int(s.weekday) - int(local.Weekday()) + 7) % 7
+	at := time.Date(local.Year(), local.Month(), local.Day()+days, s.hour, s.minute, 0, 0, weekLocation)
+	if at.Before(weekEnd) {
+		at = at.AddDate(0, 0, 7)
+	}
+	return at
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadPostSchedule(t *testing.T) {
+	tests := []struct {
+		value string
+		want  *postSchedule
+	}{
+		{"", nil},
+		{"09:00", &postSchedule{weekday: time.Monday, hour: 9}},
+		{"Tue 18:30", &postSchedule{weekday: time.Tuesday, hour: 18, minute: 30}},
+		{"sunday 07:05", &postSchedule{weekday: time.Sunday, hour: 7, minute: 5}},
+	}
+	for _, tt := range tests {
+		t.Setenv("POST_AT", tt.value)
+		got, err := loadPostSchedule()
+		if err != nil {
+			t.Fatalf("loadPostSchedule(%q) returned error: %v", tt.value, err)
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("loadPostSchedule(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"9am", "Xyz 09:00", "Mon 25:00"} {
+		t.Setenv("POST_AT", value)
+		if _, err := loadPostSchedule(); err == nil {
+			t.Errorf("expected an error for POST_AT %q", value)
+		}
+	}
+}
+
+func TestPostScheduleAfter(t *testing.T) {
+	berlin := setWeekZone(t, "Europe/Berlin")
+	// Week ending Sunday 2026-10-25, the day daylight saving time ends
+	weekEnd := time.Date(2026, 10, 26, 0, 0, 0, 0, berlin)
+
+	tests := []struct {
+		schedule postSchedule
+		want     time.Time
+	}{
+		{postSchedule{weekday: time.Monday, hour: 9}, time.Date(2026, 10, 26, 9, 0, 0, 0, berlin)},
+		{postSchedule{weekday: time.Monday}, weekEnd},
+		{postSchedule{weekday: time.Wednesday, hour: 18, minute: 30}, time.Date(2026, 10, 28, 18, 30, 0, 0, berlin)},
+		{postSchedule{weekday: time.Sunday, hour: 20}, time.Date(2026, 11, 1, 20, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		if got := tt.schedule.after(weekEnd); !got.Equal(tt.want) {
+			t.Errorf("after(%s) with %+v = %s, want %s", weekEnd, tt.schedule, got, tt.want)
+		}
+	}
+}
+
+func TestRunHoldsReportUntilPostAt(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	header := "time,latitude,longitude,depth,mag,magType,nst,gap,dmin,rms,net,id,updated,place,type,status\n"
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, header+
+			"2026-06-02T10:00:00Z,1,2,10,2.5,ml,,,,,us,a,,Place A,earthquake,reviewed\n"+
+			"2026-06-04T10:00:00Z,1,2,10,4.5,mb,,,,,us,b,,Place B,earthquake,reviewed\n")
+	}))
+	t.Cleanup(feed.Close)
+	t.Setenv("USGS_FEED_URL", feed.URL)
+	t.Setenv("MIN_WEEKLY_EVENTS", "1")
+	cfg := runConfig{format: "text", postAt: &postSchedule{weekday: time.Monday, hour: 9}}
+
+	// One minute before the scheduled time the complete week is held back
+	setNow(t, time.Date(2026, 6, 8, 8, 59, 0, 0, time.UTC))
+	if summary := run(context.Background(), store, cfg); summary.WeekPosted != nil {
+		t.Fatalf("expected the report to be held, got %s posted", *summary.WeekPosted)
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 0 {
+		t.Fatalf("expected no posts before the scheduled time, got %d", len(created))
+	}
+
+	setNow(t, time.Date(2026, 6, 8, 9, 0, 0, 0, time.UTC))
+	summary := run(context.Background(), store, cfg)
+	if summary.WeekPosted == nil || *summary.WeekPosted != "2026-W23" {
+		t.Fatalf("expected 2026-W23 to be posted at the scheduled time, got %v", summary.WeekPosted)
+	}
+
+	// Later runs find the week posted
+	setNow(t, time.Date(2026, 6, 8, 10, 0, 0, 0, time.UTC))
+	if summary := run(context.Background(), store, cfg); summary.WeekPosted != nil {
+		t.Fatalf("expected the week to be posted once, got %s again", *summary.WeekPosted)
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 1 {
+		t.Fatalf("expected one weekly post, got %d", len(created))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Read-only HTTP API over the stored weekly stats:
+//
+//	GET /                  HTML status page for human viewers
+//	GET /latest.json       report of the most recent stored week
+//	GET /weeks/{key}.json  report of one week, e.g. /weeks/2026-W23.json
+func newServer(store *Store) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		serveDashboard(store, w)
+	})
+	mux.HandleFunc("GET /latest.json", func(w http.ResponseWriter, r *http.Request) {
+		weekKey, err := store.LatestWeekKey()
+		if err != nil {
+			http.Error(w, "failed to read stored weeks", http.StatusInternalServerError)
+			return
+		}
+		if weekKey == "" {
+			http.NotFound(w, r)
+			return
+		}
+		serveWeekReport(store, w, r, weekKey)
+	})
+	mux.HandleFunc("GET /weeks/{file}", func(w http.ResponseWriter, r *http.Request) {
+		weekKey, ok := strings.CutSuffix(r.PathValue("file"), ".json")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		serveWeekReport(store, w, r, weekKey)
+	})
+	return mux
+}
+
+func serveWeekReport(store *Store, w http.ResponseWriter, r *http.Request, weekKey string) {
+	stats, ok := store.LoadWeekStats(weekKey)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := toJSON(buildReport(store, weekKey, stats))
+	if err != nil {
+		http.Error(w, "failed to encode report", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// Serve the HTTP API until the server fails
+func serve(store *Store, addr string) error {
+	fmt.Printf("Serving reports on %s\n", addr)
+	return http.ListenAndServe(addr, newServer(store))
+}
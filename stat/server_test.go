@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerServesStoredReports(t *testing.T) {
+	store := openTestStore(t)
+	server := httptest.NewServer(newServer(store))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/latest.json")
+	if err != nil {
+		t.Fatalf("GET /latest.json failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 without stored weeks, got %d", resp.StatusCode)
+	}
+
+	for _, weekKey := range []string{"2026-W09", "2026-W22", "2026-W23"} {
+		store.StoreWeekStats(weekKey, WeekStats{Counts: [7]int{0, 1, 0, 0, 0, 0, 0}, MagnitudeSum: 2.5,
+			StartDate: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)})
+	}
+	store.MarkWeekPosted("2026-W23")
+
+	for path, want := range map[string]string{"/latest.json": "2026-W23", "/weeks/2026-W09.json": "2026-W09"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		var report Report
+		err = json.NewDecoder(resp.Body).Decode(&report)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", path, err)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" || report.WeekKey != want || report.Total != 1 {
+			t.Fatalf("unexpected report for %s: %+v", path, report)
+		}
+	}
+
+	for _, path := range []string{"/weeks/2026-W30.json", "/weeks/2026-W23"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+
+	resp, err = http.Post(server.URL+"/latest.json", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /latest.json failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected the API to be read-only, got %d", resp.StatusCode)
+	}
+}
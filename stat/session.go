@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// storedSession is the session of an account kept between runs. The PDS
+// rotates the refresh token on every refresh, so the old one stops working
+// once the new one is issued.
+type storedSession struct {
+	Host       string
+	Did        string
+	Handle     string
+	AccessJwt  string
+	RefreshJwt string
+}
+
+// sessionStore keeps the login sessions in a JSON file, keyed by identifier.
+// The refresh token grants access to the account for months, so it is not
+// kept in the Pebble database, whose files other users of the host may be
+// able to read. The file is only readable by the bot's user and lives in a
+// directory only it can open.
+type sessionStore struct {
+	path string
+}
+
+// The session store at SESSION_FILE, by default session.json in the
+// earthquakestats directory of the user's cache directory. ok is false when
+// neither is available, then every run logs in with the password.
+func openSessionStore() (sessionStore, bool) {
+	if path := os.Getenv("SESSION_FILE"); path != "" {
+		return sessionStore{path: path}, true
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		fmt.Printf("Not keeping the login session: %v\n", err)
+		return sessionStore{}, false
+	}
+	return sessionStore{path: filepath.Join(dir, "earthquakestats", "session.json")}, true
+}
+
+func (s sessionStore) loadAll() (map[string]storedSession, error) {
+	sessions := make(map[string]storedSession)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sessions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions in %s: %w", s.path, err)
+	}
+	return sessions, nil
+}
+
+// Load the stored session of an account, false when none is stored
+func (s sessionStore) Load(identifier string) (storedSession, bool, error) {
+	sessions, err := s.loadAll()
+	if err != nil {
+		return storedSession{}, false, err
+	}
+	session, ok := sessions[identifier]
+	return session, ok, nil
+}
+
+// Save both tokens of a session by replacing the file in one rename, so a
+// reader never sees a new access token with an old refresh token. The file
+// is created with mode 0600 in a directory with mode 0700.
+func (s sessionStore) Save(identifier string, session storedSession) error {
+	sessions, err := s.loadAll()
+	if err != nil {
+		return err
+	}
+	sessions[identifier] = session
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return fmt.Errorf("failed to encode session of %s: %w", identifier, err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Resume the stored session of an account by refreshing it, which rotates
+// both tokens. When the refresh fails, another run may have rotated the
+// tokens in the meantime, so the session is reloaded and refreshed again if
+// it changed. Returns false when there is no usable session and the caller
+// has to log in with the password.
+func resumeSession(ctx context.Context, sessions sessionStore, client *xrpc.Client, identifier string) bool {
+	session, ok, err := sessions.Load(identifier)
+	if err != nil {
+		fmt.Printf("Error loading session: %v\n", err)
+	}
+	if !ok || session.Host != client.Host {
+		return false
+	}
+
+	err = refreshSession(ctx, sessions, client, identifier, session)
+	if err == nil {
+		return true
+	}
+
+	reloaded, ok, loadErr := sessions.Load(identifier)
+	if loadErr == nil && ok && reloaded.RefreshJwt != session.RefreshJwt && reloaded.Host == client.Host {
+		fmt.Println("Session was rotated by another run, retrying with the stored tokens")
+		if err = refreshSession(ctx, sessions, client, identifier, reloaded); err == nil {
+			return true
+		}
+	}
+	fmt.Printf("Stored session could not be refreshed, logging in again: %v\n", err)
+	return false
+}
+
+// Refresh a session and store the rotated tokens before using them
+func refreshSession(ctx context.Context, sessions sessionStore, client *xrpc.Client, identifier string, session storedSession) error {
+	client.Auth = &xrpc.AuthInfo{RefreshJwt: session.RefreshJwt, Did: session.Did, Handle: session.Handle}
+	out, err := atproto.ServerRefreshSession(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	session.AccessJwt, session.RefreshJwt = out.AccessJwt, out.RefreshJwt
+	session.Did, session.Handle = out.Did, out.Handle
+	if err := sessions.Save(identifier, session); err != nil {
+		return fmt.Errorf("failed to save refreshed session: %w", err)
+	}
+	warnFullAccessSession(out.AccessJwt)
+	client.Auth = &xrpc.AuthInfo{AccessJwt: out.AccessJwt, RefreshJwt: out.RefreshJwt, Did: out.Did, Handle: out.Handle}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoginRotatesStoredSession(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	sessions, _ := openSessionStore()
+
+	// The first login uses the password and stores the session
+	client, err := login(context.Background(), store)
+	if err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+	session, ok, _ := sessions.Load("bot.example.com")
+	if !ok || session.RefreshJwt != "refresh-1" || client.Auth.AccessJwt != "access-1" {
+		t.Fatalf("expected the first session to be stored, got %+v", session)
+	}
+
+	// The next login refreshes it and stores the rotated tokens
+	client, err = login(context.Background(), store)
+	if err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+	session, _, _ = sessions.Load("bot.example.com")
+	if session.RefreshJwt != "refresh-2" || session.AccessJwt != "access-2" || client.Auth.AccessJwt != "access-2" || client.Auth.Did != "did:plc:bot" {
+		t.Fatalf("expected the rotated tokens, got %+v", session)
+	}
+	if created := pds.calls("com.atproto.server.createSession"); len(created) != 1 {
+		t.Fatalf("expected a single password login, got %d", len(created))
+	}
+}
+
+func TestLoginReloadsSessionRotatedByAnotherRun(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+	sessions, _ := openSessionStore()
+	if _, err := login(context.Background(), store); err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+
+	// Another run refreshes the session after this one loaded it, so the
+	// refresh token in hand is revoked
+	pds.beforeRefresh = func() {
+		pds.sessions++
+		rotated := storedSession{Host: pds.URL, Did: "did:plc:bot", Handle: "bot.example.com", AccessJwt: "access-2", RefreshJwt: "refresh-2"}
+		if err := sessions.Save("bot.example.com", rotated); err != nil {
+			t.Error(err)
+		}
+	}
+
+	client, err := login(context.Background(), store)
+	if err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+	if client.Auth.AccessJwt != "access-3" {
+		t.Fatalf("expected the reloaded session to be refreshed, got %q", client.Auth.AccessJwt)
+	}
+	if session, _, _ := sessions.Load("bot.example.com"); session.RefreshJwt != "refresh-3" {
+		t.Fatalf("expected the rotated tokens to be stored, got %+v", session)
+	}
+	if refreshes := pds.calls("com.atproto.server.refreshSession"); len(refreshes) != 2 {
+		t.Fatalf("expected a failed and a successful refresh, got %d", len(refreshes))
+	}
+	if created := pds.calls("com.atproto.server.createSession"); len(created) != 1 {
+		t.Fatalf("expected no second password login, got %d", len(created))
+	}
+}
+
+func TestSessionFileIsPrivate(t *testing.T) {
+	store := openTestStore(t)
+	newMockPDS(t)
+	dir := filepath.Join(t.TempDir(), "private")
+	t.Setenv("SESSION_FILE", filepath.Join(dir, "session.json"))
+
+	if _, err := login(context.Background(), store); err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+
+	for path, want := range map[string]os.FileMode{dir: 0o700, filepath.Join(dir, "session.json"): 0o600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Fatalf("%s has mode %v, want %v", path, info.Mode().Perm(), want)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected only the session file, got %v", entries)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+)
+
+// Log in, create a real post and delete it again, to check the credentials
+// and the connection to the PDS end to end. The post is public until it is
+// deleted: followers may see it in their feeds or notifications, and relays
+// and feed generators may keep a copy.
+func smokeTest(ctx context.Context, store *Store) error {
+	client, err := login(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	ref, err := createPost(ctx, client, buildPost("Connection test, this post is deleted right away."))
+	if err != nil {
+		return fmt.Errorf("failed to create test post: %w", err)
+	}
+	fmt.Printf("Created test post %s\n", ref.Uri)
+
+	// at://<did>/app.bsky.feed.post/<rkey>
+	rkey := ref.Uri[strings.LastIndex(ref.Uri, "/")+1:]
+	_, err = atproto.RepoDeleteRecord(ctx, client, &atproto.RepoDeleteRecord_Input{
+		Repo:       client.Auth.Did,
+		Collection: "app.bsky.feed.post",
+		Rkey:       rkey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete test post %s, delete it by hand: %w", ref.Uri, err)
+	}
+	fmt.Println("Deleted test post, posting works")
+	return nil
+}
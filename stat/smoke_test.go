@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSmokeTestCreatesAndDeletesPost(t *testing.T) {
+	pds := newMockPDS(t)
+
+	if err := smokeTest(context.Background(), nil); err != nil {
+		t.Fatalf("smokeTest returned error: %v", err)
+	}
+
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 1 {
+		t.Fatalf("expected one test post, got %d", len(created))
+	}
+	deleted := pds.calls("com.atproto.repo.deleteRecord")
+	if len(deleted) != 1 {
+		t.Fatalf("expected the test post to be deleted, got %d deletions", len(deleted))
+	}
+	if deleted[0]["rkey"] != "a" || deleted[0]["collection"] != "app.bsky.feed.post" || deleted[0]["repo"] != "did:plc:bot" {
+		t.Fatalf("unexpected deletion %v", deleted[0])
+	}
+}
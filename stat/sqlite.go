@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command line shell used to write the SQLite database, so the bot needs no
+// SQLite driver or cgo
+const sqliteCommand = "sqlite3"
+
+const weeklyStatsSchema = `CREATE TABLE IF NOT EXISTS weekly_stats (
+	week TEXT PRIMARY KEY,
+	year INTEGER NOT NULL,
+	week_num INTEGER NOT NULL,
+	start_date TEXT NOT NULL,
+	end_date TEXT NOT NULL,
+	micro INTEGER NOT NULL,
+	minor INTEGER NOT NULL,
+	light INTEGER NOT NULL,
+	moderate INTEGER NOT NULL,
+	strong INTEGER NOT NULL,
+	major INTEGER NOT NULL,
+	great INTEGER NOT NULL,
+	total INTEGER NOT NULL,
+	magnitude_sum REAL NOT NULL,
+	average_magnitude REAL,
+	largest_id TEXT,
+	largest_magnitude REAL,
+	largest_place TEXT,
+	largest_time TEXT,
+	depth_shallow INTEGER NOT NULL,
+	depth_intermediate INTEGER NOT NULL,
+	depth_deep INTEGER NOT NULL,
+	depth_unknown INTEGER NOT NULL,
+	hour_counts TEXT NOT NULL,
+	latest_event TEXT,
+	historical INTEGER NOT NULL,
+	updated_at TEXT NOT NULL
+);
+`
+
+// Columns of weekly_stats in table order
+var weeklyStatsColumns = []string{
+	"week", "year", "week_num", "start_date", "end_date",
+	"micro", "minor", "light", "moderate", "strong", "major", "great",
+	"total", "magnitude_sum", "average_magnitude",
+	"largest_id", "largest_magnitude", "largest_place", "largest_time",
+	"depth_shallow", "depth_intermediate", "depth_deep", "depth_unknown",
+	"hour_counts", "latest_event", "historical", "updated_at",
+}
+
+// Check that the sqlite3 shell is installed when SQLITE_PATH is set, so a
+// missing shell stops the bot at startup instead of failing every run
+func checkSQLite() error {
+	if os.Getenv("SQLITE_PATH") == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(sqliteCommand); err != nil {
+		return fmt.Errorf("SQLITE_PATH is set but %s is not installed: %w", sqliteCommand, err)
+	}
+	return nil
+}
+
+// Write the stats of the weeks to the weekly_stats table of the SQLite
+// database at path, creating the database and the table as needed. A week
+// that is already in the table is replaced, so revised counts are picked up.
+// All weeks are written in one transaction. The values are handed to the
+// shell as a CSV file and imported with .import, so none of them is ever
+// parsed as SQL.
+func writeSQLiteWeeks(ctx context.Context, path string, weeks map[string]WeekStats) error {
+	weekKeys := make([]string, 0, len(weeks))
+	for weekKey := range weeks {
+		weekKeys = append(weekKeys, weekKey)
+	}
+	sortWeekKeys(weekKeys)
+
+	file, err := os.CreateTemp("", "weekly_stats-*.csv")
+	if err != nil {
+		return fmt.Errorf("failed to create import file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	w := csv.NewWriter(file)
+	for _, weekKey := range weekKeys {
+		w.Write(weeklyStatsRow(weekKey, weeks[weekKey]))
+	}
+	w.Flush()
+	if err := errors.Join(w.Error(), file.Close()); err != nil {
+		return fmt.Errorf("failed to write import file: %w", err)
+	}
+	// The shell takes the file name as a single-quoted argument
+	if strings.ContainsAny(file.Name(), "'\n") {
+		return fmt.Errorf("unsupported import file name %q", file.Name())
+	}
+
+	// CSV has no NULL, empty values are imported as empty strings
+	values := make([]string, len(weeklyStatsColumns))
+	for i, column := range weeklyStatsColumns {
+		values[i] = "NULLIF(" + column + ", '')"
+	}
+	script := "BEGIN;\n" + weeklyStatsSchema +
+		"CREATE TEMP TABLE weekly_stats_import AS SELECT * FROM weekly_stats WHERE 0;\n" +
+		".import --csv '" + file.Name() + "' weekly_stats_import\n" +
+		"INSERT OR REPLACE INTO weekly_stats SELECT " + strings.Join(values, ", ") + " FROM weekly_stats_import;\n" +
+		"COMMIT;\n"
+	_, err = runSQLite(ctx, path, script)
+	return err
+}
+
+// Values of one week in the order of weeklyStatsColumns, empty for NULL
+func weeklyStatsRow(weekKey string, stats WeekStats) []string {
+	row := []string{
+		weekKey, strconv.Itoa(stats.Year), strconv.Itoa(stats.WeekNum),
+		sqlTime(stats.StartDate), sqlTime(stats.EndDate),
+	}
+	for _, count := range stats.Counts {
+		row = append(row, strconv.Itoa(count))
+	}
+	row = append(row, strconv.Itoa(stats.Total()), sqlReal(stats.MagnitudeSum))
+
+	if total := stats.Total(); total > 0 {
+		largest := stats.Largest
+		row = append(row, sqlReal(stats.MagnitudeSum/float64(total)), largest.ID,
+			sqlReal(largest.Magnitude), largest.Place, sqlTime(largest.Time))
+	} else {
+		row = append(row, "", "", "", "", "")
+	}
+
+	for _, count := range stats.DepthCounts {
+		row = append(row, strconv.Itoa(count))
+	}
+	hours, _ := json.Marshal(stats.HourCounts)
+	historical := "0"
+	if stats.Historical {
+		historical = "1"
+	}
+	return append(row, string(hours), sqlTime(stats.Latest), historical, sqlTime(now()))
+}
+
+// Run SQL with the sqlite3 shell on the database at path and return its output
+func runSQLite(ctx context.Context, path string, sql string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, sqliteCommand, slices.Concat([]string{"-bail"}, args, []string{path})...)
+	cmd.Stdin = strings.NewReader(sql)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", sqliteCommand, err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// RFC 3339 time in UTC, empty for the zero time
+func sqlTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Real number, empty for NaN and infinities which SQLite cannot store
+func sqlReal(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWriteSQLiteWeeks(t *testing.T) {
+	if _, err := exec.LookPath(sqliteCommand); err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	setNow(t, time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC))
+	path := filepath.Join(t.TempDir(), "stats.db")
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	week := WeekStats{StartDate: start, EndDate: start.AddDate(0, 0, 7).Add(-time.Second), Year: 2026, WeekNum: 23,
+		Counts: [7]int{10, 20, 3, 1, 0, 0, 0}, MagnitudeSum: 68, DepthCounts: [4]int{30, 3, 1, 0},
+		Largest: Earthquake{ID: "us1", Magnitude: 5.4, Place: "Off the coast of O'Higgins, Chile'); DROP TABLE weekly_stats; --", Time: start.Add(30 * time.Hour)}}
+
+	ctx := context.Background()
+	if err := writeSQLiteWeeks(ctx, path, map[string]WeekStats{"2026-W23": week, "2026-W22": {StartDate: start.AddDate(0, 0, -7), EndDate: start.Add(-time.Second), Year: 2026, WeekNum: 22}}); err != nil {
+		t.Fatalf("writeSQLiteWeeks returned error: %v", err)
+	}
+
+	// A revised week replaces its row
+	week.Counts[1] = 21
+	week.MagnitudeSum += 2.5
+	if err := writeSQLiteWeeks(ctx, path, map[string]WeekStats{"2026-W23": week}); err != nil {
+		t.Fatalf("writeSQLiteWeeks returned error: %v", err)
+	}
+
+	out, err := runSQLite(ctx, path, "SELECT week, minor, total, round(average_magnitude, 3), largest_place, depth_shallow, start_date FROM weekly_stats ORDER BY week;", "-csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2026-W22,0,0,,,0,2026-05-25T00:00:00Z\n" +
+		"2026-W23,21,35,2.014,\"Off the coast of O'Higgins, Chile'); DROP TABLE weekly_stats; --\",30,2026-06-01T00:00:00Z\n"
+	if out != want {
+		t.Fatalf("unexpected rows:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestWeeklyStatsRowMatchesTheColumns(t *testing.T) {
+	setNow(t, time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC))
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	week := WeekStats{StartDate: start, Year: 2026, WeekNum: 23, Counts: [7]int{0, 1, 0, 0, 0, 0, 0}, MagnitudeSum: math.NaN(),
+		Largest: Earthquake{ID: "us1", Magnitude: 2.5, Place: "Place, with comma"}}
+
+	row := weeklyStatsRow("2026-W23", week)
+	if len(row) != len(weeklyStatsColumns) {
+		t.Fatalf("expected %d values, got %d: %q", len(weeklyStatsColumns), len(row), row)
+	}
+	value := func(column string) string { return row[slices.Index(weeklyStatsColumns, column)] }
+	if value("week") != "2026-W23" || value("start_date") != "2026-06-01T00:00:00Z" || value("largest_place") != "Place, with comma" {
+		t.Fatalf("unexpected row %q", row)
+	}
+	if value("end_date") != "" || value("magnitude_sum") != "" || value("largest_time") != "" {
+		t.Fatalf("expected zero times and NaN to be empty, got %q", row)
+	}
+	if value("updated_at") != "2026-06-10T06:00:00Z" {
+		t.Fatalf("unexpected update time %q", value("updated_at"))
+	}
+}
+
+func TestCheckSQLiteRequiresTheShell(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if err := checkSQLite(); err != nil {
+		t.Fatalf("expected no check without SQLITE_PATH, got %v", err)
+	}
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "stats.db"))
+	if err := checkSQLite(); err == nil {
+		t.Fatal("expected an error when sqlite3 is not installed")
+	}
+}
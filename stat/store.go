@@ -0,0 +1,454 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/cockroachdb/pebble"
+)
+
+// Key prefix for the stored WeekStats of each complete week
+const statsKeyPrefix = "stats:"
+
+// Key prefix for reports saved for review before posting
+const draftKeyPrefix = "draft:"
+
+// Key prefix for the posted marks of interim reports
+const interimKeyPrefix = "interim:"
+
+// Key prefix for the posted marks of rolling reports, by end date
+const rollingKeyPrefix = "rolling:"
+
+// Key prefix for the events collected so far of weeks that are not posted yet
+const partialKeyPrefix = "partial:"
+
+// Key prefix for the discovered PDS endpoint of each account
+const pdsKeyPrefix = "pds:"
+
+// Key prefix for the posts made so far of threads that are not complete
+const threadKeyPrefix = "thread:"
+
+// Key prefix for reports queued for another attempt at a secondary output,
+// "retry:<week key>:<output name>"
+const retryKeyPrefix = "retry:"
+
+// Key prefix for the notifications handled by the engagement pass
+const engagedKeyPrefix = "engaged:"
+
+// Key prefix for the first post and the category counts of posted weeks
+const postedKeyPrefix = "posted:"
+
+// Key prefix for the posted marks of year-in-review threads, by year
+const yearKeyPrefix = "year:"
+
+// Key of the consecutive runs without feed data
+const outageKey = "outage"
+
+// Key of the time of the last run that ended without errors
+const heartbeatKey = "heartbeat"
+
+// Store keeps the posted marks, weekly stats and drafts in Pebble. Pebble
+// handles concurrent readers and writers, so a Store can be shared between
+// goroutines.
+type Store struct {
+	db *pebble.DB
+}
+
+// openPebble opens a Pebble database. Tests replace it to simulate open errors.
+var openPebble = pebble.Open
+
+// Open the store at path. If Pebble reports corruption and repair is set, the
+// corrupt directory is moved aside, a new database is created and the entries
+// that can still be read from the corrupt one are copied into it.
+func openStore(path string, repair bool) (*Store, error) {
+	db, err := openPebble(path, &pebble.Options{})
+	if err == nil {
+		return &Store{db: db}, nil
+	}
+	if !repair || !pebble.IsCorruptionError(err) {
+		return nil, err
+	}
+
+	corruptPath := fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+	fmt.Printf("Warning: database %s is corrupt (%v), moving it to %s and starting with a new database\n", path, err, corruptPath)
+	if err := os.Rename(path, corruptPath); err != nil {
+		return nil, fmt.Errorf("failed to move corrupt database aside: %w", err)
+	}
+
+	db, err = openPebble(path, &pebble.Options{})
+	if err != nil {
+		return nil, err
+	}
+	copied, err := recoverEntries(corruptPath, db)
+	if err != nil {
+		fmt.Printf("Warning: recovered %d entries of the corrupt database, the others are lost and their weeks may be posted again: %v\n", copied, err)
+	} else {
+		fmt.Printf("Recovered %d entries of the corrupt database\n", copied)
+	}
+	return &Store{db: db}, nil
+}
+
+// Copy the entries of the database at path into db, returning the number of
+// entries copied. The database is opened read-only, so a corrupt one is not
+// changed.
+func recoverEntries(path string, db *pebble.DB) (int, error) {
+	old, err := openPebble(path, &pebble.Options{ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer old.Close()
+
+	iter, err := old.NewIter(nil)
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := db.Set(iter.Key(), iter.Value(), pebble.NoSync); err != nil {
+			iter.Close()
+			return copied, err
+		}
+		copied++
+	}
+	if err := errors.Join(iter.Error(), iter.Close()); err != nil {
+		return copied, err
+	}
+	return copied, db.Flush()
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Check if a week has already been posted
+func (s *Store) WasWeekPosted(weekKey string) bool {
+	_, closer, err := s.db.Get([]byte(weekKey))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		fmt.Printf("Error checking if week was posted: %v\n", err)
+		return false
+	}
+	defer closer.Close()
+	return true
+}
+
+// Mark a week as posted
+func (s *Store) MarkWeekPosted(weekKey string) {
+	err := s.db.Set([]byte(weekKey), []byte("posted"), pebble.Sync)
+	if err != nil {
+		fmt.Printf("Error marking week as posted: %v\n", err)
+	}
+}
+
+// Remove the posted mark of a week so that it is posted again. The posted
+// report, the progress of an incomplete thread and the queued retries of the
+// week are removed with it, so the new post starts a fresh thread and is not
+// treated as a correction of the old one.
+func (s *Store) UnmarkWeekPosted(weekKey string) error {
+	if !s.WasWeekPosted(weekKey) {
+		return fmt.Errorf("week %s is not marked as posted", weekKey)
+	}
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for _, key := range []string{weekKey, postedKeyPrefix + weekKey, threadKeyPrefix + weekKey} {
+		if err := batch.Delete([]byte(key), nil); err != nil {
+			return err
+		}
+	}
+	retries := retryKeyPrefix + weekKey + ":"
+	if err := batch.DeleteRange([]byte(retries), prefixUpperBound(retries), nil); err != nil {
+		return err
+	}
+	return batch.Commit(pebble.Sync)
+}
+
+// Check if the interim report of a week has already been posted
+func (s *Store) WasInterimPosted(weekKey string) bool {
+	return s.WasWeekPosted(interimKeyPrefix + weekKey)
+}
+
+// Mark the interim report of a week as posted
+func (s *Store) MarkInterimPosted(weekKey string) {
+	s.MarkWeekPosted(interimKeyPrefix + weekKey)
+}
+
+// Check if the rolling report ending on a date such as "2026-06-10" has been posted
+func (s *Store) WasRollingPosted(date string) bool {
+	return s.WasWeekPosted(rollingKeyPrefix + date)
+}
+
+func (s *Store) MarkRollingPosted(date string) {
+	s.MarkWeekPosted(rollingKeyPrefix + date)
+}
+
+// Store the aggregated stats of a week for later comparisons
+func (s *Store) StoreWeekStats(weekKey string, stats WeekStats) {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		fmt.Printf("Error encoding stats for week %s: %v\n", weekKey, err)
+		return
+	}
+	if err := s.db.Set([]byte(statsKeyPrefix+weekKey), data, pebble.Sync); err != nil {
+		fmt.Printf("Error storing stats for week %s: %v\n", weekKey, err)
+	}
+}
+
+// Load the stored stats of a week, reporting whether they exist
+func (s *Store) LoadWeekStats(weekKey string) (WeekStats, bool) {
+	var stats WeekStats
+	value, closer, err := s.db.Get([]byte(statsKeyPrefix + weekKey))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return stats, false
+	}
+	if err != nil {
+		fmt.Printf("Error loading stats for week %s: %v\n", weekKey, err)
+		return stats, false
+	}
+	defer closer.Close()
+
+	if err := json.Unmarshal(value, &stats); err != nil {
+		fmt.Printf("Error decoding stats for week %s: %v\n", weekKey, err)
+		return stats, false
+	}
+	return stats, true
+}
+
+// Key of the most recent week with stored stats, empty when none is stored.
+// Week keys are zero padded, so the last key in order is the latest week.
+func (s *Store) LatestWeekKey() (string, error) {
+	iter, err := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(statsKeyPrefix),
+		UpperBound: prefixUpperBound(statsKeyPrefix),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer iter.Close()
+
+	if !iter.Last() {
+		return "", iter.Error()
+	}
+	return strings.TrimPrefix(string(iter.Key()), statsKeyPrefix), nil
+}
+
+// Load the stats of every stored week, keyed by week
+func (s *Store) AllWeekStats() (map[string]WeekStats, error) {
+	iter, err := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(statsKeyPrefix),
+		UpperBound: prefixUpperBound(statsKeyPrefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	weeks := make(map[string]WeekStats)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var stats WeekStats
+		if err := json.Unmarshal(iter.Value(), &stats); err != nil {
+			return nil, fmt.Errorf("failed to decode stats of %s: %w", iter.Key(), err)
+		}
+		weeks[strings.TrimPrefix(string(iter.Key()), statsKeyPrefix)] = stats
+	}
+	return weeks, iter.Error()
+}
+
+// Save the post texts of a week as a draft, replacing an older draft
+func (s *Store) SaveDraft(weekKey string, texts []string) error {
+	data, err := json.Marshal(texts)
+	if err != nil {
+		return fmt.Errorf("failed to encode draft: %w", err)
+	}
+	return s.db.Set([]byte(draftKeyPrefix+weekKey), data, pebble.Sync)
+}
+
+// Load the draft of a week
+func (s *Store) LoadDraft(weekKey string) ([]string, error) {
+	value, closer, err := s.db.Get([]byte(draftKeyPrefix + weekKey))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, fmt.Errorf("no draft for week %s", weekKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var texts []string
+	if err := json.Unmarshal(value, &texts); err != nil {
+		return nil, fmt.Errorf("failed to decode draft: %w", err)
+	}
+	return texts, nil
+}
+
+func (s *Store) DeleteDraft(weekKey string) error {
+	return s.db.Delete([]byte(draftKeyPrefix+weekKey), pebble.Sync)
+}
+
+// Load the events collected so far of a week, nil when none are stored
+func (s *Store) LoadPartialWeek(weekKey string) ([]Earthquake, error) {
+	value, closer, err := s.db.Get([]byte(partialKeyPrefix + weekKey))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var events []Earthquake
+	if err := json.Unmarshal(value, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode events of week %s: %w", weekKey, err)
+	}
+	return events, nil
+}
+
+// Replace the collected events of a week
+func (s *Store) SavePartialWeek(weekKey string, events []Earthquake) error {
+	data, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to encode events of week %s: %w", weekKey, err)
+	}
+	return s.db.Set([]byte(partialKeyPrefix+weekKey), data, pebble.Sync)
+}
+
+func (s *Store) DeletePartialWeek(weekKey string) error {
+	return s.db.Delete([]byte(partialKeyPrefix+weekKey), pebble.Sync)
+}
+
+// Keys of the weeks with collected events
+func (s *Store) PartialWeekKeys() ([]string, error) {
+	iter, err := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(partialKeyPrefix),
+		UpperBound: prefixUpperBound(partialKeyPrefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var weekKeys []string
+	for iter.First(); iter.Valid(); iter.Next() {
+		weekKeys = append(weekKeys, strings.TrimPrefix(string(iter.Key()), partialKeyPrefix))
+	}
+	return weekKeys, iter.Error()
+}
+
+// pdsEndpoint is a PDS service endpoint and when it was discovered
+type pdsEndpoint struct {
+	URL        string
+	ResolvedAt time.Time
+}
+
+// Load the cached PDS endpoint of an account, false when none is cached
+func (s *Store) LoadPDSEndpoint(identifier string) (pdsEndpoint, bool, error) {
+	var endpoint pdsEndpoint
+	value, closer, err := s.db.Get([]byte(pdsKeyPrefix + identifier))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return endpoint, false, nil
+	}
+	if err != nil {
+		return endpoint, false, err
+	}
+	defer closer.Close()
+
+	if err := json.Unmarshal(value, &endpoint); err != nil {
+		return endpoint, false, fmt.Errorf("failed to decode PDS endpoint of %s: %w", identifier, err)
+	}
+	return endpoint, true, nil
+}
+
+func (s *Store) SavePDSEndpoint(identifier string, endpoint pdsEndpoint) error {
+	data, err := json.Marshal(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode PDS endpoint of %s: %w", identifier, err)
+	}
+	return s.db.Set([]byte(pdsKeyPrefix+identifier), data, pebble.Sync)
+}
+
+// Load the posts made so far of an incomplete thread, nil when none are stored
+func (s *Store) LoadThreadProgress(threadKey string) ([]*atproto.RepoStrongRef, error) {
+	value, closer, err := s.db.Get([]byte(threadKeyPrefix + threadKey))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var posted []*atproto.RepoStrongRef
+	if err := json.Unmarshal(value, &posted); err != nil {
+		return nil, fmt.Errorf("failed to decode thread %s: %w", threadKey, err)
+	}
+	return posted, nil
+}
+
+func (s *Store) SaveThreadProgress(threadKey string, posted []*atproto.RepoStrongRef) error {
+	data, err := json.Marshal(posted)
+	if err != nil {
+		return fmt.Errorf("failed to encode thread %s: %w", threadKey, err)
+	}
+	return s.db.Set([]byte(threadKeyPrefix+threadKey), data, pebble.Sync)
+}
+
+func (s *Store) DeleteThreadProgress(threadKey string) error {
+	return s.db.Delete([]byte(threadKeyPrefix+threadKey), pebble.Sync)
+}
+
+func retryKey(outputName, weekKey string) []byte {
+	return []byte(retryKeyPrefix + weekKey + ":" + outputName)
+}
+
+// Queue a report for another attempt at an output
+func (s *Store) QueueOutputRetry(outputName string, reportData ReportData) error {
+	data, err := json.Marshal(reportData)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return s.db.Set(retryKey(outputName, reportData.WeekKey), data, pebble.Sync)
+}
+
+func (s *Store) DeleteOutputRetry(outputName, weekKey string) error {
+	return s.db.Delete(retryKey(outputName, weekKey), pebble.Sync)
+}
+
+// Queued reports by output name, oldest week first
+func (s *Store) QueuedOutputRetries() (map[string][]ReportData, error) {
+	iter, err := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(retryKeyPrefix),
+		UpperBound: prefixUpperBound(retryKeyPrefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	queued := make(map[string][]ReportData)
+	for iter.First(); iter.Valid(); iter.Next() {
+		key := strings.TrimPrefix(string(iter.Key()), retryKeyPrefix)
+		_, outputName, ok := strings.Cut(key, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid retry key %s", key)
+		}
+		var reportData ReportData
+		if err := json.Unmarshal(iter.Value(), &reportData); err != nil {
+			return nil, fmt.Errorf("failed to decode queued report %s: %w", key, err)
+		}
+		queued[outputName] = append(queued[outputName], reportData)
+	}
+	return queued, iter.Error()
+}
+
+// Smallest key after every key with the given prefix
+func prefixUpperBound(prefix string) []byte {
+	upper := []byte(prefix)
+	upper[len(upper)-1]++
+	return upper
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Run with -race to check that a shared Store is safe for concurrent use
+func TestStoreConcurrentReads(t *testing.T) {
+	store := openTestStore(t)
+	for week := 1; week <= 8; week++ {
+		weekKey := fmt.Sprintf("2026-W%02d", week)
+		store.StoreWeekStats(weekKey, WeekStats{Year: 2026, WeekNum: week, Counts: [7]int{week}})
+		if week%2 == 0 {
+			store.MarkWeekPosted(weekKey)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for range 16 {
+		wg.Go(func() {
+			for week := 1; week <= 8; week++ {
+				weekKey := fmt.Sprintf("2026-W%02d", week)
+				stats, ok := store.LoadWeekStats(weekKey)
+				if !ok || stats.Total() != week {
+					errs <- fmt.Errorf("unexpected stats for %s: %+v", weekKey, stats)
+					return
+				}
+				if posted := store.WasWeekPosted(weekKey); posted != (week%2 == 0) {
+					errs <- fmt.Errorf("unexpected posted status %v for %s", posted, weekKey)
+					return
+				}
+				if latest, err := store.LatestWeekKey(); err != nil || latest != "2026-W08" {
+					errs <- fmt.Errorf("unexpected latest week %q (err %v)", latest, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestOpenStoreRepairsCorruptDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quake-stats-db")
+	old, err := openStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.MarkWeekPosted("2026-W23")
+	if err := old.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	openPebble = func(dirname string, opts *pebble.Options) (*pebble.DB, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("open %s: %w", dirname, pebble.ErrCorruption)
+		}
+		return pebble.Open(dirname, opts)
+	}
+	t.Cleanup(func() { openPebble = pebble.Open })
+
+	if _, err := openStore(path, false); !pebble.IsCorruptionError(err) {
+		t.Fatalf("expected corruption error without repair, got %v", err)
+	}
+
+	calls = 0
+	store, err := openStore(path, true)
+	if err != nil {
+		t.Fatalf("openStore with repair returned error: %v", err)
+	}
+	defer store.Close()
+
+	if moved, err := filepath.Glob(path + ".corrupt-*"); err != nil || len(moved) != 1 {
+		t.Fatalf("expected corrupt database to be moved aside, got %v (err %v)", moved, err)
+	}
+	if !store.WasWeekPosted("2026-W23") {
+		t.Fatal("expected the posted mark to be recovered")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// Reply rules from REPLY_POLICY: "open" (default) creates no threadgate,
+// "nobody" closes the replies, and a comma-separated list of "following",
+// "followers" and "mentioned" allows replies from those accounts. Returns
+// nil rules for open replies.
+func replyPolicy() ([]*bsky.FeedThreadgate_Allow_Elem, error) {
+	policy := strings.TrimSpace(os.Getenv("REPLY_POLICY"))
+	switch policy {
+	case "", "open":
+		return nil, nil
+	case "nobody":
+		// An empty allow list means nobody, but optional lists are left out of
+		// the record when empty, which would open the replies. The posts
+		// mention nobody, so allowing mentioned accounts allows no one.
+		return []*bsky.FeedThreadgate_Allow_Elem{
+			{FeedThreadgate_MentionRule: &bsky.FeedThreadgate_MentionRule{}},
+		}, nil
+	}
+
+	var rules []*bsky.FeedThreadgate_Allow_Elem
+	for name := range strings.SplitSeq(policy, ",") {
+		switch strings.TrimSpace(name) {
+		case "following":
+			rules = append(rules, &bsky.FeedThreadgate_Allow_Elem{FeedThreadgate_FollowingRule: &bsky.FeedThreadgate_FollowingRule{}})
+		case "followers":
+			rules = append(rules, &bsky.FeedThreadgate_Allow_Elem{FeedThreadgate_FollowerRule: &bsky.FeedThreadgate_FollowerRule{}})
+		case "mentioned":
+			rules = append(rules, &bsky.FeedThreadgate_Allow_Elem{FeedThreadgate_MentionRule: &bsky.FeedThreadgate_MentionRule{}})
+		default:
+			return nil, fmt.Errorf("invalid REPLY_POLICY %q", policy)
+		}
+	}
+	return rules, nil
+}
+
+// Limit the replies to the thread of a post. The threadgate record must have
+// the same record key as the post.
+func createThreadgate(ctx context.Context, client *xrpc.Client, post *atproto.RepoStrongRef, rules []*bsky.FeedThreadgate_Allow_Elem) error {
+	rkey := post.Uri[strings.LastIndex(post.Uri, "/")+1:]
+	_, err := atproto.RepoCreateRecord(ctx, client, &atproto.RepoCreateRecord_Input{
+		Repo:       client.Auth.Did,
+		Collection: "app.bsky.feed.threadgate",
+		Rkey:       &rkey,
+		Record: &util.LexiconTypeDecoder{Val: &bsky.FeedThreadgate{
+			LexiconTypeID: "app.bsky.feed.threadgate",
+			Allow:         rules,
+			CreatedAt:     now().Format(time.RFC3339),
+			Post:          post.Uri,
+		}},
+	})
+	return err
+}
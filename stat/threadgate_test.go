@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestThreadgateLimitsReplies(t *testing.T) {
+	pds := newMockPDS(t)
+	t.Setenv("REPLY_POLICY", "following, followers")
+
+	if err := postToBluesky(context.Background(), nil, "Weekly report", "Second part"); err != nil {
+		t.Fatalf("postToBluesky returned error: %v", err)
+	}
+
+	created := pds.calls("com.atproto.repo.createRecord")
+	if len(created) != 3 {
+		t.Fatalf("expected two posts and one threadgate, got %d records", len(created))
+	}
+	gate := created[1]
+	if gate["collection"] != "app.bsky.feed.threadgate" || gate["rkey"] != "a" {
+		t.Fatalf("expected a threadgate with the rkey of the first post, got %v", gate)
+	}
+	record := gate["record"].(map[string]any)
+	if record["post"] != "at://did:plc:bot/app.bsky.feed.post/a" {
+		t.Fatalf("expected the threadgate to reference the first post, got %v", record["post"])
+	}
+	allow := record["allow"].([]any)
+	if len(allow) != 2 ||
+		allow[0].(map[string]any)["$type"] != "app.bsky.feed.threadgate#followingRule" ||
+		allow[1].(map[string]any)["$type"] != "app.bsky.feed.threadgate#followerRule" {
+		t.Fatalf("unexpected allow rules %v", allow)
+	}
+	if created[2]["collection"] != "app.bsky.feed.post" {
+		t.Fatalf("expected the reply to have no threadgate, got %v", created[2])
+	}
+}
+
+func TestReplyPolicy(t *testing.T) {
+	if rules, err := replyPolicy(); rules != nil || err != nil {
+		t.Fatalf("expected open replies by default, got %v (err %v)", rules, err)
+	}
+	t.Setenv("REPLY_POLICY", "nobody")
+	if rules, err := replyPolicy(); err != nil || len(rules) != 1 || rules[0].FeedThreadgate_MentionRule == nil {
+		t.Fatalf("expected nobody to allow mentioned accounts only, got %v (err %v)", rules, err)
+	}
+	t.Setenv("REPLY_POLICY", "friends")
+	if _, err := replyPolicy(); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
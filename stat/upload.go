@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// Bluesky rejects image blobs larger than this. Tests lower it.
+var maxImageBytes = 1_000_000
+
+const (
+	// Images are not downscaled below this width
+	minImageWidth  = 200
+	uploadAttempts = 3
+)
+
+// Delay before the first retry, doubled for every further retry. Tests shorten it.
+var uploadRetryDelay = 2 * time.Second
+
+// Upload a PNG image as a blob. Images over maxImageBytes are re-encoded with
+// the best compression and then halved in size until they fit. Transient
+// upload errors are retried.
+func uploadImage(ctx context.Context, client *xrpc.Client, data []byte) (*util.LexBlob, error) {
+	data, err := fitImage(data)
+	if err != nil {
+		return nil, err
+	}
+	mimeType := http.DetectContentType(data)
+	if mimeType != "image/png" {
+		return nil, fmt.Errorf("expected a PNG image, got %s", mimeType)
+	}
+
+	delay := uploadRetryDelay
+	for attempt := 1; ; attempt++ {
+		out, err := atproto.RepoUploadBlob(ctx, client, bytes.NewReader(data))
+		if err == nil {
+			blob := out.Blob
+			blob.MimeType = mimeType
+			return blob, nil
+		}
+		if attempt == uploadAttempts || !isTransient(err) {
+			return nil, fmt.Errorf("failed to upload image: %w", err)
+		}
+
+		fmt.Printf("Uploading image failed (attempt %d of %d), retrying in %v: %v\n", attempt, uploadAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Rate limits and server errors are worth retrying, as are network errors
+// that never got an XRPC response
+func isTransient(err error) bool {
+	var xrpcErr *xrpc.Error
+	if errors.As(err, &xrpcErr) {
+		return xrpcErr.StatusCode == http.StatusTooManyRequests || xrpcErr.StatusCode >= 500
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// Shrink a PNG to at most maxImageBytes, returning it unchanged when it fits
+func fitImage(data []byte) ([]byte, error) {
+	if len(data) <= maxImageBytes {
+		return data, nil
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	for {
+		var buf bytes.Buffer
+		if err := encoder.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode image: %w", err)
+		}
+		if buf.Len() <= maxImageBytes {
+			return buf.Bytes(), nil
+		}
+
+		bounds := img.Bounds()
+		if bounds.Dx()/2 < minImageWidth {
+			return nil, fmt.Errorf("image of %d bytes at %dx%d exceeds the %d byte limit", buf.Len(), bounds.Dx(), bounds.Dy(), maxImageBytes)
+		}
+		img = halve(img)
+	}
+}
+
+// Scale an image to half its size, averaging each 2x2 block
+func halve(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	half := image.NewRGBA(image.Rect(0, 0, bounds.Dx()/2, bounds.Dy()/2))
+	for y := range half.Bounds().Dy() {
+		for x := range half.Bounds().Dx() {
+			var r, g, b, a uint32
+			for _, p := range [4]image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				pr, pg, pb, pa := img.At(bounds.Min.X+2*x+p.X, bounds.Min.Y+2*y+p.Y).RGBA()
+				r, g, b, a = r+pr, g+pg, b+pb, a+pa
+			}
+			half.SetRGBA64(x, y, color.RGBA64{R: uint16(r / 4), G: uint16(g / 4), B: uint16(b / 4), A: uint16(a / 4)})
+		}
+	}
+	return half
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Encode a PNG of random pixels, which hardly compresses
+func noisePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	rng := rand.New(rand.NewPCG(1, 2))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(rng.IntN(256)), G: uint8(rng.IntN(256)), B: uint8(rng.IntN(256)), A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestFitImageDownscalesOrRejectsOversizedImages(t *testing.T) {
+	data := noisePNG(t, 800, 400)
+	t.Cleanup(func() { maxImageBytes = 1_000_000 })
+
+	// Halving twice brings 960 kB of noise under 100 kB
+	maxImageBytes = 100_000
+	fitted, err := fitImage(data)
+	if err != nil {
+		t.Fatalf("fitImage returned error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(fitted))
+	if err != nil || len(fitted) > maxImageBytes || img.Bounds().Dx() != 200 {
+		t.Fatalf("expected a 200 px wide PNG under the limit, got %d bytes (err %v)", len(fitted), err)
+	}
+
+	// Below the minimum width the image can not be reduced any further
+	maxImageBytes = 10_000
+	if _, err := fitImage(data); err == nil || !strings.Contains(err.Error(), "exceeds the 10000 byte limit") {
+		t.Fatalf("expected an error for an image that can not be reduced, got %v", err)
+	}
+}
+
+func TestUploadImageRetriesTransientErrors(t *testing.T) {
+	pds := newMockPDS(t)
+	original := uploadRetryDelay
+	uploadRetryDelay = time.Millisecond
+	t.Cleanup(func() { uploadRetryDelay = original })
+
+	client, err := login(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+	data := noisePNG(t, 10, 10)
+
+	pds.failUploads = 2
+	blob, err := uploadImage(context.Background(), client, data)
+	if err != nil {
+		t.Fatalf("uploadImage returned error: %v", err)
+	}
+	if blob.MimeType != "image/png" || blob.Ref.S != "bafkblob" || blob.Size != int64(len(data)) {
+		t.Fatalf("unexpected blob %+v", blob)
+	}
+	if uploads := len(pds.calls("com.atproto.repo.uploadBlob")); uploads != 3 {
+		t.Fatalf("expected 3 upload attempts, got %d", uploads)
+	}
+
+	pds.failUploads = uploadAttempts
+	if _, err := uploadImage(context.Background(), client, data); err == nil {
+		t.Fatal("expected an error once all attempts fail")
+	}
+}
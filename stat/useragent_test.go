@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOutboundRequestsSendUserAgent(t *testing.T) {
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		w.Write([]byte("time,latitude,longitude,depth,mag,id,place\n2026-06-01T10:00:00.000Z,35.0,139.0,10,4.5,us1,Japan\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	if _, _, err := fetchFeeds(context.Background(), []string{server.URL}); err != nil {
+		t.Fatalf("fetchFeeds returned error: %v", err)
+	}
+	t.Setenv("USER_AGENT", "my-quake-bot (mailto:ops@example.com)")
+	if _, _, err := fetchFeeds(context.Background(), []string{server.URL}); err != nil {
+		t.Fatalf("fetchFeeds returned error: %v", err)
+	}
+
+	if len(agents) != 2 || agents[0] != defaultUserAgent || agents[1] != "my-quake-bot (mailto:ops@example.com)" {
+		t.Fatalf("unexpected User-Agent headers %q", agents)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Key of an ISO week such as "2026-W03". The week is always zero-padded so
+// keys also sort chronologically as strings, which the store relies on.
+func formatWeekKey(year, week int) string {
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// Parse a week key, accepting keys without zero-padding like "2026-W3"
+func parseWeekKey(weekKey string) (year, week int, ok bool) {
+	var rest string
+	if n, _ := fmt.Sscanf(weekKey, "%d-W%d%s", &year, &week, &rest); n != 2 {
+		return 0, 0, false
+	}
+	return year, week, week >= 1 && week <= 53
+}
+
+// Order week keys chronologically by year and week. Keys that cannot be
+// parsed sort after all valid keys, in string order.
+func compareWeekKeys(a, b string) int {
+	yearA, weekA, okA := parseWeekKey(a)
+	yearB, weekB, okB := parseWeekKey(b)
+	switch {
+	case okA && okB:
+		return cmp.Or(cmp.Compare(yearA, yearB), cmp.Compare(weekA, weekB), cmp.Compare(a, b))
+	case okA:
+		return -1
+	case okB:
+		return 1
+	}
+	return cmp.Compare(a, b)
+}
+
+// Sort week keys chronologically, oldest first
+func sortWeekKeys(weekKeys []string) {
+	slices.SortFunc(weekKeys, compareWeekKeys)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortWeekKeysAcrossYearBoundaries(t *testing.T) {
+	weekKeys := []string{"2027-W01", "2026-W53", "2026-W9", "2027-W2", "2026-W52", "invalid", "2025-W52", "2026-W10"}
+	sortWeekKeys(weekKeys)
+	want := []string{"2025-W52", "2026-W9", "2026-W10", "2026-W52", "2026-W53", "2027-W01", "2027-W2", "invalid"}
+	if !slices.Equal(weekKeys, want) {
+		t.Fatalf("sortWeekKeys = %v, want %v", weekKeys, want)
+	}
+}
+
+func TestFormatWeekKeyIsZeroPadded(t *testing.T) {
+	if got := formatWeekKey(2027, 1); got != "2027-W01" {
+		t.Fatalf("formatWeekKey(2027, 1) = %q", got)
+	}
+	if year, week, ok := parseWeekKey("2026-W53"); !ok || year != 2026 || week != 53 {
+		t.Fatalf("parseWeekKey(2026-W53) = %d, %d, %v", year, week, ok)
+	}
+	for _, weekKey := range []string{"2026-W54", "2026-W00", "2026-W01x", "W01"} {
+		if _, _, ok := parseWeekKey(weekKey); ok {
+			t.Fatalf("expected %q to be invalid", weekKey)
+		}
+	}
+}
+
+func TestGenerateReportsPicksLatestWeekAcrossYearBoundary(t *testing.T) {
+	store := openTestStore(t)
+	stats := WeekStats{Counts: [7]int{0, 1, 0, 0, 0, 0, 0}, MagnitudeSum: 2.5, StartDate: time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC)}
+	data := generateReports(store, map[string]WeekStats{"2026-W52": stats, "2026-W53": stats, "2027-W1": stats})
+	if data.WeekKey != "2027-W1" {
+		t.Fatalf("expected the first week of 2027 to be reported, got %s", data.WeekKey)
+	}
+}
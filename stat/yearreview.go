@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Number of busiest weeks listed in the year in review
+const yearReviewWeeks = 3
+
+// Check if the year in review of a year has already been posted
+func (s *Store) WasYearReviewPosted(year int) bool {
+	return s.WasWeekPosted(yearKeyPrefix + strconv.Itoa(year))
+}
+
+func (s *Store) MarkYearReviewPosted(year int) {
+	s.MarkWeekPosted(yearKeyPrefix + strconv.Itoa(year))
+}
+
+// YearReview aggregates the stored weeks of a year. Weeks belong to the ISO
+// year of their key and to the month of their Thursday, the day that decides
+// which year an ISO week belongs to.
+type YearReview struct {
+	Year        int
+	Weeks       int
+	Counts      [7]int
+	Total       int
+	Largest     Earthquake
+	MonthTotals [12]int
+	// Weeks with the most events, which are usually aftershock sequences
+	BusiestWeeks []string
+	weekTotals   map[string]int
+	weekLargest  map[string]Earthquake
+}
+
+// Aggregate the stored weekly stats of a year and render the review as a
+// thread. Returns an error when no week of the year is stored.
+func generateYearReview(store *Store, year int) ([]string, error) {
+	weeks, err := store.AllWeekStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load weekly stats: %w", err)
+	}
+
+	review := YearReview{Year: year, weekTotals: make(map[string]int), weekLargest: make(map[string]Earthquake)}
+	prefix := fmt.Sprintf("%d-W", year)
+	for weekKey, stats := range weeks {
+		if !strings.HasPrefix(weekKey, prefix) {
+			continue
+		}
+		review.Weeks++
+		for i, count := range stats.Counts {
+			review.Counts[i] += count
+		}
+		total := stats.ReportedTotal()
+		review.Total += total
+		review.MonthTotals[stats.StartDate.AddDate(0, 0, 3).Month()-1] += total
+		if stats.Largest.Magnitude > review.Largest.Magnitude {
+			review.Largest = stats.Largest
+		}
+		review.weekTotals[weekKey] = total
+		review.weekLargest[weekKey] = stats.Largest
+		review.BusiestWeeks = append(review.BusiestWeeks, weekKey)
+	}
+	if review.Weeks == 0 {
+		return nil, fmt.Errorf("no stored weeks for %d", year)
+	}
+
+	slices.SortFunc(review.BusiestWeeks, func(a, b string) int {
+		if review.weekTotals[a] != review.weekTotals[b] {
+			return review.weekTotals[b] - review.weekTotals[a]
+		}
+		return strings.Compare(a, b)
+	})
+	review.BusiestWeeks = review.BusiestWeeks[:min(len(review.BusiestWeeks), yearReviewWeeks)]
+	return review.posts(), nil
+}
+
+// Render the review as a thread: the headline numbers, the magnitude
+// categories and the months, and the busiest weeks
+func (r YearReview) posts() []string {
+	busiestMonth := 0
+	for i, total := range r.MonthTotals {
+		if total > r.MonthTotals[busiestMonth] {
+			busiestMonth = i
+		}
+	}
+
+	var first strings.Builder
+	fmt.Fprintf(&first, "%d Year in Review\n\n", r.Year)
+	fmt.Fprintf(&first, "Total: %s earthquakes in %d weeks\n", formatCount(r.Total), r.Weeks)
+	if r.Largest.Magnitude > 0 {
+		fmt.Fprintf(&first, "Largest: M%s near %s on %s\n", formatMag(r.Largest.Magnitude), shortPlace(r.Largest.Place), r.Largest.Time.Format("Jan 2"))
+	}
+	fmt.Fprintf(&first, "Most active month: %s (%s)", time.Month(busiestMonth+1), formatCount(r.MonthTotals[busiestMonth]))
+
+	var second strings.Builder
+	second.WriteString("By magnitude:")
+	excludeMicro := envBool("EXCLUDE_MICRO")
+	for i, count := range r.Counts {
+		if i == 0 && excludeMicro {
+			continue
+		}
+		fmt.Fprintf(&second, "\n%s: %s", categories[i], formatCount(count))
+	}
+	fmt.Fprintf(&second, "\n\nBy month: %s", monthSparkline(r.MonthTotals))
+
+	var third strings.Builder
+	third.WriteString("Busiest weeks:")
+	for _, weekKey := range r.BusiestWeeks {
+		fmt.Fprintf(&third, "\n%s: %s", weekKey, formatCount(r.weekTotals[weekKey]))
+		if largest := r.weekLargest[weekKey]; largest.Magnitude > 0 {
+			fmt.Fprintf(&third, ", largest M%s near %s", formatMag(largest.Magnitude), shortPlace(largest.Place))
+		}
+	}
+
+	return []string{first.String(), second.String(), third.String()}
+}
+
+// Sparkline of the month totals from January to December
+func monthSparkline(totals [12]int) string {
+	peak := slices.Max(totals[:])
+	var spark strings.Builder
+	for _, total := range totals {
+		if peak == 0 {
+			spark.WriteRune(sparkBars[0])
+			continue
+		}
+		spark.WriteRune(sparkBars[total*(len(sparkBars)-1)/peak])
+	}
+	return spark.String() + " (Jan - Dec)"
+}
+
+// In January, post the review of the previous year once its last week is
+// stored, reporting the year that was posted. Nothing is posted before the
+// last week of the year is complete.
+func postYearReview(ctx context.Context, store *Store) (int, bool, error) {
+	today := now().In(weekLocation)
+	if today.Month() != time.January {
+		return 0, false, nil
+	}
+	year := today.Year() - 1
+	if store.WasYearReviewPosted(year) {
+		return 0, false, nil
+	}
+	// December 28 is always in the last ISO week of its year
+	lastYear, lastWeek := time.Date(year, time.December, 28, 0, 0, 0, 0, time.UTC).ISOWeek()
+	if _, ok := store.LoadWeekStats(formatWeekKey(lastYear, lastWeek)); !ok {
+		return 0, false, nil
+	}
+
+	texts, err := generateYearReview(store, year)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, text := range texts {
+		fmt.Println(text + "\n")
+	}
+	if _, err := postThread(ctx, store, yearKeyPrefix+strconv.Itoa(year), texts); err != nil {
+		return 0, false, err
+	}
+	store.MarkYearReviewPosted(year)
+	return year, true, nil
+}
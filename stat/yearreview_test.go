@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Store the 52 weeks of 2025 with 100 minor events each, a busy March and
+// a large event in week 10
+func seedYear(t *testing.T, store *Store) {
+	t.Helper()
+	monday := time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)
+	for i := range 52 {
+		start, end, year, week := getWeekBoundaries(monday.AddDate(0, 0, 7*i))
+		stats := WeekStats{StartDate: start, EndDate: end, Year: year, WeekNum: week}
+		stats.Counts[1] = 100
+		stats.Largest = Earthquake{Magnitude: 4.5, Place: "10 km N of Quiet, Chile", Time: start}
+		switch week {
+		case 10:
+			stats.Counts[1], stats.Counts[5] = 900, 1
+			stats.Largest = Earthquake{Magnitude: 7.4, Place: "50 km S of Busy, Japan", Time: start.AddDate(0, 0, 2)}
+		case 11:
+			stats.Counts[1] = 400
+		case 30:
+			stats.Counts[1] = 300
+		}
+		store.StoreWeekStats(fmt.Sprintf("%d-W%02d", year, week), stats)
+	}
+	// A week of the next year is not part of the review
+	store.StoreWeekStats("2026-W01", WeekStats{Counts: [7]int{0, 5000}})
+}
+
+func TestGenerateYearReview(t *testing.T) {
+	store := openTestStore(t)
+	seedYear(t, store)
+
+	texts, err := generateYearReview(store, 2025)
+	if err != nil {
+		t.Fatalf("generateYearReview returned error: %v", err)
+	}
+	if len(texts) != 3 {
+		t.Fatalf("expected a thread of 3 posts, got %d", len(texts))
+	}
+
+	want := "2025 Year in Review\n\nTotal: 6501 earthquakes in 52 weeks\nLargest: M7.4 near Busy, Japan on Mar 5\nMost active month: March (1501)"
+	if texts[0] != want {
+		t.Fatalf("unexpected first post:\n%s\nwant:\n%s", texts[0], want)
+	}
+	if !strings.Contains(texts[1], "\nMinor 2.0 - 3.9: 6500\n") || !strings.Contains(texts[1], "\nMajor 7.0 - 7.9: 1\n") {
+		t.Fatalf("unexpected category post:\n%s", texts[1])
+	}
+	wantWeeks := "Busiest weeks:\n2025-W10: 901, largest M7.4 near Busy, Japan\n2025-W11: 400, largest M4.5 near Quiet, Chile\n2025-W30: 300, largest M4.5 near Quiet, Chile"
+	if texts[2] != wantWeeks {
+		t.Fatalf("unexpected busiest weeks:\n%s\nwant:\n%s", texts[2], wantWeeks)
+	}
+	for _, text := range texts {
+		if postLength(text) > maxPostLength {
+			t.Fatalf("post too long (%d graphemes):\n%s", postLength(text), text)
+		}
+	}
+
+	if _, err := generateYearReview(store, 2019); err == nil {
+		t.Fatal("expected an error for a year without stored weeks")
+	}
+}
+
+func TestPostYearReviewOncePerYear(t *testing.T) {
+	store := openTestStore(t)
+	pds := newMockPDS(t)
+
+	// Not before the last week of the year is stored
+	setNow(t, time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC))
+	if _, posted, err := postYearReview(context.Background(), store); posted || err != nil {
+		t.Fatalf("expected no review before the last week is stored, got %v (err %v)", posted, err)
+	}
+
+	seedYear(t, store)
+	setNow(t, time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC))
+	year, posted, err := postYearReview(context.Background(), store)
+	if err != nil || !posted || year != 2025 {
+		t.Fatalf("expected the 2025 review to be posted, got %d %v (err %v)", year, posted, err)
+	}
+	if created := pds.calls("com.atproto.repo.createRecord"); len(created) != 3 {
+		t.Fatalf("expected a thread of 3 posts, got %d", len(created))
+	}
+
+	if _, posted, _ := postYearReview(context.Background(), store); posted {
+		t.Fatal("expected the review to be posted once")
+	}
+	setNow(t, time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC))
+	store2 := openTestStore(t)
+	seedYear(t, store2)
+	if _, posted, _ := postYearReview(context.Background(), store2); posted {
+		t.Fatal("expected no review outside January")
+	}
+}